@@ -0,0 +1,144 @@
+package rpn
+
+import (
+	"math"
+	"math/big"
+)
+
+const (
+	integrateTolerance = 1e-10
+	solveTolerance     = 1e-12
+	solveSteps         = 100
+)
+
+// numeric is a numeric method applied to a function of one variable and
+// the values of the other arguments of its form
+type numeric func(f func(float64) (float64, error), args []float64) (float64, error)
+
+// numericForm builds a form applying method to its body, which is evaluated
+// in float64 arithmetic whatever the mode of the evaluation
+func numericForm(method numeric) *form {
+	return &form{1, 0, func(e *evaluator, body *Program, args []*big.Rat) (*big.Rat, error) {
+		fargs := make([]float64, len(args))
+		for i, arg := range args {
+			fargs[i], _ = arg.Float64()
+			if math.IsInf(fargs[i], 0) {
+				return nil, ErrInvalidArgument
+			}
+		}
+		v, err := method(func(x float64) (float64, error) {
+			v, err := e.bind(body, new(big.Rat).SetFloat64(x))
+			if err != nil {
+				return 0, err
+			}
+			f, _ := v.Float64()
+			return f, nil
+		}, fargs)
+		if err != nil {
+			return nil, err
+		}
+		return setFinite(new(big.Rat), v)
+	}, func(e *evaluator, body *Program, args []float64) (float64, error) {
+		for _, arg := range args {
+			if math.IsInf(arg, 0) || math.IsNaN(arg) {
+				return math.NaN(), nil
+			}
+		}
+		return method(func(x float64) (float64, error) {
+			return e.bindFloat(body, x)
+		}, args)
+	}}
+}
+
+// integrate approximates the integral of f from args[0] to args[1] with
+// adaptive Simpson's rule
+func integrate(f func(float64) (float64, error), args []float64) (float64, error) {
+	a, b := args[0], args[1]
+	if a == b {
+		return 0, nil
+	}
+	fa, err := f(a)
+	if err != nil {
+		return 0, err
+	}
+	fb, err := f(b)
+	if err != nil {
+		return 0, err
+	}
+	m := (a + b) / 2
+	fm, err := f(m)
+	if err != nil {
+		return 0, err
+	}
+	s := &simpson{f: f}
+	return s.refine(a, b, fa, fm, fb, (b-a)/6*(fa+4*fm+fb), integrateTolerance, 50)
+}
+
+// simpson is an adaptive Simpson's rule integration bounding the number of
+// evaluations of f
+type simpson struct {
+	f     func(float64) (float64, error)
+	evals int
+}
+
+// refine returns the integral over [a, b] given the estimate whole, halving
+// the interval until both halves agree with it
+func (s *simpson) refine(a, b, fa, fm, fb, whole, tol float64, depth int) (float64, error) {
+	s.evals += 2
+	if s.evals > maxIterations {
+		return 0, ErrInvalidArgument
+	}
+	m := (a + b) / 2
+	lm, rm := (a+m)/2, (m+b)/2
+	flm, err := s.f(lm)
+	if err != nil {
+		return 0, err
+	}
+	frm, err := s.f(rm)
+	if err != nil {
+		return 0, err
+	}
+	left := (m - a) / 6 * (fa + 4*flm + fm)
+	right := (b - m) / 6 * (fm + 4*frm + fb)
+	delta := left + right - whole
+	if depth <= 0 || math.Abs(delta) <= 15*tol {
+		return left + right + delta/15, nil
+	}
+	l, err := s.refine(a, m, fa, flm, fm, left, tol/2, depth-1)
+	if err != nil {
+		return 0, err
+	}
+	r, err := s.refine(m, b, fm, frm, fb, right, tol/2, depth-1)
+	if err != nil {
+		return 0, err
+	}
+	return l + r, nil
+}
+
+// solve finds a root of f near the guess args[0] with the secant method,
+// failing with ErrInvalidArgument if it does not converge
+func solve(f func(float64) (float64, error), args []float64) (float64, error) {
+	x0 := args[0]
+	x1 := x0 + 1e-4*math.Max(1, math.Abs(x0))
+	f0, err := f(x0)
+	if err != nil {
+		return 0, err
+	}
+	for i := 0; i < solveSteps; i++ {
+		if f0 == 0 {
+			return x0, nil
+		}
+		f1, err := f(x1)
+		if err != nil {
+			return 0, err
+		}
+		if f1 == 0 || math.Abs(x1-x0) <= solveTolerance*math.Max(1, math.Abs(x1)) {
+			return x1, nil
+		}
+		if f1 == f0 || math.IsNaN(f1) || math.IsInf(f1, 0) {
+			break
+		}
+		x0, x1, f0 = x1, x1-f1*(x1-x0)/(f1-f0), f1
+	}
+	return 0, ErrInvalidArgument
+}
@@ -0,0 +1,56 @@
+package rpn
+
+import (
+	"errors"
+	"math"
+	"math/big"
+	"testing"
+)
+
+func TestAnalysis(t *testing.T) {
+	cases := []struct {
+		in     string
+		result float64
+		err    error
+	}{
+		{"integrate(x^2, x, 0, 3)", 9, nil},
+		{"integrate(sin(x), x, 0, 3.14159265358979)", 2, nil},
+		{"integrate(1 / x, x, 1, 2)", math.Ln2, nil},
+		{"integrate(k * x, x, 0, 1)", 2, nil},
+		{"integrate(x, x, 2, 2)", 0, nil},
+		{"integrate(x, x, 1, 0)", -0.5, nil},
+		{"integrate(1 / x, x, 0, 1)", 0, ErrZeroDivision},
+		{"solve(x^2 - 2, x, 1)", math.Sqrt2, nil},
+		{"solve(cos(x) - x, x, 1)", 0.7390851332151607, nil},
+		{"solve(x - k, x, k)", 4, nil},
+		{"solve(x^2 + 1, x, 0)", 0, ErrInvalidArgument},
+		{"integrate(solve(y - x, y, 0), x, 0, 1)", 0.5, nil},
+	}
+	for _, tc := range cases {
+		r, err := New(tc.in)
+		if err != nil {
+			t.Errorf("can not convert [%v], err %v", tc.in, err)
+			continue
+		}
+		result, err := r.Eval(map[string]*big.Rat{"k": big.NewRat(4, 1)})
+		if !errors.Is(err, tc.err) {
+			t.Errorf("[%v] err should be %v but %v", tc.in, tc.err, err)
+		} else if err == nil {
+			if f, _ := result.Float64(); math.Abs(f-tc.result) > 1e-8 {
+				t.Errorf("[%v] result should be %v but %v", tc.in, tc.result, f)
+			}
+		}
+		f, err := r.EvalFloat64(map[string]float64{"k": 4})
+		if !errors.Is(err, tc.err) {
+			t.Errorf("[%v] float err should be %v but %v", tc.in, tc.err, err)
+		} else if err == nil && math.Abs(f-tc.result) > 1e-8 {
+			t.Errorf("[%v] float result should be %v but %v", tc.in, tc.result, f)
+		}
+	}
+
+	for _, in := range []string{"integrate(x, 1, 0, 1)", "solve(x, x)"} {
+		if _, err := New(in); !errors.Is(err, ErrUnrecognizedExpression) {
+			t.Errorf("[%v] err should be %v but %v", in, ErrUnrecognizedExpression, err)
+		}
+	}
+}
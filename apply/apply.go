@@ -0,0 +1,162 @@
+// Package apply evaluates a formula for each row of a CSV or JSON lines
+// stream, binding the columns of the row as variables and appending the
+// result as a new column.
+package apply
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math/big"
+	"strings"
+
+	"github.com/Pasithea/rpn"
+)
+
+// Options configures how results are added to rows
+type Options struct {
+	Column   string // name of the result column, "result" if empty
+	Decimals int    // digits kept of results without a finite decimal expansion, 10 if zero
+}
+
+func (o Options) column() string {
+	if o.Column == "" {
+		return "result"
+	}
+	return o.Column
+}
+
+// Format renders v in decimal, exactly if it has a finite decimal expansion
+// and rounded to o.Decimals digits after the point otherwise
+func (o Options) Format(v *big.Rat) string {
+	if n, ok := rpn.DecimalPlaces(v); ok {
+		return v.FloatString(n)
+	}
+	decimals := o.Decimals
+	if decimals == 0 {
+		decimals = 10
+	}
+	return v.FloatString(decimals)
+}
+
+// CSV copies the CSV stream in to out with a result column appended to the
+// header and to each row. The header names the variables, cells which are
+// not numbers leave their variable unbound. It stops at the first row
+// failing to evaluate, the rows before it having been written.
+func CSV(in io.Reader, out io.Writer, p *rpn.Program, o Options) error {
+	r := csv.NewReader(in)
+	w := csv.NewWriter(out)
+	// keep the rows converted before a failing one
+	defer w.Flush()
+	header, err := r.Read()
+	if err == io.EOF {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if err := w.Write(append(header[:len(header):len(header)], o.column())); err != nil {
+		return err
+	}
+	for row := 1; ; row++ {
+		record, err := r.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return err
+		}
+		vars := make(map[string]*big.Rat, len(header))
+		for i, cell := range record {
+			if v, ok := new(big.Rat).SetString(strings.TrimSpace(cell)); ok && i < len(header) {
+				vars[header[i]] = v
+			}
+		}
+		rv, err := p.Eval(vars)
+		if err != nil {
+			return fmt.Errorf("row %d: %w", row, err)
+		}
+		if err := w.Write(append(record, o.Format(rv))); err != nil {
+			return err
+		}
+	}
+	w.Flush()
+	return w.Error()
+}
+
+// JSONLines copies the stream of JSON objects in, one per line, to out with
+// the result added as a last member of each. Members holding numbers, numeric
+// strings or booleans are bound as variables, true being 1 and false 0.
+// Blank lines are skipped. It stops at the first line failing to evaluate or
+// already having a member named like the result, the lines before it having
+// been written.
+func JSONLines(in io.Reader, out io.Writer, p *rpn.Program, o Options) error {
+	sc := bufio.NewScanner(in)
+	sc.Buffer(make([]byte, 64*1024), 64*1024*1024)
+	w := bufio.NewWriter(out)
+	// keep the lines converted before a failing one
+	defer w.Flush()
+	key, err := json.Marshal(o.column())
+	if err != nil {
+		return err
+	}
+	for line := 1; sc.Scan(); line++ {
+		text := bytes.TrimSpace(sc.Bytes())
+		if len(text) == 0 {
+			continue
+		}
+		var members map[string]json.RawMessage
+		if err := json.Unmarshal(text, &members); err != nil {
+			return fmt.Errorf("line %d: %w", line, err)
+		}
+		if text[0] != '{' {
+			return fmt.Errorf("line %d: not an object", line)
+		}
+		if _, ok := members[o.column()]; ok {
+			return fmt.Errorf("line %d: member %s already exists", line, key)
+		}
+		vars := make(map[string]*big.Rat, len(members))
+		for name, raw := range members {
+			if v, ok := jsonValue(raw); ok {
+				vars[name] = v
+			}
+		}
+		rv, err := p.Eval(vars)
+		if err != nil {
+			return fmt.Errorf("line %d: %w", line, err)
+		}
+		// keep the members as written, appending the result
+		w.Write(text[:len(text)-1])
+		if len(members) > 0 {
+			w.WriteByte(',')
+		}
+		w.Write(key)
+		w.WriteByte(':')
+		w.WriteString(o.Format(rv))
+		w.WriteString("}\n")
+	}
+	if err := sc.Err(); err != nil {
+		return err
+	}
+	return w.Flush()
+}
+
+// jsonValue returns the number a JSON value stands for
+func jsonValue(raw json.RawMessage) (*big.Rat, bool) {
+	s := string(raw)
+	switch s {
+	case "true":
+		return big.NewRat(1, 1), true
+	case "false":
+		return new(big.Rat), true
+	}
+	if strings.HasPrefix(s, `"`) {
+		if err := json.Unmarshal(raw, &s); err != nil {
+			return nil, false
+		}
+	}
+	return new(big.Rat).SetString(strings.TrimSpace(s))
+}
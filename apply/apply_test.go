@@ -0,0 +1,94 @@
+package apply
+
+import (
+	"errors"
+	"math/big"
+	"strings"
+	"testing"
+
+	"github.com/Pasithea/rpn"
+)
+
+func TestCSV(t *testing.T) {
+	cases := []struct {
+		formula string
+		in      string
+		out     string
+		err     error
+	}{
+		{"price * qty", "price,qty\n2.5,4\n1,3\n", "price,qty,result\n2.5,4,10\n1,3,3\n", nil},
+		{"a / 3", "a\n1\n", "a,result\n1,0.3333333333\n", nil},
+		{"a / 8", "a,note\n1,x\n", "a,note,result\n1,x,0.125\n", nil},
+		{"a", "", "", nil},
+		{"a + b", "a,b\n1,2\n1,\n", "a,b,result\n1,2,3\n", rpn.ErrUndefined},
+	}
+	for _, tc := range cases {
+		p, err := rpn.Compile(tc.formula)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var out strings.Builder
+		err = CSV(strings.NewReader(tc.in), &out, p, Options{})
+		if !errors.Is(err, tc.err) {
+			t.Errorf("[%v] err should be %v but %v", tc.formula, tc.err, err)
+			continue
+		}
+		if out.String() != tc.out {
+			t.Errorf("[%v] output should be %q but %q", tc.formula, tc.out, out.String())
+		}
+	}
+}
+
+func TestJSONLines(t *testing.T) {
+	cases := []struct {
+		formula string
+		in      string
+		out     string
+		err     error
+	}{
+		{"price * qty", "{\"price\": 2.5, \"qty\": \"4\", \"id\": \"x\"}\n\n{\"qty\":1,\"price\":1}\n",
+			"{\"price\": 2.5, \"qty\": \"4\", \"id\": \"x\",\"total\":10}\n{\"qty\":1,\"price\":1,\"total\":1}\n", nil},
+		{"1 + ok", "{\"ok\": true}\n", "{\"ok\": true,\"total\":2}\n", nil},
+		{"2 / 3", "{}\n", "{\"total\":0.67}\n", nil},
+		{"a", "{\"a\": null}\n", "", rpn.ErrUndefined},
+		{"a", "{\"a\": 1}\n{}\n", "{\"a\": 1,\"total\":1}\n", rpn.ErrUndefined},
+		{"a", "[1]\n", "", nil},
+		{"a", "{\"a\": 1, \"total\": 2}\n", "", nil},
+	}
+	for _, tc := range cases {
+		p, err := rpn.Compile(tc.formula)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var out strings.Builder
+		err = JSONLines(strings.NewReader(tc.in), &out, p, Options{Column: "total", Decimals: 2})
+		if tc.out == "" && tc.err == nil {
+			if err == nil {
+				t.Errorf("[%v] %q should fail", tc.formula, tc.in)
+			}
+			continue
+		}
+		if !errors.Is(err, tc.err) {
+			t.Errorf("[%v] err should be %v but %v", tc.formula, tc.err, err)
+			continue
+		}
+		if out.String() != tc.out {
+			t.Errorf("[%v] output should be %q but %q", tc.formula, tc.out, out.String())
+		}
+	}
+}
+
+func TestFormat(t *testing.T) {
+	for _, tc := range []struct {
+		v   *big.Rat
+		out string
+	}{
+		{big.NewRat(3, 1), "3"},
+		{big.NewRat(-1, 40), "-0.025"},
+		{big.NewRat(2, 3), "0.6666666667"},
+	} {
+		if got := (Options{}).Format(tc.v); got != tc.out {
+			t.Errorf("%v should be formatted %q but %q", tc.v, tc.out, got)
+		}
+	}
+}
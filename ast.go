@@ -0,0 +1,57 @@
+package rpn
+
+// NodeKind is the kind of a syntax tree node
+type NodeKind uint8
+
+const (
+	NodeInvalid  NodeKind = iota // stands in for a part that could not be parsed
+	NodeNumber                   // a number literal
+	NodeIdent                    // a reference to a named expression
+	NodeOperator                 // an operator, unary minus has a single argument
+	NodeFunction                 // a function call
+)
+
+// Node is a node of the syntax tree of an expression
+type Node struct {
+	Kind  NodeKind
+	Value string // the literal, name or operator as written
+	Pos   int    // byte offset in the expression
+	Args  []*Node
+}
+
+// ParseTolerant parses expr without stopping at the first syntax error. It
+// skips each error up to the next comma or closing parenthesis and returns
+// the partial syntax tree together with all errors found, which lets editors
+// analyse expressions while they are being typed.
+func ParseTolerant(expr string) (*Node, []*SyntaxError) {
+	reg := snapshot()
+	p := &pratt{input: lex(continuations(expr), reg.typeOfToken), end: len(expr), recover: true, reg: reg}
+	root, _ := p.parse()
+	for _, err := range p.errs {
+		err.Line, err.Col = lineCol(expr, err.Pos)
+	}
+	return exportNode(root), p.errs
+}
+
+func exportNode(n *node) *Node {
+	e := &Node{Value: n.tok.v, Pos: n.tok.pos, Args: make([]*Node, 0, len(n.args))}
+	switch n.tok.tp {
+	case tokenTypeOperand:
+		e.Kind = NodeNumber
+	case tokenTypeIdentifier:
+		e.Kind = NodeIdent
+	case tokenTypeOperator:
+		e.Kind = NodeOperator
+		if e.Value == "@" {
+			e.Value = "-"
+		}
+	case tokenTypeFunction:
+		e.Kind = NodeFunction
+	default:
+		e.Kind = NodeInvalid
+	}
+	for _, arg := range n.args {
+		e.Args = append(e.Args, exportNode(arg))
+	}
+	return e
+}
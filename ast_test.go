@@ -0,0 +1,80 @@
+package rpn
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestParseTolerant(t *testing.T) {
+	cases := []struct {
+		in   string
+		tree string
+		errs []string
+	}{
+		{"2 * (3 - -x)", "(* 2 (- 3 (- x)))", nil},
+		{"1 + * 2", "(+ 1 ?)", []string{`unexpected "*" at offset 4`}},
+		{"(1 2) + 3", "(+ 1 3)", []string{`expected ")" but found "2" at offset 3`}},
+		{"abs(1 $ 2, 3) + sin(", "(+ (abs 1 3) (sin ?))", []string{
+			`expected "," or ")" but found "$" at offset 6`,
+			`wrong number of arguments to abs at offset 0`,
+			`unexpected end of expression at offset 20`,
+		}},
+		{"1 ) 2", "(? 1 2)", []string{`unexpected ")" at offset 2`}},
+		{"", "?", []string{`unexpected end of expression at offset 0`}},
+	}
+	for _, tc := range cases {
+		root, errs := ParseTolerant(tc.in)
+		if tree := sexpr(root); tree != tc.tree {
+			t.Errorf("[%v] tree should be %v but %v", tc.in, tc.tree, tree)
+		}
+		msgs := make([]string, 0, len(errs))
+		for _, err := range errs {
+			msgs = append(msgs, err.Error())
+		}
+		if !equal(msgs, tc.errs) {
+			t.Errorf("[%v] errors should be %q but %q", tc.in, tc.errs, msgs)
+		}
+	}
+}
+
+func sexpr(n *Node) string {
+	v := n.Value
+	if n.Kind == NodeInvalid {
+		v = "?"
+	}
+	if len(n.Args) == 0 {
+		return v
+	}
+	s := make([]string, 0, len(n.Args)+1)
+	s = append(s, v)
+	for _, arg := range n.Args {
+		s = append(s, sexpr(arg))
+	}
+	return "(" + strings.Join(s, " ") + ")"
+}
+
+func TestFormatError(t *testing.T) {
+	cases := []struct {
+		in  string
+		out string
+	}{
+		{"1 + * 2", "unexpected \"*\" at offset 4\n1 + * 2\n    ^"},
+		{"2 ÷ round(1", "expected \",\" or \")\" but found end of expression at offset 12\n2 ÷ round(1\n           ^"},
+		{"\tabs(1, 2) × 3", "wrong number of arguments to abs at offset 1\n\tabs(1, 2) × 3\n\t^^^"},
+		{"3 × (2 $$ 1)", "expected \")\" but found \"$\" at offset 8\n3 × (2 $$ 1)\n       ^"},
+	}
+	for _, tc := range cases {
+		_, err := New(tc.in, WithPrattParser())
+		if err == nil {
+			t.Errorf("infix [%v] should not be converted", tc.in)
+			continue
+		}
+		if out := FormatError(err, tc.in); out != tc.out {
+			t.Errorf("[%v] formatted error should be\n%v\nbut\n%v", tc.in, tc.out, out)
+		}
+	}
+
+	if out := FormatError(ErrZeroDivision, "1 / 0"); out != ErrZeroDivision.Error() {
+		t.Errorf("formatted error should be %v but %v", ErrZeroDivision, out)
+	}
+}
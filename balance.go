@@ -0,0 +1,54 @@
+package rpn
+
+import "strings"
+
+// bracketReplacer turns brackets and braces into parentheses
+var bracketReplacer = strings.NewReplacer("[", "(", "]", ")", "{", "(", "}", ")")
+
+// closers maps opening parentheses, brackets and braces to their closers
+var closers = map[byte]byte{'(': ')', '[': ']', '{': '}'}
+
+// CheckBalanced reports the first unmatched or mismatched parenthesis,
+// bracket or brace of expr without parsing it, nil if they all match. The
+// SyntaxError holds the offset of a closer without an opener, of a closer
+// not matching the last opener, or of the first opener left unclosed.
+func CheckBalanced(expr string) *SyntaxError {
+	err := checkBalanced(expr)
+	if err != nil {
+		err.Line, err.Col = lineCol(expr, err.Pos)
+	}
+	return err
+}
+
+func checkBalanced(expr string) *SyntaxError {
+	var open []int
+	for i := 0; i < len(expr); i++ {
+		c := expr[i]
+		switch c {
+		case '(', '[', '{':
+			open = append(open, i)
+		case ')', ']', '}':
+			if len(open) == 0 {
+				return &SyntaxError{Pos: i, End: i + 1, Msg: "unmatched \"" + string(c) + "\""}
+			}
+			if want := closers[expr[open[len(open)-1]]]; c != want {
+				return &SyntaxError{Pos: i, End: i + 1, Msg: "expected \"" + string(want) + "\" but found \"" + string(c) + "\""}
+			}
+			open = open[:len(open)-1]
+		}
+	}
+	if len(open) > 0 {
+		i := open[0]
+		return &SyntaxError{Pos: i, End: i + 1, Msg: "unclosed \"" + string(expr[i]) + "\""}
+	}
+	return nil
+}
+
+// WithBrackets accepts brackets and braces as well as parentheses for
+// grouping and calling functions, such as "[a + b] * {c - d}". Each must be
+// closed by its own kind, "(a]" is a SyntaxError.
+func WithBrackets() Option {
+	return func(o *options) {
+		o.brackets = true
+	}
+}
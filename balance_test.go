@@ -0,0 +1,61 @@
+package rpn
+
+import (
+	"errors"
+	"math/big"
+	"testing"
+)
+
+func TestCheckBalanced(t *testing.T) {
+	cases := []struct {
+		in  string
+		err *SyntaxError
+	}{
+		{"", nil},
+		{"(a + [b * {c}]) - round(x, 2)", nil},
+		{"(a + b", &SyntaxError{Pos: 0, End: 1, Msg: "unclosed \"(\""}},
+		{"a + b)", &SyntaxError{Pos: 5, End: 6, Msg: "unmatched \")\""}},
+		{"((a + b)", &SyntaxError{Pos: 0, End: 1, Msg: "unclosed \"(\""}},
+		{"[a + (b])", &SyntaxError{Pos: 7, End: 8, Msg: "expected \")\" but found \"]\""}},
+		{"{a} + }", &SyntaxError{Pos: 6, End: 7, Msg: "unmatched \"}\""}},
+		{"× (", &SyntaxError{Pos: 3, End: 4, Msg: "unclosed \"(\""}},
+	}
+	for _, tc := range cases {
+		err := CheckBalanced(tc.in)
+		if (err == nil) != (tc.err == nil) || err != nil && *err != *tc.err {
+			t.Errorf("[%v] error should be %v but %v", tc.in, tc.err, err)
+		}
+	}
+}
+
+func TestWithBrackets(t *testing.T) {
+	cases := []struct {
+		in     string
+		result *big.Rat
+		err    error
+	}{
+		{"[1 + 2] * {3 - 1}", big.NewRat(6, 1), nil},
+		{"round[7 / 2, {1 - 1}]", big.NewRat(4, 1), nil},
+		{"-[2 + 1]", big.NewRat(-3, 1), nil},
+		{"[1 + 2)", nil, ErrUnrecognizedExpression},
+		{"{1 + 2", nil, ErrUnrecognizedExpression},
+	}
+	for _, tc := range cases {
+		for _, opts := range [][]Option{{WithBrackets()}, {WithBrackets(), WithPrattParser()}} {
+			r, err := New(tc.in, opts...)
+			if !errors.Is(err, tc.err) {
+				t.Errorf("[%v] err should be %v but %v", tc.in, tc.err, err)
+				continue
+			}
+			if err != nil {
+				continue
+			}
+			if rv, err := r.Result(); err != nil || rv.Cmp(tc.result) != 0 {
+				t.Errorf("[%v] result should be %v but %v, err %v", tc.in, tc.result, rv, err)
+			}
+		}
+	}
+	if _, err := New("[1 + 2] * 3"); !errors.Is(err, ErrUnrecognizedExpression) {
+		t.Errorf("brackets should be unrecognized by default but err %v", err)
+	}
+}
@@ -0,0 +1,81 @@
+package rpn
+
+import "strings"
+
+// absBars rewrites the absolute value bars of expr, as in |x - 3|, into
+// calls of abs. It returns expr unchanged if it has none, otherwise the
+// rewritten expression and the offset in expr of each of its bytes.
+//
+// A bar where an operand is expected opens an absolute value, as does a
+// single bar after an operand if none is open, so that 2|x| is 2 abs(x)
+// with implicit multiplication. A bar after an operand closes the innermost
+// open one unless it starts || followed by an operand, which remains the
+// boolean or: |a > 0 || b| is abs(a > 0 || b) and |a||||b| is abs(a) || abs(b).
+func absBars(expr string) (string, []int) {
+	if strings.IndexByte(expr, '|') < 0 {
+		return expr, nil
+	}
+	var b strings.Builder
+	offsets := make([]int, 0, len(expr)+8)
+	write := func(s string, at int) {
+		b.WriteString(s)
+		for range s {
+			offsets = append(offsets, at)
+		}
+	}
+	rewritten := false
+	open := 0
+	afterOperand := false
+	for i := 0; i < len(expr); {
+		c := expr[i]
+		n := scanName(expr[i:])
+		if n == 0 {
+			n = scanNumeral(expr[i:])
+		}
+		switch {
+		case n > 0:
+			afterOperand = expr[i:i+n] != "div"
+		case c == '|' && afterOperand && i+1 < len(expr) && expr[i+1] == '|' && (open == 0 || startsOperandAt(expr, i+2)):
+			n = 2
+			afterOperand = false
+		case c == '|' && afterOperand && open > 0:
+			write(")", i)
+			open--
+			rewritten = true
+			i++
+			continue
+		case c == '|':
+			write("abs(", i)
+			open++
+			afterOperand = false
+			rewritten = true
+			i++
+			continue
+		case c == ')':
+			afterOperand = true
+		case !isBlank(rune(c)):
+			afterOperand = false
+		}
+		if n == 0 {
+			n = 1
+		}
+		for j := i; j < i+n; j++ {
+			b.WriteByte(expr[j])
+			offsets = append(offsets, j)
+		}
+		i += n
+	}
+	if !rewritten {
+		return expr, nil
+	}
+	return b.String(), offsets
+}
+
+// startsOperandAt reports whether what follows offset i of expr, whitespace
+// aside, may start an operand
+func startsOperandAt(expr string, i int) bool {
+	for i < len(expr) && isBlank(rune(expr[i])) {
+		i++
+	}
+	return i < len(expr) && !strings.ContainsRune("|),", rune(expr[i]))
+}
@@ -0,0 +1,62 @@
+package rpn
+
+import (
+	"errors"
+	"math/big"
+	"testing"
+)
+
+func TestAbsBars(t *testing.T) {
+	vars := map[string]*big.Rat{"x": big.NewRat(1, 1), "a": big.NewRat(-2, 1), "b": big.NewRat(0, 1)}
+	cases := []struct {
+		in     string
+		opts   []Option
+		result *big.Rat
+		err    error
+	}{
+		{"|x - 3|", nil, big.NewRat(2, 1), nil},
+		{"|-x|", nil, big.NewRat(1, 1), nil},
+		{"||a| - 5|", nil, big.NewRat(3, 1), nil},
+		{"|a| * |x - 4| + 1", nil, big.NewRat(7, 1), nil},
+		{"-|a|", nil, big.NewRat(-2, 1), nil},
+		{"|a > 0 || b|", nil, big.NewRat(0, 1), nil},
+		{"|a||||b|", nil, big.NewRat(1, 1), nil},
+		{"|a| || |b|", nil, big.NewRat(1, 1), nil},
+		{"a || b", nil, big.NewRat(1, 1), nil},
+		{"8 div |a|", nil, big.NewRat(4, 1), nil},
+		{"2|a|", []Option{WithImplicitMultiplication()}, big.NewRat(4, 1), nil},
+		{"round(|a| / 3, 1)", nil, big.NewRat(7, 10), nil},
+		{"|x - 3", nil, nil, ErrUnrecognizedExpression},
+		{"x - 3|", nil, nil, ErrUnrecognizedExpression},
+		{"||", nil, nil, ErrUnrecognizedExpression},
+	}
+	for _, tc := range cases {
+		for _, pratt := range []bool{false, true} {
+			opts := tc.opts
+			if pratt {
+				opts = append(opts[:len(opts):len(opts)], WithPrattParser())
+			}
+			r, err := New(tc.in, opts...)
+			if err == nil {
+				var rv *big.Rat
+				if rv, err = r.Eval(vars); err == nil && rv.Cmp(tc.result) != 0 {
+					t.Errorf("[%v] result should be %v but %v", tc.in, tc.result, rv)
+				}
+			}
+			if !errors.Is(err, tc.err) {
+				t.Errorf("[%v] err should be %v but %v", tc.in, tc.err, err)
+			}
+		}
+	}
+
+	r, err := New("1 + |x|", WithPrattParser())
+	if err != nil {
+		t.Fatal(err)
+	}
+	pos := []int{0, 2, 4, 4, 5, 6}
+	for i, tok := range r.Tokens() {
+		if tok.Pos != pos[i] {
+			t.Errorf("token %v should be at offset %d but %d", tok.Value, pos[i], tok.Pos)
+		}
+	}
+}
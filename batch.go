@@ -0,0 +1,196 @@
+package rpn
+
+import (
+	"fmt"
+	"strings"
+)
+
+// RowError is the error evaluating a row of a batch
+type RowError struct {
+	Row int // index of the row, from 0
+	Err error
+}
+
+func (e *RowError) Error() string {
+	return fmt.Sprintf("row %d: %v", e.Row, e.Err)
+}
+
+func (e *RowError) Unwrap() error {
+	return e.Err
+}
+
+// EvalFloat64Batch evaluates the program in float64 arithmetic for each row
+// of columns, binding every identifier to the value of its column in the row,
+// with the same results as calling EvalFloat64 for each row. All columns must
+// have the same length.
+//
+// Programs without branches nor forms whose identifiers all name a column
+// are evaluated a column at a time, applying each instruction to every row
+// in a loop the compiler can vectorize. Other programs are evaluated row by
+// row. A RowError reports a failing row, which is the first one only when
+// evaluating row by row.
+func (p *Program) EvalFloat64Batch(columns map[string][]float64) ([]float64, error) {
+	rows := -1
+	for _, col := range columns {
+		if rows >= 0 && len(col) != rows {
+			return nil, ErrInvalidArgument
+		}
+		rows = len(col)
+	}
+	if rows < 0 {
+		rows = 0
+	}
+	if inputs, ok := p.columnar(columns); ok {
+		e := &evaluator{opts: &p.opts, memo: p.memo, reg: p.reg}
+		return e.runColumns(p, inputs, rows)
+	}
+	results := make([]float64, rows)
+	vars := make(map[string]float64, len(columns))
+	for i := range results {
+		for name, col := range columns {
+			vars[name] = col[i]
+		}
+		v, err := p.EvalFloat64(vars)
+		if err != nil {
+			return nil, &RowError{i, err}
+		}
+		results[i] = v
+	}
+	return results, nil
+}
+
+// columnar returns the column each name of p is bound to and whether p can
+// be evaluated a column at a time
+func (p *Program) columnar(columns map[string][]float64) ([][]float64, bool) {
+	for _, in := range p.code {
+		switch in.op {
+		case opLocal, opForm, opBool, opJumpFalse, opJumpTrue, opSkip, opJump, opNoMatch:
+			return nil, false
+		}
+	}
+	inputs := make([][]float64, len(p.names))
+	for i, name := range p.names {
+		col, ok := columns[name]
+		if !ok && p.opts.caseMode == CaseInsensitive {
+			for k, c := range columns {
+				if strings.EqualFold(k, name) {
+					col, ok = c, true
+					break
+				}
+			}
+		}
+		if !ok {
+			return nil, false
+		}
+		inputs[i] = col
+	}
+	return inputs, true
+}
+
+// runColumns executes the straight-line code of p once for all rows, the
+// values on the stack are columns and owned ones may be overwritten
+func (e *evaluator) runColumns(p *Program, inputs [][]float64, rows int) ([]float64, error) {
+	var ops int
+	for _, in := range p.code {
+		if in.op.counted() {
+			ops++
+		}
+	}
+	if e.opts.maxOps > 0 && ops > e.opts.maxOps {
+		return nil, &RowError{0, ErrBudgetExceeded}
+	}
+
+	type column struct {
+		v     []float64
+		owned bool
+	}
+	regs := make([][]float64, p.regs)
+	var s []column
+	// dst returns a column to write the result of an instruction into
+	dst := func(c column) []float64 {
+		if c.owned {
+			return c.v
+		}
+		return make([]float64, rows)
+	}
+	for _, in := range p.code {
+		switch in.op {
+		case opConst:
+			v := make([]float64, rows)
+			for i := range v {
+				v[i] = p.fconsts[in.arg]
+			}
+			s = append(s, column{v, true})
+		case opLoad:
+			s = append(s, column{inputs[in.arg], false})
+		case opStore:
+			regs[in.arg] = s[len(s)-1].v
+			s[len(s)-1].owned = false
+		case opLoadReg:
+			s = append(s, column{regs[in.arg], false})
+		case opCapture:
+		case opNeg:
+			x := s[len(s)-1]
+			z := dst(x)
+			for i, f := range x.v {
+				z[i] = -f
+			}
+			s[len(s)-1] = column{z, true}
+		case opCall:
+			n := len(s) - in.argc
+			z := make([]float64, rows)
+			args := make([]float64, in.argc)
+			for i := range z {
+				for j, c := range s[n:] {
+					args[j] = c.v[i]
+				}
+				v, err := e.callFloat(p.funcNames[in.arg], p.funcs[in.arg], args)
+				if err != nil {
+					return nil, &RowError{i, err}
+				}
+				z[i] = v
+			}
+			s = append(s[:n], column{z, true})
+		default:
+			x, y := s[len(s)-2], s[len(s)-1]
+			z := dst(x)
+			if err := e.binaryColumn(in.op, z, x.v, y.v); err != nil {
+				return nil, err
+			}
+			s = append(s[:len(s)-2], column{z, true})
+		}
+	}
+	rv := s[len(s)-1]
+	if !rv.owned {
+		return append([]float64(nil), rv.v...), nil
+	}
+	return rv.v, nil
+}
+
+// binaryColumn sets z[i] to x[i] op y[i] for every row like binaryFloat, z
+// may be x
+func (e *evaluator) binaryColumn(op opcode, z, x, y []float64) error {
+	switch op {
+	case opAdd:
+		for i := range z {
+			z[i] = x[i] + y[i]
+		}
+	case opSub:
+		for i := range z {
+			z[i] = x[i] - y[i]
+		}
+	case opMul:
+		for i := range z {
+			z[i] = x[i] * y[i]
+		}
+	default:
+		for i := range z {
+			v, err := e.binaryFloat(op, x[i], y[i])
+			if err != nil {
+				return &RowError{i, err}
+			}
+			z[i] = v
+		}
+	}
+	return nil
+}
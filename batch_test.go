@@ -0,0 +1,98 @@
+package rpn
+
+import (
+	"errors"
+	"math"
+	"testing"
+)
+
+func TestEvalFloat64Batch(t *testing.T) {
+	columns := map[string][]float64{
+		"x": {0, 1, -2.5, 4},
+		"y": {1, 3, 0.5, -1},
+	}
+	cases := []string{
+		"x + y * 2",
+		"(x - y) * (x - y) / -y",
+		"sqrt(abs(x)) + X",
+		"x ^ 2 % 3",
+		"x > y && y > 0",
+		"pw(x > 0, x, y)",
+		"sum(i, 1, 3, i * x)",
+		"x + inf",
+		"2 * 3",
+	}
+	for _, in := range cases {
+		p, err := Compile(in, WithCaseSensitivity(CaseInsensitive))
+		if err != nil {
+			t.Fatal(err)
+		}
+		rvs, err := p.EvalFloat64Batch(columns)
+		if err != nil {
+			t.Errorf("[%v] %v", in, err)
+			continue
+		}
+		for i, rv := range rvs {
+			want, err := p.EvalFloat64(map[string]float64{"x": columns["x"][i], "y": columns["y"][i]})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if rv != want && !(math.IsNaN(rv) && math.IsNaN(want)) {
+				t.Errorf("[%v] row %d should be %v but %v", in, i, want, rv)
+			}
+		}
+	}
+}
+
+func TestEvalFloat64BatchErrors(t *testing.T) {
+	p, err := Compile("1 / (x - 1)")
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = p.EvalFloat64Batch(map[string][]float64{"x": {0, 1, 2}})
+	var re *RowError
+	if !errors.As(err, &re) || re.Row != 1 || !errors.Is(err, ErrZeroDivision) {
+		t.Errorf("error should be a zero division at row 1 but %v", err)
+	}
+	if _, err := p.EvalFloat64Batch(map[string][]float64{"x": {0}, "y": {1, 2}}); err != ErrInvalidArgument {
+		t.Errorf("columns of different lengths should be invalid but %v", err)
+	}
+	p, err = Compile("x + 1 + 2", WithMaxOperations(1))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := p.EvalFloat64Batch(map[string][]float64{"x": {0}}); !errors.Is(err, ErrBudgetExceeded) {
+		t.Errorf("error should exceed the budget but %v", err)
+	}
+}
+
+func BenchmarkEvalFloat64Batch(b *testing.B) {
+	p, err := Compile("(x * 3 + 1) / 2 - x * x * y + (x - 1) * (y + 1)")
+	if err != nil {
+		b.Fatal(err)
+	}
+	const rows = 10000
+	columns := map[string][]float64{"x": make([]float64, rows), "y": make([]float64, rows)}
+	for i := 0; i < rows; i++ {
+		columns["x"][i] = float64(i) / 7
+		columns["y"][i] = float64(rows-i) / 3
+	}
+	b.Run("rows", func(b *testing.B) {
+		vars := make(map[string]float64, 2)
+		for n := 0; n < b.N; n++ {
+			for i := 0; i < rows; i++ {
+				vars["x"], vars["y"] = columns["x"][i], columns["y"][i]
+				if _, err := p.EvalFloat64(vars); err != nil {
+					b.Fatal(err)
+				}
+			}
+		}
+	})
+	b.Run("columns", func(b *testing.B) {
+		for n := 0; n < b.N; n++ {
+			if _, err := p.EvalFloat64Batch(columns); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+}
@@ -0,0 +1,259 @@
+package rpn
+
+import (
+	"math"
+	"math/big"
+)
+
+// Builder constructs an expression without formatting and parsing a string,
+// such as B().Num(2).Add(B().Var("x").Mul(3)) for 2 + x * 3. Builders are
+// immutable, each method returns a new one, so a builder may be used in
+// several expressions. The first error met is reported by Build.
+//
+// Operands are builders, integers, float64 values and *big.Rat values.
+type Builder struct {
+	n   *node
+	err error
+}
+
+// B returns an empty builder, to be started with Num, Var or Call
+func B() *Builder {
+	return &Builder{}
+}
+
+// Num starts the expression with the number x
+func (b *Builder) Num(x interface{}) *Builder {
+	return b.start(func() (*node, error) { return operandNode(x) })
+}
+
+// Var starts the expression with the variable or named expression name
+func (b *Builder) Var(name string) *Builder {
+	return b.start(func() (*node, error) {
+		return &node{tok: &token{tp: tokenTypeIdentifier, v: name}}, nil
+	})
+}
+
+// Call starts the expression with a call of the function name
+func (b *Builder) Call(name string, args ...interface{}) *Builder {
+	return b.start(func() (*node, error) {
+		n := &node{tok: &token{tp: tokenTypeFunction, v: name, argc: len(args)}}
+		for _, arg := range args {
+			a, err := operandNode(arg)
+			if err != nil {
+				return nil, err
+			}
+			n.args = append(n.args, a)
+		}
+		return n, nil
+	})
+}
+
+// Add, Sub, Mul, Div, Mod and Pow apply +, -, *, /, % and ^ to the
+// expression and y, Lt, Le, Gt, Ge, Eq, Ne, And and Or apply <, <=, >, >=,
+// ==, !=, && and ||
+func (b *Builder) Add(y interface{}) *Builder { return b.binary("+", y) }
+func (b *Builder) Sub(y interface{}) *Builder { return b.binary("-", y) }
+func (b *Builder) Mul(y interface{}) *Builder { return b.binary("*", y) }
+func (b *Builder) Div(y interface{}) *Builder { return b.binary("/", y) }
+func (b *Builder) Mod(y interface{}) *Builder { return b.binary("%", y) }
+func (b *Builder) Pow(y interface{}) *Builder { return b.binary("^", y) }
+func (b *Builder) Lt(y interface{}) *Builder  { return b.binary("<", y) }
+func (b *Builder) Le(y interface{}) *Builder  { return b.binary("<=", y) }
+func (b *Builder) Gt(y interface{}) *Builder  { return b.binary(">", y) }
+func (b *Builder) Ge(y interface{}) *Builder  { return b.binary(">=", y) }
+func (b *Builder) Eq(y interface{}) *Builder  { return b.binary("==", y) }
+func (b *Builder) Ne(y interface{}) *Builder  { return b.binary("!=", y) }
+func (b *Builder) And(y interface{}) *Builder { return b.binary("&&", y) }
+func (b *Builder) Or(y interface{}) *Builder  { return b.binary("||", y) }
+
+// Neg negates the expression
+func (b *Builder) Neg() *Builder {
+	if b.err != nil || b.n == nil {
+		return b.fail()
+	}
+	return &Builder{n: negNode(b.n)}
+}
+
+// Build returns the expression built, parsed with opts as if by New. It
+// fails with ErrInvalidName if a variable is not a valid name and with
+// ErrUnrecognizedExpression if a function is unknown or given the wrong
+// number of arguments, with a PolicyError if opts do not allow a function
+// or operator.
+func (b *Builder) Build(opts ...Option) (*RPN, error) {
+	if b.err != nil || b.n == nil {
+		return nil, b.fail().err
+	}
+	r := configure(opts)
+	if err := r.checkBuilt(b.n); err != nil {
+		return nil, err
+	}
+	r.postfix = postfixOf(b.n, nil)
+	if r.opts.consts != nil {
+		r.postfix = r.inlineConsts(r.postfix)
+	}
+	if err := r.opts.policy.check(r.postfix); err != nil {
+		return nil, err
+	}
+	r.infix = infixOf(b.n, r.opts.rightPow(), nil)
+	if err := r.prepare(); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// start returns a builder holding the node made by mk, b must be empty
+func (b *Builder) start(mk func() (*node, error)) *Builder {
+	if b.err != nil || b.n != nil {
+		return b.fail()
+	}
+	n, err := mk()
+	if err != nil {
+		return &Builder{err: err}
+	}
+	return &Builder{n: n}
+}
+
+// binary applies the operator op to the expression and y
+func (b *Builder) binary(op string, y interface{}) *Builder {
+	if b.err != nil || b.n == nil {
+		return b.fail()
+	}
+	n, err := operandNode(y)
+	if err != nil {
+		return &Builder{err: err}
+	}
+	return &Builder{n: &node{tok: &token{tp: tokenTypeOperator, v: op}, args: []*node{b.n, n}}}
+}
+
+// fail returns a builder holding the error of b, ErrInvalidArgument for a
+// missing or extra start
+func (b *Builder) fail() *Builder {
+	if b.err != nil {
+		return b
+	}
+	return &Builder{err: ErrInvalidArgument}
+}
+
+// operandNode returns the node of the operand x
+func operandNode(x interface{}) (*node, error) {
+	var v *big.Rat
+	switch x := x.(type) {
+	case *Builder:
+		if x.err != nil || x.n == nil {
+			return nil, x.fail().err
+		}
+		return x.n, nil
+	case int:
+		v = big.NewRat(int64(x), 1)
+	case int64:
+		v = big.NewRat(x, 1)
+	case float64:
+		if math.IsInf(x, 0) || math.IsNaN(x) {
+			return nil, ErrInvalidArgument
+		}
+		v = new(big.Rat).SetFloat64(x)
+	case *big.Rat:
+		if x == nil {
+			return nil, ErrInvalidArgument
+		}
+		v = x
+	default:
+		return nil, ErrInvalidArgument
+	}
+	return numberNode(v), nil
+}
+
+// numberNode returns the node of v, a literal if v has a finite decimal
+// expansion and a division otherwise, negated if v is negative
+func numberNode(v *big.Rat) *node {
+	abs := new(big.Rat).Abs(v)
+	var n *node
+	if decimals, ok := DecimalPlaces(abs); ok {
+		n = &node{tok: &token{tp: tokenTypeOperand, v: abs.FloatString(decimals)}}
+	} else {
+		n = &node{tok: &token{tp: tokenTypeOperator, v: "/"}, args: []*node{
+			{tok: &token{tp: tokenTypeOperand, v: abs.Num().String()}},
+			{tok: &token{tp: tokenTypeOperand, v: abs.Denom().String()}},
+		}}
+	}
+	if v.Sign() < 0 {
+		return negNode(n)
+	}
+	return n
+}
+
+func negNode(n *node) *node {
+	return &node{tok: &token{tp: tokenTypeOperator, v: "@"}, args: []*node{n}}
+}
+
+// checkBuilt checks the names and functions of the tree n against the
+// registry and options of r
+func (r *RPN) checkBuilt(n *node) error {
+	switch n.tok.tp {
+	case tokenTypeIdentifier:
+		if r.reg.classify(n.tok.v, r.opts.caseMode) != tokenTypeIdentifier {
+			return ErrInvalidName
+		}
+	case tokenTypeFunction:
+		if r.reg.classify(n.tok.v, r.opts.caseMode) != tokenTypeFunction || !r.reg.validArity(n.tok.v, len(n.args)) {
+			return ErrUnrecognizedExpression
+		}
+	}
+	for _, arg := range n.args {
+		if err := r.checkBuilt(arg); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// postfixOf appends the postfix tokens of n to s
+func postfixOf(n *node, s []*token) []*token {
+	for _, arg := range n.args {
+		s = postfixOf(arg, s)
+	}
+	return append(s, n.tok)
+}
+
+// infixOf appends the infix tokens of n to s, with the parentheses needed
+// to keep its structure
+func infixOf(n *node, rightPow bool, s []*token) []*token {
+	tok := n.tok
+	switch {
+	case tok.tp == tokenTypeFunction:
+		s = append(s, tok, &token{tp: tokenTypeParenthesis, v: "("})
+		for i, arg := range n.args {
+			if i > 0 {
+				s = append(s, &token{tp: tokenTypeSeparator, v: ","})
+			}
+			s = infixOf(arg, rightPow, s)
+		}
+		return append(s, &token{tp: tokenTypeParenthesis, v: ")"})
+	case tok.v == "@":
+		s = append(s, tok)
+		return operandOf(n.args[0], operators[tok.v][0], rightPow, s)
+	case tok.tp == tokenTypeOperator:
+		prec, as := operators[tok.v][0], associativity(tok.v, rightPow)
+		left, right := prec, prec
+		if as == associativeRight {
+			left++
+		} else {
+			right++
+		}
+		s = operandOf(n.args[0], left, rightPow, s)
+		s = append(s, tok)
+		return operandOf(n.args[1], right, rightPow, s)
+	}
+	return append(s, tok)
+}
+
+// operandOf appends the infix tokens of the operand n, parenthesised if it
+// is an operator binding less tightly than min
+func operandOf(n *node, min int8, rightPow bool, s []*token) []*token {
+	if n.tok.tp != tokenTypeOperator || operators[n.tok.v][0] >= min {
+		return infixOf(n, rightPow, s)
+	}
+	s = append(s, &token{tp: tokenTypeParenthesis, v: "("})
+	s = infixOf(n, rightPow, s)
+	return append(s, &token{tp: tokenTypeParenthesis, v: ")"})
+}
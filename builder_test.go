@@ -0,0 +1,86 @@
+package rpn
+
+import (
+	"errors"
+	"math/big"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestBuilder(t *testing.T) {
+	x := B().Var("x")
+	vars := map[string]*big.Rat{"x": big.NewRat(4, 1)}
+	cases := []struct {
+		b       *Builder
+		infix   string
+		postfix []string
+		result  *big.Rat
+		err     error
+	}{
+		{B().Num(2).Add(x.Mul(3)), "2 + x * 3", []string{"2", "x", "3", "*", "+"}, big.NewRat(14, 1), nil},
+		{B().Num(2).Add(x).Mul(3), "( 2 + x ) * 3", []string{"2", "x", "+", "3", "*"}, big.NewRat(18, 1), nil},
+		{x.Sub(B().Num(1).Sub(x)), "x - ( 1 - x )", []string{"x", "1", "x", "-", "-"}, big.NewRat(7, 1), nil},
+		{x.Neg().Pow(2), "( @ x ) ^ 2", []string{"x", "@", "2", "^"}, big.NewRat(16, 1), nil},
+		{x.Pow(2).Neg(), "@ x ^ 2", []string{"x", "2", "^", "@"}, big.NewRat(-16, 1), nil},
+		{B().Num(-1.5).Mul(big.NewRat(1, 3)), "@ 1.5 * ( 1 / 3 )", []string{"1.5", "@", "1", "3", "/", "*"}, big.NewRat(-1, 2), nil},
+		{B().Call("sqrt", x).Add(B().Call("round", x.Div(3), 1)), "sqrt ( x ) + round ( x / 3 , 1 )",
+			[]string{"x", "sqrt", "x", "3", "/", "1", "round", "+"}, big.NewRat(33, 10), nil},
+		{x.Gt(1).And(x.Lt(5)), "x > 1 && x < 5", []string{"x", "1", ">", "x", "5", "<", "&&"}, big.NewRat(1, 1), nil},
+		{B().Call("sum", B().Var("i"), 1, x, B().Var("i")), "sum ( i , 1 , x , i )", nil, big.NewRat(10, 1), nil},
+		{B().Num(1).Num(2), "", nil, nil, ErrInvalidArgument},
+		{B().Add(1), "", nil, nil, ErrInvalidArgument},
+		{x.Add("y"), "", nil, nil, ErrInvalidArgument},
+		{B().Var("2x"), "", nil, nil, ErrInvalidName},
+		{B().Var("sqrt"), "", nil, nil, ErrInvalidName},
+		{B().Call("sqrt", 1, 2), "", nil, nil, ErrUnrecognizedExpression},
+		{B().Call("nosuch", 1), "", nil, nil, ErrUnrecognizedExpression},
+	}
+	for i, tc := range cases {
+		r, err := tc.b.Build()
+		if !errors.Is(err, tc.err) {
+			t.Errorf("case %d err should be %v but %v", i, tc.err, err)
+			continue
+		}
+		if err != nil {
+			continue
+		}
+		var infix []string
+		for _, tok := range r.Tokens() {
+			infix = append(infix, tok.Value)
+		}
+		if got := strings.Join(infix, " "); got != tc.infix {
+			t.Errorf("case %d infix should be %q but %q", i, tc.infix, got)
+		}
+		if tc.postfix != nil && !reflect.DeepEqual(r.Postfix(), tc.postfix) {
+			t.Errorf("case %d postfix should be %v but %v", i, tc.postfix, r.Postfix())
+		}
+		if tc.result == nil {
+			continue
+		}
+		if rv, err := r.Eval(vars); err != nil || rv.Cmp(tc.result) != 0 {
+			t.Errorf("case %d result should be %v but %v, err %v", i, tc.result, rv, err)
+		}
+	}
+
+	// the infix notation parses back to the same postfix notation
+	for _, b := range []*Builder{x.Neg().Pow(2), B().Num(2).Pow(B().Num(3).Pow(2)), x.Sub(1).Sub(2)} {
+		for _, opts := range [][]Option{nil, {WithSemanticsVersion(Semantics2)}} {
+			r, err := b.Build(opts...)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var infix []string
+			for _, tok := range r.Tokens() {
+				infix = append(infix, strings.Replace(tok.Value, "@", "-", 1))
+			}
+			p, err := New(strings.Join(infix, " "), opts...)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(p.Postfix(), r.Postfix()) {
+				t.Errorf("[%v] postfix should be %v but %v", strings.Join(infix, " "), r.Postfix(), p.Postfix())
+			}
+		}
+	}
+}
@@ -0,0 +1,72 @@
+package rpn
+
+import (
+	"container/list"
+	"math/big"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// lru is a size bounded cache of results evicting the least recently used
+type lru struct {
+	mu    sync.Mutex
+	size  int
+	order *list.List // most recently used first
+	items map[string]*list.Element
+}
+
+type lruEntry struct {
+	key string
+	rv  *big.Rat
+}
+
+func newLRU(size int) *lru {
+	return &lru{size: size, order: list.New(), items: make(map[string]*list.Element)}
+}
+
+func (c *lru) get(key string) (*big.Rat, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	el, ok := c.items[key]
+	if !ok {
+		return nil, false
+	}
+	c.order.MoveToFront(el)
+	return el.Value.(*lruEntry).rv, true
+}
+
+func (c *lru) put(key string, rv *big.Rat) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if el, ok := c.items[key]; ok {
+		el.Value.(*lruEntry).rv = rv
+		c.order.MoveToFront(el)
+		return
+	}
+	c.items[key] = c.order.PushFront(&lruEntry{key: key, rv: rv})
+	if c.order.Len() > c.size {
+		oldest := c.order.Back()
+		c.order.Remove(oldest)
+		delete(c.items, oldest.Value.(*lruEntry).key)
+	}
+}
+
+// bindingKey identifies a set of variable bindings independent of map order
+func bindingKey(vars map[string]*big.Rat) string {
+	names := make([]string, 0, len(vars))
+	for name := range vars {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	var b strings.Builder
+	for _, name := range names {
+		b.WriteString(name)
+		b.WriteByte('=')
+		if v := vars[name]; v != nil {
+			b.WriteString(v.RatString())
+		}
+		b.WriteByte(' ')
+	}
+	return b.String()
+}
@@ -0,0 +1,36 @@
+package rpn
+
+import (
+	"math/big"
+	"testing"
+)
+
+func TestEvalCache(t *testing.T) {
+	r, err := New("x * 2", WithEvalCache(2))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, x := range []int64{1, 2, 1, 3, 4, 1} {
+		result, err := r.Eval(map[string]*big.Rat{"x": big.NewRat(x, 1)})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if result.Cmp(big.NewRat(2*x, 1)) != 0 {
+			t.Errorf("x = %v result should be %v but %v", x, 2*x, result)
+		}
+	}
+	if n := r.cache.order.Len(); n != 2 {
+		t.Errorf("cache should hold 2 results but %v", n)
+	}
+}
+
+func TestBindingKey(t *testing.T) {
+	a := bindingKey(map[string]*big.Rat{"a": big.NewRat(1, 2), "b": big.NewRat(3, 1)})
+	b := bindingKey(map[string]*big.Rat{"b": big.NewRat(6, 2), "a": big.NewRat(2, 4)})
+	if a != b {
+		t.Errorf("keys of equal bindings differ: %v, %v", a, b)
+	}
+	if c := bindingKey(map[string]*big.Rat{"a": big.NewRat(1, 2)}); a == c {
+		t.Errorf("keys of different bindings are equal: %v", c)
+	}
+}
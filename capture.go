@@ -0,0 +1,25 @@
+package rpn
+
+import "math/big"
+
+// EvalCapture evaluates the expression like Eval and returns the values
+// marked with capture(name, value) by their names, so that intermediates
+// such as a subtotal can be reported along with the result. Results are not
+// cached by WithEvalCache.
+func (r *RPN) EvalCapture(vars map[string]*big.Rat) (*big.Rat, map[string]*big.Rat, error) {
+	return r.prog.EvalCapture(vars)
+}
+
+// EvalCapture evaluates the program like Eval and returns the values marked
+// with capture(name, value) by their names. Values not computed, such as
+// those of a branch not taken, are missing, and a value computed several
+// times, such as within sum, is the last one.
+func (p *Program) EvalCapture(vars map[string]*big.Rat) (*big.Rat, map[string]*big.Rat, error) {
+	captures := make(map[string]*big.Rat)
+	e := &evaluator{opts: &p.opts, memo: p.memo, reg: p.reg, vars: vars, captures: captures}
+	rv, err := e.run(p)
+	if err != nil {
+		return nil, nil, err
+	}
+	return rv, captures, nil
+}
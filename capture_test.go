@@ -0,0 +1,60 @@
+package rpn
+
+import (
+	"math/big"
+	"testing"
+)
+
+func TestEvalCapture(t *testing.T) {
+	vars := map[string]*big.Rat{"price": big.NewRat(20, 1), "qty": big.NewRat(3, 1), "rate": big.NewRat(1, 10)}
+	cases := []struct {
+		in       string
+		result   *big.Rat
+		captures map[string]*big.Rat
+	}{
+		{"price * qty", big.NewRat(60, 1), map[string]*big.Rat{}},
+		{"capture(subtotal, price * qty) * (1 + rate)", big.NewRat(66, 1),
+			map[string]*big.Rat{"subtotal": big.NewRat(60, 1)}},
+		{"with(s, capture(subtotal, price * qty), s + capture(tax, s * rate))", big.NewRat(66, 1),
+			map[string]*big.Rat{"subtotal": big.NewRat(60, 1), "tax": big.NewRat(6, 1)}},
+		{"pw(qty > 5, capture(bulk, price / 2), capture(retail, price))", big.NewRat(20, 1),
+			map[string]*big.Rat{"retail": big.NewRat(20, 1)}},
+		{"sum(i, 1, 3, capture(last, i * qty))", big.NewRat(18, 1),
+			map[string]*big.Rat{"last": big.NewRat(9, 1)}},
+	}
+	for _, tc := range cases {
+		r, err := New(tc.in)
+		if err != nil {
+			t.Errorf("can not convert [%v], err %v", tc.in, err)
+			continue
+		}
+		result, captures, err := r.EvalCapture(vars)
+		if err != nil || result.Cmp(tc.result) != 0 {
+			t.Errorf("[%v] result should be %v but %v, err %v", tc.in, tc.result, result, err)
+			continue
+		}
+		if len(captures) != len(tc.captures) {
+			t.Errorf("[%v] captures should be %v but %v", tc.in, tc.captures, captures)
+			continue
+		}
+		for name, want := range tc.captures {
+			if got, ok := captures[name]; !ok || got.Cmp(want) != 0 {
+				t.Errorf("[%v] capture %v should be %v but %v", tc.in, name, want, got)
+			}
+		}
+		if v, err := r.Eval(vars); err != nil || v.Cmp(tc.result) != 0 {
+			t.Errorf("[%v] Eval should be %v but %v, err %v", tc.in, tc.result, v, err)
+		}
+		if f, err := r.EvalFloat64(map[string]float64{"price": 20, "qty": 3, "rate": 0.1}); err != nil || f != float64FromRat(tc.result) {
+			t.Errorf("[%v] EvalFloat64 should be %v but %v, err %v", tc.in, tc.result, f, err)
+		}
+	}
+	if _, err := New("capture(1, 2)"); err == nil {
+		t.Errorf("[capture(1, 2)] should fail")
+	}
+}
+
+func float64FromRat(r *big.Rat) float64 {
+	f, _ := r.Float64()
+	return f
+}
@@ -0,0 +1,121 @@
+package rpn
+
+import (
+	"math/big"
+	"strconv"
+	"strings"
+)
+
+// maxRangeCells bounds the number of cells a range such as A1:C3 covers
+const maxRangeCells = 10000
+
+// CellResolver returns the value of the spreadsheet cell in column col and
+// row row, both counted from 1 so that B3 is column 2 and row 3, and false
+// if the cell holds no number
+type CellResolver func(col, row int) (*big.Rat, bool)
+
+// WithCellResolver makes identifiers such as A1 or AB12 refer to the cells
+// of a spreadsheet, resolved at evaluation time by resolve unless they are
+// bound as variables. A range such as B12:B14 stands for its cells row by
+// row, separated by commas, so it can be passed to functions taking any
+// number of arguments. sum(B12:B14) adds the cells like sumall(B12:B14) as
+// in spreadsheets. Column letters are upper case.
+func WithCellResolver(resolve CellResolver) Option {
+	return func(o *options) {
+		o.cells = resolve
+	}
+}
+
+// parseCell returns the column and row of the cell reference ref
+func parseCell(ref string) (col, row int, ok bool) {
+	i := 0
+	for i < len(ref) && i < 3 && 'A' <= ref[i] && ref[i] <= 'Z' {
+		col = col*26 + int(ref[i]-'A'+1)
+		i++
+	}
+	if i == 0 || i == len(ref) || ref[i] == '0' || len(ref)-i > 7 {
+		return 0, 0, false
+	}
+	row, err := strconv.Atoi(ref[i:])
+	if err != nil || row <= 0 {
+		return 0, 0, false
+	}
+	return col, row, true
+}
+
+// cellName returns the reference of the cell in column col and row row
+func cellName(col, row int) string {
+	var letters []byte
+	for ; col > 0; col = (col - 1) / 26 {
+		letters = append([]byte{byte('A' + (col-1)%26)}, letters...)
+	}
+	return string(letters) + strconv.Itoa(row)
+}
+
+// expandRanges replaces each range of cells by its cells separated by
+// commas, a range too large is left as is. sum given a range is the
+// spreadsheet function adding its arguments, it becomes sumall.
+func expandRanges(input []*token) []*token {
+	tokens := make([]*token, 0, len(input))
+	for i := 0; i < len(input); i++ {
+		t := input[i]
+		if i+2 < len(input) && t.tp == tokenTypeIdentifier && input[i+1].v == ":" &&
+			input[i+2].tp == tokenTypeIdentifier {
+			c1, r1, ok1 := parseCell(t.v)
+			c2, r2, ok2 := parseCell(input[i+2].v)
+			if ok1 && ok2 && c1 <= c2 && r1 <= r2 && (c2-c1+1)*(r2-r1+1) <= maxRangeCells {
+				if f := enclosingCall(tokens); f >= 0 && strings.EqualFold(tokens[f].v, "sum") {
+					tokens[f] = &token{tp: tokenTypeFunction, v: "sumall", pos: tokens[f].pos}
+				}
+				for row := r1; row <= r2; row++ {
+					for col := c1; col <= c2; col++ {
+						if row > r1 || col > c1 {
+							tokens = append(tokens, &token{tp: tokenTypeSeparator, v: ",", pos: t.pos})
+						}
+						tokens = append(tokens, &token{tp: tokenTypeIdentifier, v: cellName(col, row), pos: t.pos})
+					}
+				}
+				i += 2
+				continue
+			}
+		}
+		tokens = append(tokens, t)
+	}
+	return tokens
+}
+
+// enclosingCall returns the index of the function called by the innermost
+// parenthesis left open in tokens, -1 if there is none
+func enclosingCall(tokens []*token) int {
+	depth := 0
+	for i := len(tokens) - 1; i >= 0; i-- {
+		switch tokens[i].v {
+		case ")":
+			depth++
+		case "(":
+			if depth == 0 {
+				if i > 0 && tokens[i-1].tp == tokenTypeFunction {
+					return i - 1
+				}
+				return -1
+			}
+			depth--
+		}
+	}
+	return -1
+}
+
+// cell returns the value of name if it refers to a cell holding a number
+func (e *evaluator) cell(name string) *big.Rat {
+	if e.opts.cells == nil {
+		return nil
+	}
+	col, row, ok := parseCell(name)
+	if !ok {
+		return nil
+	}
+	if v, ok := e.opts.cells(col, row); ok {
+		return v
+	}
+	return nil
+}
@@ -0,0 +1,67 @@
+package rpn
+
+import (
+	"errors"
+	"math/big"
+	"testing"
+)
+
+func TestCellResolver(t *testing.T) {
+	// the value of each cell is ten times its column plus its row
+	grid := func(col, row int) (*big.Rat, bool) {
+		if row > 20 {
+			return nil, false
+		}
+		return big.NewRat(int64(10*col+row), 1), true
+	}
+	cases := []struct {
+		in     string
+		vars   map[string]*big.Rat
+		result *big.Rat
+		err    error
+	}{
+		{"A1 + B2", nil, big.NewRat(33, 1), nil},
+		{"SUM(A1:A3) + sum(i, 1, 2, i)", nil, big.NewRat(39, 1), nil},
+		{"sum(A1:A2, B1)", nil, big.NewRat(44, 1), nil},
+		{"abs(sum(A1:A2) - 1)", nil, big.NewRat(22, 1), nil},
+		{"sum(B12:B14) / 3", nil, big.NewRat(33, 1), nil},
+		{"sumall(A1:B2, AA1)", nil, big.NewRat(337, 1), nil},
+		{"A1 * 2", map[string]*big.Rat{"A1": big.NewRat(5, 1)}, big.NewRat(10, 1), nil},
+		{"A21", nil, nil, ErrUndefined},
+		{"a1", nil, nil, ErrUndefined},
+		{"A0 + A01", nil, nil, ErrUndefined},
+		{"B2:A1", nil, nil, ErrUnrecognizedExpression},
+	}
+	for _, tc := range cases {
+		for _, opts := range [][]Option{{WithCellResolver(grid)}, {WithCellResolver(grid), WithPrattParser()}} {
+			r, err := New(tc.in, opts...)
+			if err != nil {
+				if !errors.Is(err, tc.err) {
+					t.Errorf("can not convert [%v], err %v", tc.in, err)
+				}
+				continue
+			}
+			result, err := r.Eval(tc.vars)
+			if !errors.Is(err, tc.err) {
+				t.Errorf("[%v] err should be %v but %v", tc.in, tc.err, err)
+				continue
+			}
+			if err == nil && result.Cmp(tc.result) != 0 {
+				t.Errorf("[%v] result should be %v but %v", tc.in, tc.result, result)
+			}
+			if err == nil && len(tc.vars) == 0 {
+				f, err := r.EvalFloat64(nil)
+				if want, _ := tc.result.Float64(); err != nil || f != want {
+					t.Errorf("[%v] float result should be %v but %v, err %v", tc.in, want, f, err)
+				}
+			}
+		}
+	}
+
+	for _, ref := range []string{"A1", "Z9", "AA10", "AZ3", "XFD1048576"} {
+		col, row, ok := parseCell(ref)
+		if !ok || cellName(col, row) != ref {
+			t.Errorf("%v should round trip but %v, %v, %v", ref, col, row, ok)
+		}
+	}
+}
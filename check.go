@@ -0,0 +1,250 @@
+package rpn
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Type is the type of a value as seen by Check
+type Type uint8
+
+const (
+	TypeNumber Type = iota // a number
+	TypeBool               // the 1 or 0 of comparisons and boolean operators
+)
+
+func (t Type) String() string {
+	if t == TypeBool {
+		return "boolean"
+	}
+	return "number"
+}
+
+// Schema declares the types of the variables an expression may use
+type Schema map[string]Type
+
+// TypeError is a mismatch found by Check, Pos and End are the byte offsets
+// of the offending operator, function or name as far as they are known.
+// Line and Col locate Pos in expressions spanning several lines like those
+// of SyntaxError, they are 0 otherwise.
+type TypeError struct {
+	Pos  int
+	End  int
+	Msg  string
+	Line int
+	Col  int
+}
+
+func (e *TypeError) Error() string {
+	return e.Msg + " " + position(e.Pos, e.Line, e.Col)
+}
+
+// Unwrap makes every TypeError match ErrTypeMismatch
+func (e *TypeError) Unwrap() error {
+	return ErrTypeMismatch
+}
+
+// Check type-checks the expression against the variable types declared by
+// schema without evaluating it and returns all mismatches found. Operators
+// and functions take numbers except for && and ||, which take booleans like
+// the conditions of piecewise, and == and != which take operands of the
+// same type. Comparisons, between, isnan, isinf and isfinite yield booleans,
+// piecewise, case and the ifXX functions the common type of their values.
+// Names neither declared nor registered as named expressions are reported
+// as well.
+func (r *RPN) Check(schema Schema) []*TypeError {
+	c := &checker{reg: r.reg, opts: &r.opts, schema: schema, visiting: make(map[*RPN]bool)}
+	c.check(r)
+	for _, err := range c.errs {
+		err.Line, err.Col = lineCol(r.src, err.Pos)
+	}
+	return c.errs
+}
+
+type checker struct {
+	reg      *registry
+	opts     *options
+	schema   Schema
+	scope    []local
+	visiting map[*RPN]bool // named expressions being checked
+	errs     []*TypeError
+}
+
+// local is a name bound by a form and its type
+type local struct {
+	name string
+	tp   Type
+}
+
+// check returns the type of the expression of r
+func (c *checker) check(r *RPN) Type {
+	roots, _, err := buildTree(r.postfix, 1, r.opts.caseMode == CaseInsensitive)
+	if err != nil {
+		c.errs = append(c.errs, &TypeError{Msg: "malformed expression"})
+		return TypeNumber
+	}
+	return c.typeOf(roots[0])
+}
+
+func (c *checker) typeOf(n *node) Type {
+	tok := n.tok
+	switch tok.tp {
+	case tokenTypeIdentifier:
+		return c.name(tok)
+	case tokenTypeOperator:
+		switch tok.v {
+		case "&&", "||":
+			c.want(n.args, TypeBool, tok)
+			return TypeBool
+		case "==", "!=":
+			if x, y := c.typeOf(n.args[0]), c.typeOf(n.args[1]); x != y {
+				c.fail(tok, "%s compares a %v with a %v", tok.v, x, y)
+			}
+			return TypeBool
+		case "<", "<=", ">", ">=":
+			c.want(n.args, TypeNumber, tok)
+			return TypeBool
+		}
+		c.want(n.args, TypeNumber, tok)
+		return TypeNumber
+	case tokenTypeFunction:
+		name := strings.ToLower(tok.v)
+		if fn, ok := c.reg.functions[name]; ok && fn.call == nil {
+			if f := forms[name]; f != nil {
+				return c.form(n, f, name)
+			}
+			if name == "piecewise" || name == "pw" {
+				return c.piecewise(n)
+			}
+			if name == "capture" {
+				return c.typeOf(n.args[1])
+			}
+		}
+		// registered functions may replace the builtins and take numbers
+		if _, ok := c.reg.spelling[name]; !ok {
+			switch name {
+			case "between", "isnan", "isinf", "isfinite":
+				c.want(n.args, TypeNumber, tok)
+				return TypeBool
+			case "ifgt", "ifge", "iflt", "ifle", "ifeq", "ifne":
+				c.want(n.args[:2], TypeNumber, tok)
+				return c.choice(tok, n.args[2:])
+			case "case":
+				return c.cases(n)
+			}
+		}
+		c.want(n.args, TypeNumber, tok)
+	}
+	return TypeNumber
+}
+
+// want checks that the arguments of tok have type tp
+func (c *checker) want(args []*node, tp Type, tok *token) {
+	for _, arg := range args {
+		if got := c.typeOf(arg); got != tp {
+			c.fail(tok, "%s expects a %v but is given a %v", opName(tok), tp, got)
+		}
+	}
+}
+
+// name returns the type of a local, a declared variable or a named
+// expression
+func (c *checker) name(tok *token) Type {
+	for i := len(c.scope) - 1; i >= 0; i-- {
+		if c.opts.sameName(c.scope[i].name, tok.v) {
+			return c.scope[i].tp
+		}
+	}
+	for name, tp := range c.schema {
+		if c.opts.sameName(name, tok.v) {
+			return tp
+		}
+	}
+	r, ok := c.reg.namedExpr(tok.v, c.opts.caseMode)
+	if !ok {
+		c.fail(tok, "undefined name %s", tok.v)
+		return TypeNumber
+	}
+	if c.visiting[r] {
+		return TypeNumber
+	}
+	// mismatches within named expressions are not the caller's
+	nc := &checker{reg: c.reg, opts: c.opts, schema: c.schema, visiting: c.visiting}
+	c.visiting[r] = true
+	defer delete(c.visiting, r)
+	return nc.check(r)
+}
+
+// form checks a form binding a number in its body, whose type it has. The
+// variable of with has the type of its value.
+func (c *checker) form(n *node, f *form, name string) Type {
+	tp := TypeNumber
+	for i, arg := range n.args {
+		switch {
+		case i == f.name || i == f.body:
+		case name == "with":
+			tp = c.typeOf(arg)
+		default:
+			c.want([]*node{arg}, TypeNumber, n.tok)
+		}
+	}
+	c.scope = append(c.scope, local{n.args[f.name].tok.v, tp})
+	defer func() { c.scope = c.scope[:len(c.scope)-1] }()
+	if name == "with" {
+		return c.typeOf(n.args[f.body])
+	}
+	c.want([]*node{n.args[f.body]}, TypeNumber, n.tok)
+	return TypeNumber
+}
+
+// piecewise checks that the conditions are booleans and the values have a
+// common type, which it returns
+func (c *checker) piecewise(n *node) Type {
+	var values []*node
+	for i, arg := range n.args {
+		if i%2 == 0 && i+1 < len(n.args) {
+			c.want([]*node{arg}, TypeBool, n.tok)
+		} else {
+			values = append(values, arg)
+		}
+	}
+	return c.choice(n.tok, values)
+}
+
+// cases checks case, whose conditions hold if they are non-zero and may
+// have either type, and returns the common type of its values
+func (c *checker) cases(n *node) Type {
+	var values []*node
+	for i, arg := range n.args {
+		if i%2 == 0 && i+1 < len(n.args) {
+			c.typeOf(arg)
+		} else {
+			values = append(values, arg)
+		}
+	}
+	return c.choice(n.tok, values)
+}
+
+// choice checks that the values tok chooses from have a common type, which
+// it returns
+func (c *checker) choice(tok *token, values []*node) Type {
+	tp := c.typeOf(values[0])
+	for _, v := range values[1:] {
+		if got := c.typeOf(v); got != tp {
+			c.fail(tok, "%s yields a %v or a %v", tok.v, tp, got)
+		}
+	}
+	return tp
+}
+
+// opName returns the operator or function of tok as written
+func opName(tok *token) string {
+	if tok.v == "@" {
+		return "-"
+	}
+	return tok.v
+}
+
+func (c *checker) fail(tok *token, format string, args ...interface{}) {
+	c.errs = append(c.errs, &TypeError{Pos: tok.pos, End: tok.pos + len(tok.v), Msg: fmt.Sprintf(format, args...)})
+}
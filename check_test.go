@@ -0,0 +1,76 @@
+package rpn
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestCheck(t *testing.T) {
+	if err := RegisterExpr("checkPositive", "checkX > 0"); err != nil {
+		t.Fatal(err)
+	}
+	schema := Schema{"x": TypeNumber, "y": TypeNumber, "ok": TypeBool, "checkX": TypeNumber}
+	cases := []struct {
+		in   string
+		errs []TypeError
+	}{
+		{"x * 2 + sqrt(y)", nil},
+		{"x > 0 && (ok || y != 1)", nil},
+		{"pw(x > 0, 1, ok, 2, 3) * 2", nil},
+		{"ok == (x < y)", nil},
+		{"with(b, x > y, b && ok)", nil},
+		{"sum(i, 1, x, i * y)", nil},
+		{"checkPositive && ok", nil},
+		{"ok + 1", []TypeError{{Pos: 3, End: 4, Msg: "+ expects a number but is given a boolean"}}},
+		{"x && ok", []TypeError{{Pos: 2, End: 4, Msg: "&& expects a boolean but is given a number"}}},
+		{"-ok", []TypeError{{Pos: 0, End: 1, Msg: "- expects a number but is given a boolean"}}},
+		{"sqrt(x < 1)", []TypeError{{Pos: 0, End: 4, Msg: "sqrt expects a number but is given a boolean"}}},
+		{"ok == 1", []TypeError{{Pos: 3, End: 5, Msg: "== compares a boolean with a number"}}},
+		{"pw(x, 1, ok)", []TypeError{
+			{Pos: 0, End: 2, Msg: "pw expects a boolean but is given a number"},
+			{Pos: 0, End: 2, Msg: "pw yields a number or a boolean"},
+		}},
+		{"z + with(b, ok, b * 2)", []TypeError{
+			{Pos: 0, End: 1, Msg: "undefined name z"},
+			{Pos: 18, End: 19, Msg: "* expects a number but is given a boolean"},
+		}},
+		{"sum(i, 1, ok, i)", []TypeError{{Pos: 0, End: 3, Msg: "sum expects a number but is given a boolean"}}},
+		{"between(x, 1, 2) && ok", nil},
+		{"isnan(x) || isinf(y) || isfinite(x)", nil},
+		{"ifgt(x, 1, ok, ok) && ok", nil},
+		{"ifgt(x, 1, ok, 2)", []TypeError{{Pos: 0, End: 4, Msg: "ifgt yields a boolean or a number"}}},
+		{"ifle(ok, 1, 2, 3)", []TypeError{{Pos: 0, End: 4, Msg: "ifle expects a number but is given a boolean"}}},
+		{"case(x, ok, ok, x > 1, ok) || ok", nil},
+		{"case(ok, 1, 2) + 1", nil},
+		{"case(x, 1, ok)", []TypeError{{Pos: 0, End: 4, Msg: "case yields a number or a boolean"}}},
+		{"between(x, 1, 2) + 1", []TypeError{{Pos: 17, End: 18, Msg: "+ expects a number but is given a boolean"}}},
+	}
+	for _, opts := range [][]Option{nil, {WithPrattParser()}} {
+		for _, tc := range cases {
+			r, err := New(tc.in, opts...)
+			if err != nil {
+				t.Errorf("can not convert [%v], err %v", tc.in, err)
+				continue
+			}
+			errs := r.Check(schema)
+			if len(errs) != len(tc.errs) {
+				t.Errorf("[%v] errors should be %v but %v", tc.in, tc.errs, errs)
+				continue
+			}
+			for i, err := range errs {
+				if *err != tc.errs[i] {
+					t.Errorf("[%v] error %d should be %+v but %+v", tc.in, i, tc.errs[i], *err)
+				}
+				if !errors.Is(err, ErrTypeMismatch) {
+					t.Errorf("[%v] error %v should be %v", tc.in, err, ErrTypeMismatch)
+				}
+			}
+		}
+	}
+
+	// an expression which can not be built into a tree is not checked
+	r := &RPN{reg: snapshot(), opts: defaultOptions(), postfix: []*token{{tp: tokenTypeOperator, v: "+"}}}
+	if errs := r.Check(schema); len(errs) != 1 || errs[0].Msg != "malformed expression" {
+		t.Errorf("errors should be malformed expression but %v", errs)
+	}
+}
@@ -0,0 +1,62 @@
+package rpn
+
+import "math/big"
+
+// Clone returns an independent deep copy of the expression, including its
+// cached result, memoized function results and evaluation cache. It lets
+// goroutines sharing a parsed expression each evaluate their own copy.
+func (r *RPN) Clone() *RPN {
+	c := &RPN{opts: r.opts, reg: r.reg, src: r.src}
+	// postfix reuses the tokens of infix, keep it that way in the copy
+	copies := make(map[*token]*token, len(r.infix))
+	c.infix = cloneTokens(r.infix, copies)
+	c.postfix = cloneTokens(r.postfix, copies)
+	if r.result != nil {
+		c.result = new(big.Rat).Set(r.result)
+	}
+	if r.memo != nil {
+		c.memo = r.memo.clone()
+	}
+	if r.cache != nil {
+		c.cache = r.cache.clone()
+	}
+	// the postfix notation compiled before, so it compiles again
+	c.prog, _ = compile(c, 1)
+	return c
+}
+
+func cloneTokens(tokens []*token, copies map[*token]*token) []*token {
+	if tokens == nil {
+		return nil
+	}
+	s := make([]*token, len(tokens))
+	for i, tok := range tokens {
+		c, ok := copies[tok]
+		if !ok {
+			t := *tok
+			c = &t
+			copies[tok] = c
+		}
+		s[i] = c
+	}
+	return s
+}
+
+func (m *memo) clone() *memo {
+	c := &memo{}
+	for i, s := range m.shards {
+		c.shards[i] = s.clone()
+	}
+	return c
+}
+
+func (c *lru) clone() *lru {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	n := newLRU(c.size)
+	for el := c.order.Back(); el != nil; el = el.Prev() {
+		entry := el.Value.(*lruEntry)
+		n.items[entry.key] = n.order.PushFront(&lruEntry{key: entry.key, rv: new(big.Rat).Set(entry.rv)})
+	}
+	return n
+}
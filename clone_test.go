@@ -0,0 +1,53 @@
+package rpn
+
+import (
+	"math/big"
+	"testing"
+)
+
+func TestClone(t *testing.T) {
+	for _, opts := range [][]Option{
+		nil,
+		{WithPrattParser(), WithMemoization(), WithEvalCache(4)},
+	} {
+		r, err := New("round(x, 1) * -(2 + 1) + 3", opts...)
+		if err != nil {
+			t.Fatal(err)
+		}
+		vars := map[string]*big.Rat{"x": big.NewRat(5, 4)}
+		if _, err := r.Eval(vars); err != nil {
+			t.Fatal(err)
+		}
+		c := r.Clone()
+		if !equal(r.Postfix(), c.Postfix()) {
+			t.Errorf("clone postfix should be %v but %v", r.Postfix(), c.Postfix())
+		}
+		for i := range r.postfix {
+			if r.postfix[i] == c.postfix[i] {
+				t.Fatalf("clone shares token %v", r.postfix[i].v)
+			}
+		}
+		result, err := c.Eval(vars)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if result.Cmp(big.NewRat(-9, 10)) != 0 {
+			t.Errorf("clone result should be -9/10 but %v", result)
+		}
+		if r.cache != nil && (c.cache == r.cache || c.cache.order.Len() != 1) {
+			t.Errorf("clone should have its own copy of the cache")
+		}
+		if r.memo != nil && (c.memo == r.memo || memoLen(c.memo) != memoLen(r.memo)) {
+			t.Errorf("clone should have its own copy of memoized results")
+		}
+	}
+}
+
+// memoLen returns the number of results held by m
+func memoLen(m *memo) int {
+	n := 0
+	for i := range m.shards {
+		n += m.shards[i].order.Len()
+	}
+	return n
+}
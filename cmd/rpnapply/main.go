@@ -0,0 +1,54 @@
+// Command rpnapply evaluates a formula for each row read from stdin, CSV with
+// a header or JSON lines, and writes the rows to stdout with the result
+// appended:
+//
+//	rpnapply -column total 'price * qty' < orders.csv
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/Pasithea/rpn"
+	"github.com/Pasithea/rpn/apply"
+)
+
+func main() {
+	format := flag.String("format", "csv", "input format, csv or jsonl")
+	column := flag.String("column", "result", "name of the result column")
+	decimals := flag.Int("decimals", 10, "digits kept of results without a finite decimal expansion")
+	memo := flag.Bool("memo", false, "compute function calls once per distinct arguments across rows")
+	flag.Usage = func() {
+		fmt.Fprintf(flag.CommandLine.Output(), "usage: %s [flags] formula\n", os.Args[0])
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+	if flag.NArg() != 1 {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	var opts []rpn.Option
+	if *memo {
+		opts = append(opts, rpn.WithMemoization())
+	}
+	p, err := rpn.Compile(flag.Arg(0), opts...)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, rpn.FormatError(err, flag.Arg(0)))
+		os.Exit(1)
+	}
+	o := apply.Options{Column: *column, Decimals: *decimals}
+	switch *format {
+	case "csv":
+		err = apply.CSV(os.Stdin, os.Stdout, p, o)
+	case "jsonl":
+		err = apply.JSONLines(os.Stdin, os.Stdout, p, o)
+	default:
+		err = fmt.Errorf("unknown format %q", *format)
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+}
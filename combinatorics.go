@@ -0,0 +1,71 @@
+package rpn
+
+import "math/big"
+
+// maxIntArg bounds the arguments of fib, binom and pow10, whose results grow
+// to tens of thousands of digits at the bound
+const maxIntArg = 100000
+
+// intArg returns x as an integer within ±maxIntArg, failing with
+// ErrInvalidArgument if it is not an integer and ErrOverflow if it is too
+// large
+func intArg(x *big.Rat) (int64, error) {
+	if !x.IsInt() {
+		return 0, ErrInvalidArgument
+	}
+	if x.Num().CmpAbs(big.NewInt(maxIntArg)) > 0 {
+		return 0, ErrOverflow
+	}
+	return x.Num().Int64(), nil
+}
+
+// fib(n) returns the nth Fibonacci number, fib(0) = 0 and fib(1) = 1
+func fib(o *options, args []*big.Rat) (*big.Rat, error) {
+	n, err := intArg(args[0])
+	if err != nil {
+		return nil, err
+	}
+	if n < 0 {
+		return nil, ErrInvalidArgument
+	}
+	// fast doubling: fib(2k) = fib(k) (2 fib(k+1) - fib(k)) and
+	// fib(2k+1) = fib(k)² + fib(k+1)²
+	a, b := big.NewInt(0), big.NewInt(1)
+	for i := 62; i >= 0; i-- {
+		t := new(big.Int).Lsh(b, 1)
+		t.Sub(t, a)
+		c := t.Mul(t, a)
+		d := new(big.Int).Mul(a, a)
+		d.Add(d, new(big.Int).Mul(b, b))
+		a, b = c, d
+		if n>>uint(i)&1 == 1 {
+			a, b = b, c.Add(c, d)
+		}
+	}
+	return new(big.Rat).SetInt(a), nil
+}
+
+// binom(n, k) returns the binomial coefficient n choose k, zero if k > n
+func binom(o *options, args []*big.Rat) (*big.Rat, error) {
+	n, err := intArg(args[0])
+	if err != nil {
+		return nil, err
+	}
+	k, err := intArg(args[1])
+	if err != nil {
+		return nil, err
+	}
+	if n < 0 || k < 0 {
+		return nil, ErrInvalidArgument
+	}
+	return new(big.Rat).SetInt(new(big.Int).Binomial(n, k)), nil
+}
+
+// pow10Func returns 10 to the integer power n exactly
+func pow10Func(o *options, args []*big.Rat) (*big.Rat, error) {
+	n, err := intArg(args[0])
+	if err != nil {
+		return nil, err
+	}
+	return pow10(int(n)), nil
+}
@@ -0,0 +1,48 @@
+package rpn
+
+import (
+	"errors"
+	"math/big"
+	"testing"
+)
+
+func TestCombinatorics(t *testing.T) {
+	fib100, _ := new(big.Rat).SetString("354224848179261915075")
+	binom100, _ := new(big.Rat).SetString("100891344545564193334812497256")
+	cases := []struct {
+		in     string
+		result *big.Rat
+		err    error
+	}{
+		{"fib(0)", new(big.Rat), nil},
+		{"fib(1)", big.NewRat(1, 1), nil},
+		{"fib(10)", big.NewRat(55, 1), nil},
+		{"fib(100)", fib100, nil},
+		{"fib(-1)", nil, ErrInvalidArgument},
+		{"fib(1.5)", nil, ErrInvalidArgument},
+		{"fib(100001)", nil, ErrOverflow},
+		{"binom(5, 2)", big.NewRat(10, 1), nil},
+		{"binom(100, 50)", binom100, nil},
+		{"binom(3, 5)", new(big.Rat), nil},
+		{"binom(5, -1)", nil, ErrInvalidArgument},
+		{"pow10(3)", big.NewRat(1000, 1), nil},
+		{"pow10(-2)", big.NewRat(1, 100), nil},
+		{"pow10(25) + 1 - pow10(25)", big.NewRat(1, 1), nil},
+		{"pow10(1000000)", nil, ErrOverflow},
+	}
+	for _, tc := range cases {
+		r, err := New(tc.in)
+		if err != nil {
+			t.Errorf("can not convert [%v], err %v", tc.in, err)
+			continue
+		}
+		result, err := r.Result()
+		if !errors.Is(err, tc.err) {
+			t.Errorf("[%v] err should be %v but %v", tc.in, tc.err, err)
+			continue
+		}
+		if err == nil && result.Cmp(tc.result) != 0 {
+			t.Errorf("[%v] result should be %v but %v", tc.in, tc.result.RatString(), result.RatString())
+		}
+	}
+}
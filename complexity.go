@@ -0,0 +1,66 @@
+package rpn
+
+// Complexity describes the size of an expression, so overly complex
+// expressions can be rejected or priced before they are evaluated
+type Complexity struct {
+	Tokens      int // tokens in the postfix notation
+	Depth       int // nesting depth of the syntax tree
+	Operators   int // operators applied, unary minus included
+	Functions   int // function calls
+	Identifiers int // references to variables and named expressions
+	Cost        int // estimated evaluation cost in arbitrary units
+}
+
+// estimated relative cost of evaluating a token
+const (
+	costOperand  = 1
+	costAdd      = 1
+	costMul      = 2
+	costDiv      = 4
+	costFloat    = 8 // operations computed in float64 and converted back
+	costFunction = 8
+)
+
+var operatorCosts = map[string]int{
+	"@": costAdd, "+": costAdd, "-": costAdd,
+	"*": costMul, "×": costMul,
+	"/": costDiv, "÷": costDiv, "//": costDiv, "div": costDiv,
+	"%": costFloat, "^": costFloat, "**": costFloat,
+}
+
+// Complexity returns the complexity metrics of the expression
+func (r *RPN) Complexity() Complexity {
+	c := Complexity{Tokens: len(r.postfix)}
+	depths := make([]int, 0, len(r.postfix))
+	for _, tok := range r.postfix {
+		switch tok.tp {
+		case tokenTypeOperand:
+			c.Cost += costOperand
+		case tokenTypeIdentifier:
+			c.Identifiers++
+			c.Cost += costOperand
+		case tokenTypeOperator:
+			c.Operators++
+			c.Cost += operatorCosts[tok.v]
+		case tokenTypeFunction:
+			c.Functions++
+			c.Cost += costFunction
+		}
+
+		n := arity(tok)
+		if n > len(depths) {
+			n = len(depths)
+		}
+		depth := 0
+		for _, d := range depths[len(depths)-n:] {
+			if d > depth {
+				depth = d
+			}
+		}
+		depths = append(depths[:len(depths)-n], depth+1)
+		if depth+1 > c.Depth {
+			c.Depth = depth + 1
+		}
+	}
+	return c
+}
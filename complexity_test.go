@@ -0,0 +1,24 @@
+package rpn
+
+import "testing"
+
+func TestComplexity(t *testing.T) {
+	cases := []struct {
+		in string
+		c  Complexity
+	}{
+		{"1", Complexity{Tokens: 1, Depth: 1, Cost: 1}},
+		{"-x + 2 * 3", Complexity{Tokens: 6, Depth: 3, Operators: 3, Identifiers: 1, Cost: 7}},
+		{"sin(2 ^ y) / round(1, 2)", Complexity{Tokens: 8, Depth: 4, Operators: 2, Functions: 2, Identifiers: 1, Cost: 32}},
+		{"max", Complexity{Tokens: 1, Depth: 1, Identifiers: 1, Cost: 1}},
+	}
+	for _, tc := range cases {
+		r, err := New(tc.in)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if c := r.Complexity(); c != tc.c {
+			t.Errorf("[%v] complexity should be %+v but %+v", tc.in, tc.c, c)
+		}
+	}
+}
@@ -0,0 +1,88 @@
+package rpn
+
+import (
+	"math/big"
+	"strings"
+)
+
+// ConstResolver returns the value of the constant name, such as a rate read
+// from configuration, and false if name is not a constant
+type ConstResolver func(name string) (*big.Rat, bool)
+
+// WithConstResolver consults resolve while parsing for each identifier which
+// is neither a named expression, a cell reference nor local to a form such
+// as sum, and writes the value it returns in place of the identifier. The
+// expression then depends on the constant as it was when parsed and the
+// identifier is no longer bound at evaluation.
+func WithConstResolver(resolve ConstResolver) Option {
+	return func(o *options) {
+		o.consts = resolve
+	}
+}
+
+// inlineConsts replaces the identifiers of postfix resolved as constants by
+// their values
+func (r *RPN) inlineConsts(postfix []*token) []*token {
+	roots, _, err := buildTree(postfix, 1, false)
+	if err != nil {
+		// left for the compiler to report
+		return postfix
+	}
+	return postfixOf(r.inline(roots[0], nil), nil)
+}
+
+// inline returns n with the constants it refers to replaced by their values,
+// the names in scope being local variables
+func (r *RPN) inline(n *node, scope []string) *node {
+	if n.tok.tp == tokenTypeIdentifier {
+		v, ok := r.constant(n.tok, scope)
+		if !ok {
+			return n
+		}
+		c := numberNode(v)
+		for _, tok := range postfixOf(c, nil) {
+			tok.pos = n.tok.pos
+		}
+		return c
+	}
+	var f *form
+	capture := false
+	if n.tok.tp == tokenTypeFunction {
+		name := strings.ToLower(n.tok.v)
+		if fn, ok := r.reg.functions[name]; ok && fn.call == nil {
+			f, capture = forms[name], name == "capture"
+		}
+	}
+	args := make([]*node, len(n.args))
+	for i, arg := range n.args {
+		switch {
+		case f != nil && i == f.name || capture && i == 0:
+			args[i] = arg
+		case f != nil && i == f.body:
+			args[i] = r.inline(arg, append(scope[:len(scope):len(scope)], n.args[f.name].tok.v))
+		default:
+			args[i] = r.inline(arg, scope)
+		}
+	}
+	return &node{tok: n.tok, args: args}
+}
+
+// constant returns the value of the identifier tok if it is a constant
+func (r *RPN) constant(tok *token, scope []string) (*big.Rat, bool) {
+	for _, name := range scope {
+		if r.opts.sameName(name, tok.v) {
+			return nil, false
+		}
+	}
+	if _, ok := holeIndex(tok); ok {
+		return nil, false
+	}
+	if _, ok := r.reg.namedExpr(tok.v, r.opts.caseMode); ok {
+		return nil, false
+	}
+	if _, _, ok := parseCell(tok.v); ok && r.opts.cells != nil {
+		return nil, false
+	}
+	v, ok := r.opts.consts(tok.v)
+	return v, ok && v != nil
+}
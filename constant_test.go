@@ -0,0 +1,65 @@
+package rpn
+
+import (
+	"math/big"
+	"strings"
+	"testing"
+)
+
+func TestConstResolver(t *testing.T) {
+	config := map[string]*big.Rat{
+		"vat_rate": big.NewRat(1, 5),
+		"third":    big.NewRat(1, 3),
+		"offset":   big.NewRat(-3, 2),
+		"i":        big.NewRat(100, 1),
+	}
+	resolve := func(name string) (*big.Rat, bool) {
+		v, ok := config[name]
+		return v, ok
+	}
+	cases := []struct {
+		in      string
+		postfix string
+		result  *big.Rat
+	}{
+		{"price * (1 + vat_rate)", "price 1 0.2 + *", big.NewRat(120, 1)},
+		{"third * 3 + offset", "1 3 / 3 * 1.5 @ +", big.NewRat(-1, 2)},
+		{"sum(i, 1, 3, i) + i", "i 1 3 i sum 100 +", big.NewRat(106, 1)},
+		{"with(vat_rate, 2, vat_rate) * vat_rate", "vat_rate 2 vat_rate with 0.2 *", big.NewRat(2, 5)},
+	}
+	for _, tc := range cases {
+		r, err := New(tc.in, WithConstResolver(resolve))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if postfix := strings.Join(r.Postfix(), " "); postfix != tc.postfix {
+			t.Errorf("[%v] postfix should be %v but %v", tc.in, tc.postfix, postfix)
+		}
+		result, err := r.Eval(map[string]*big.Rat{"price": big.NewRat(100, 1)})
+		if err != nil {
+			t.Errorf("[%v] %v", tc.in, err)
+			continue
+		}
+		if result.Cmp(tc.result) != 0 {
+			t.Errorf("[%v] result should be %v but %v", tc.in, tc.result, result)
+		}
+	}
+
+	// the value at parse time is kept
+	r, err := New("vat_rate * 10", WithConstResolver(resolve))
+	if err != nil {
+		t.Fatal(err)
+	}
+	config["vat_rate"] = big.NewRat(1, 10)
+	if result, err := r.Eval(map[string]*big.Rat{"vat_rate": big.NewRat(1, 1)}); err != nil || result.Cmp(big.NewRat(2, 1)) != 0 {
+		t.Errorf("result should be 2 but %v, err %v", result, err)
+	}
+
+	r, err = B().Var("third").Mul(B().Num(6)).Build(WithConstResolver(resolve))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result, err := r.Result(); err != nil || result.Cmp(big.NewRat(2, 1)) != 0 {
+		t.Errorf("built result should be 2 but %v, err %v", result, err)
+	}
+}
@@ -0,0 +1,165 @@
+package rpn
+
+import "strings"
+
+// ChangeKind is the kind of a difference between two expressions
+type ChangeKind uint8
+
+const (
+	ChangeConstant ChangeKind = iota // a number changed
+	ChangeAdded                      // a term or factor only in the second expression
+	ChangeRemoved                    // a term or factor only in the first expression
+	ChangeReplaced                   // a subexpression replaced by another
+)
+
+// Change is a difference between two expressions, From and To are the
+// subexpressions of the first and the second one, empty if there is none
+type Change struct {
+	Kind ChangeKind
+	From string
+	To   string
+}
+
+func (c Change) String() string {
+	switch c.Kind {
+	case ChangeConstant:
+		return "constant " + c.From + " changed to " + c.To
+	case ChangeAdded:
+		return "added " + c.To
+	case ChangeRemoved:
+		return "removed " + c.From
+	}
+	return c.From + " replaced by " + c.To
+}
+
+// Diff parses a and b with opts and returns how b differs structurally from
+// a, for reviewing changes of formulas. Expressions are compared in the
+// canonical form of Fingerprint. The terms of sums and the factors of
+// products are matched regardless of their order, so that adding a term is
+// reported as such rather than as a replaced sum. Identical expressions
+// have no changes.
+func Diff(a, b string, opts ...Option) ([]Change, error) {
+	ra, err := New(a, opts...)
+	if err != nil {
+		return nil, err
+	}
+	rb, err := New(b, opts...)
+	if err != nil {
+		return nil, err
+	}
+	x, _, err := buildTree(ra.postfix, 1, false)
+	if err != nil {
+		return nil, err
+	}
+	y, _, err := buildTree(rb.postfix, 1, false)
+	if err != nil {
+		return nil, err
+	}
+	d := &differ{r: ra}
+	d.diff(x[0], y[0])
+	return d.changes, nil
+}
+
+type differ struct {
+	r       *RPN
+	changes []Change
+}
+
+// term is a term of a sum, negated if subtracted, or a factor of a product
+type term struct {
+	n   *node
+	neg bool
+}
+
+func (d *differ) diff(x, y *node) {
+	if d.key(x) == d.key(y) {
+		return
+	}
+	cx, cy := d.r.canonical(x.tok), d.r.canonical(y.tok)
+	switch {
+	case x.tok.tp == tokenTypeOperand && y.tok.tp == tokenTypeOperand:
+		d.changes = append(d.changes, Change{ChangeConstant, x.tok.v, y.tok.v})
+	case (cx == "+" || cx == "-") && (cy == "+" || cy == "-"):
+		d.terms(d.flatten(x, false, "+", "-", nil), d.flatten(y, false, "+", "-", nil))
+	case cx == "*" && cy == "*":
+		d.terms(d.flatten(x, false, "*", "", nil), d.flatten(y, false, "*", "", nil))
+	case cx == cy && len(x.args) == len(y.args):
+		for i := range x.args {
+			d.diff(x.args[i], y.args[i])
+		}
+	default:
+		d.changes = append(d.changes, Change{ChangeReplaced, formatNode(x, d.r.opts.rightPow()), formatNode(y, d.r.opts.rightPow())})
+	}
+}
+
+// flatten appends the terms of n to s, n being a chain of the operators add
+// and sub, sub negating its right operand
+func (d *differ) flatten(n *node, neg bool, add, sub string, s []term) []term {
+	switch d.r.canonical(n.tok) {
+	case add:
+		return d.flatten(n.args[1], neg, add, sub, d.flatten(n.args[0], neg, add, sub, s))
+	case sub:
+		return d.flatten(n.args[1], !neg, add, sub, d.flatten(n.args[0], neg, add, sub, s))
+	}
+	return append(s, term{n, neg})
+}
+
+// terms matches the terms xs and ys, reporting those left unmatched as
+// removed or added unless a single one is left on each side
+func (d *differ) terms(xs, ys []term) {
+	matched := make([]bool, len(ys))
+	var removed []term
+	for _, x := range xs {
+		found := false
+		for i, y := range ys {
+			if !matched[i] && x.neg == y.neg && d.key(x.n) == d.key(y.n) {
+				matched[i], found = true, true
+				break
+			}
+		}
+		if !found {
+			removed = append(removed, x)
+		}
+	}
+	var added []term
+	for i, y := range ys {
+		if !matched[i] {
+			added = append(added, y)
+		}
+	}
+	if len(removed) == 1 && len(added) == 1 && removed[0].neg == added[0].neg {
+		d.diff(removed[0].n, added[0].n)
+		return
+	}
+	for _, t := range removed {
+		d.changes = append(d.changes, Change{Kind: ChangeRemoved, From: d.format(t)})
+	}
+	for _, t := range added {
+		d.changes = append(d.changes, Change{Kind: ChangeAdded, To: d.format(t)})
+	}
+}
+
+// format returns the infix notation of t
+func (d *differ) format(t term) string {
+	if t.neg {
+		return formatNode(negNode(t.n), d.r.opts.rightPow())
+	}
+	return formatNode(t.n, d.r.opts.rightPow())
+}
+
+// key returns the canonical form of the tree n
+func (d *differ) key(n *node) string {
+	var b strings.Builder
+	b.WriteString(d.r.canonical(n.tok))
+	if len(n.args) > 0 {
+		b.WriteByte('(')
+		for i, arg := range n.args {
+			if i > 0 {
+				b.WriteByte(',')
+			}
+			b.WriteString(d.key(arg))
+		}
+		b.WriteByte(')')
+	}
+	return b.String()
+}
@@ -0,0 +1,39 @@
+package rpn
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestDiff(t *testing.T) {
+	cases := []struct {
+		a, b    string
+		changes []string
+	}{
+		{"price * (1 + rate)", "(rate + 1.0) × price", nil},
+		{"price * (1 + 0.2)", "price * (1 + 0.25)", []string{"constant 0.2 changed to 0.25"}},
+		{"a + b - c", "b + a", []string{"removed -c"}},
+		{"a + b", "a + b - c + d * e", []string{"added -c", "added d * e"}},
+		{"a * b * c", "c * a", []string{"removed b"}},
+		{"a + b", "a - b", []string{"removed b", "added -b"}},
+		{"sqrt(x) + 1", "abs(x) + 1", []string{"sqrt(x) replaced by abs(x)"}},
+		{"round(x, 2)", "round(y, 3)", []string{"x replaced by y", "constant 2 changed to 3"}},
+		{"a + sin(2 * x)", "sin(3 * x) + a", []string{"constant 2 changed to 3"}},
+	}
+	for _, tc := range cases {
+		changes, err := Diff(tc.a, tc.b)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var got []string
+		for _, c := range changes {
+			got = append(got, c.String())
+		}
+		if !reflect.DeepEqual(got, tc.changes) {
+			t.Errorf("[%v] and [%v] changes should be %q but %q", tc.a, tc.b, tc.changes, got)
+		}
+	}
+	if _, err := Diff("1 +", "1"); err == nil {
+		t.Errorf("an invalid expression should fail")
+	}
+}
@@ -0,0 +1,47 @@
+package rpn
+
+import "math/big"
+
+// Env is a scope of variables consulted at evaluation time. A child scope
+// overrides the variables of its parent without copying them, so bindings
+// can be layered such as global constants, tenant overrides and the
+// variables of a request. The maps of an Env are not copied and must not be
+// modified while it is in use, it is otherwise safe for concurrent use.
+type Env struct {
+	parent *Env
+	vars   map[string]*big.Rat
+}
+
+// NewEnv returns a scope binding vars
+func NewEnv(vars map[string]*big.Rat) *Env {
+	return &Env{vars: vars}
+}
+
+// Child returns a scope binding vars and falling back to env for other names
+func (env *Env) Child(vars map[string]*big.Rat) *Env {
+	return &Env{parent: env, vars: vars}
+}
+
+// Lookup returns the value of name in the innermost scope binding it
+func (env *Env) Lookup(name string) (*big.Rat, bool) {
+	for ; env != nil; env = env.parent {
+		if rv := env.vars[name]; rv != nil {
+			return rv, true
+		}
+	}
+	return nil, false
+}
+
+// EvalEnv evaluates the expression with identifiers bound by env, see
+// Program.EvalEnv. Results are not cached by WithEvalCache.
+func (r *RPN) EvalEnv(env *Env) (*big.Rat, error) {
+	return r.prog.EvalEnv(env)
+}
+
+// EvalEnv evaluates the program with identifiers bound by the innermost
+// scope of env binding them, identifiers no scope binds refer to named
+// expressions
+func (p *Program) EvalEnv(env *Env) (*big.Rat, error) {
+	e := &evaluator{opts: &p.opts, memo: p.memo, reg: p.reg, env: env}
+	return e.run(p)
+}
@@ -0,0 +1,52 @@
+package rpn
+
+import (
+	"errors"
+	"math/big"
+	"testing"
+)
+
+func TestEnv(t *testing.T) {
+	if err := RegisterExpr("envGross", "envNet * (1 + envVat)"); err != nil {
+		t.Fatal(err)
+	}
+	global := NewEnv(map[string]*big.Rat{"envVat": big.NewRat(1, 5), "fee": big.NewRat(1, 1)})
+	tenant := global.Child(map[string]*big.Rat{"envVat": big.NewRat(1, 10)})
+	request := tenant.Child(map[string]*big.Rat{"envNet": big.NewRat(10, 1)})
+	cases := []struct {
+		in     string
+		env    *Env
+		opts   []Option
+		result *big.Rat
+		err    error
+	}{
+		{"envGross + fee", request, nil, big.NewRat(12, 1), nil},
+		{"envGross + fee", global.Child(map[string]*big.Rat{"envNet": big.NewRat(10, 1)}), nil, big.NewRat(13, 1), nil},
+		{"envGross", tenant, nil, nil, ErrUndefined},
+		{"ENVVAT", request, []Option{WithCaseSensitivity(CaseInsensitive)}, big.NewRat(1, 10), nil},
+		{"ENVVAT", request, nil, nil, ErrUndefined},
+		{"fee", nil, nil, nil, ErrUndefined},
+	}
+	for _, tc := range cases {
+		r, err := New(tc.in, tc.opts...)
+		if err != nil {
+			t.Errorf("can not convert [%v], err %v", tc.in, err)
+			continue
+		}
+		result, err := r.EvalEnv(tc.env)
+		if !errors.Is(err, tc.err) {
+			t.Errorf("[%v] err should be %v but %v", tc.in, tc.err, err)
+			continue
+		}
+		if err == nil && result.Cmp(tc.result) != 0 {
+			t.Errorf("[%v] result should be %v but %v", tc.in, tc.result, result)
+		}
+	}
+
+	if v, ok := request.Lookup("envVat"); !ok || v.Cmp(big.NewRat(1, 10)) != 0 {
+		t.Errorf("envVat should be 1/10 but %v", v)
+	}
+	if _, ok := tenant.Lookup("envNet"); ok {
+		t.Error("envNet should not be bound in the tenant scope")
+	}
+}
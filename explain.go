@@ -0,0 +1,143 @@
+package rpn
+
+import (
+	"math/big"
+	"strings"
+)
+
+// Explanation is the list of steps an evaluation took, such as
+// "subtotal (120) × rate (0.08) = 9.6" followed by "+ subtotal = 129.6"
+type Explanation []string
+
+// String joins the steps with semicolons
+func (x Explanation) String() string {
+	return strings.Join(x, "; ")
+}
+
+// Explain evaluates the expression like Eval and narrates each operator and
+// function applied in order. Variables and named expressions are shown with
+// their values the first time, a step continuing from the result of the
+// previous one leaves it out. The steps of named expressions precede their
+// use, the bodies of forms such as sum are not narrated.
+func (r *RPN) Explain(vars map[string]*big.Rat) (Explanation, error) {
+	p := r.prog
+	x := &explainer{seen: make(map[string]bool), regs: make(map[*Program][]operand)}
+	e := &evaluator{opts: &p.opts, memo: p.memo, reg: p.reg, vars: vars, explain: x}
+	if _, err := e.run(p); err != nil {
+		return nil, err
+	}
+	return x.steps, nil
+}
+
+// explainer narrates the instructions exec executes, keeping how each value
+// on the stack is shown
+type explainer struct {
+	steps Explanation
+	seen  map[string]bool // names already shown with their values
+	s     []operand
+	conds []operand // conditions of && and || awaiting their right operand
+	regs  map[*Program][]operand
+}
+
+// operand is how a value is shown in a step
+type operand struct {
+	label string
+	step  int // index of the step computing it, -1 for an input
+}
+
+// named labels the value of a variable or named expression
+func (x *explainer) named(name string, v *big.Rat) operand {
+	if x.seen[name] {
+		return operand{name, -1}
+	}
+	x.seen[name] = true
+	return operand{name + " (" + formatExplained(v) + ")", -1}
+}
+
+// result records a step computing v and returns v as an operand
+func (x *explainer) result(step string, v *big.Rat) operand {
+	x.steps = append(x.steps, step+" = "+formatExplained(v))
+	return operand{formatExplained(v), len(x.steps) - 1}
+}
+
+// pop removes the last n operands and returns them
+func (x *explainer) pop(n int) []operand {
+	ops := x.s[len(x.s)-n:]
+	x.s = x.s[:len(x.s)-n]
+	return ops
+}
+
+// expanded replaces the result of the named expression name, whose steps
+// have been narrated, by its name
+func (x *explainer) expanded(name string, v *big.Rat) {
+	x.s[len(x.s)-1] = x.named(name, v)
+}
+
+// exec follows the instruction in of p, rv being the value it pushed and nil
+// if it pushed none
+func (x *explainer) exec(p *Program, in instr, rv *big.Rat) {
+	switch in.op {
+	case opConst:
+		x.s = append(x.s, operand{in.tok.v, -1})
+	case opLoad:
+		x.s = append(x.s, x.named(p.names[in.arg], rv))
+	case opStore:
+		if x.regs[p] == nil {
+			x.regs[p] = make([]operand, p.regs)
+		}
+		x.regs[p][in.arg] = x.s[len(x.s)-1]
+	case opLoadReg:
+		x.s = append(x.s, x.regs[p][in.arg])
+	case opBool:
+		c := x.conds[len(x.conds)-1]
+		x.conds = x.conds[:len(x.conds)-1]
+		y := x.pop(1)[0]
+		x.s = append(x.s, x.result(x.binaryStep(c, in.tok.v, y), rv))
+	case opJumpFalse, opJumpTrue:
+		c := x.pop(1)[0]
+		if rv == nil {
+			x.conds = append(x.conds, c)
+			return
+		}
+		x.s = append(x.s, x.result(x.binaryStep(c, in.tok.v, operand{"…", -1}), rv))
+	case opSkip:
+		x.pop(1)
+	case opNeg:
+		a := x.pop(1)[0]
+		x.s = append(x.s, x.result("-"+a.label, rv))
+	case opCall, opForm:
+		labels := []string{"…"}
+		if in.op == opCall {
+			labels = labels[:0]
+			for _, a := range x.s[len(x.s)-in.argc:] {
+				labels = append(labels, a.label)
+			}
+		}
+		x.pop(in.argc)
+		x.s = append(x.s, x.result(in.tok.v+"("+strings.Join(labels, ", ")+")", rv))
+	default:
+		if in.op.counted() {
+			ab := x.pop(2)
+			x.s = append(x.s, x.result(x.binaryStep(ab[0], in.tok.v, ab[1]), rv))
+		}
+	}
+}
+
+// binaryStep shows the operator op applied to a and b, leaving a out if it
+// is the result of the previous step
+func (x *explainer) binaryStep(a operand, op string, b operand) string {
+	if a.step >= 0 && a.step == len(x.steps)-1 {
+		return op + " " + b.label
+	}
+	return a.label + " " + op + " " + b.label
+}
+
+// formatExplained renders v in decimal, rounded to 6 decimals and marked
+// as approximate if it has no finite decimal expansion
+func formatExplained(v *big.Rat) string {
+	if decimals, ok := DecimalPlaces(v); ok {
+		return v.FloatString(decimals)
+	}
+	s := strings.TrimRight(v.FloatString(6), "0")
+	return "≈" + strings.TrimSuffix(s, ".")
+}
@@ -0,0 +1,58 @@
+package rpn
+
+import (
+	"errors"
+	"math/big"
+	"testing"
+)
+
+func TestExplain(t *testing.T) {
+	if err := RegisterExpr("explainSubtotal", "price * qty"); err != nil {
+		t.Fatal(err)
+	}
+	vars := map[string]*big.Rat{
+		"subtotal": big.NewRat(120, 1), "rate": big.NewRat(8, 100),
+		"price": big.NewRat(10, 1), "qty": big.NewRat(3, 1), "x": big.NewRat(0, 1),
+	}
+	cases := []struct {
+		in      string
+		explain string
+		err     error
+	}{
+		{"subtotal × rate + subtotal", "subtotal (120) × rate (0.08) = 9.6; + subtotal = 129.6", nil},
+		{"round(subtotal / 7, 2)", "subtotal (120) / 7 = ≈17.142857; round(≈17.142857, 2) = 17.14", nil},
+		{"explainSubtotal * (1 + rate)",
+			"price (10) * qty (3) = 30; 1 + rate (0.08) = 1.08; explainSubtotal (30) * 1.08 = 32.4", nil},
+		{"-subtotal", "-subtotal (120) = -120", nil},
+		{"x != 0 && 1 / x > 2", "x (0) != 0 = 0; && … = 0", nil},
+		{"rate > 0 && qty > 2", "rate (0.08) > 0 = 1; qty (3) > 2 = 1; 1 && 1 = 1", nil},
+		{"sum(i, 1, qty, i) * 2", "sum(…) = 6; * 2 = 12", nil},
+		{"(rate + 1) * (rate + 1)", "rate (0.08) + 1 = 1.08; * 1.08 = 1.1664", nil},
+		{"ifgt(qty, 2, qty * 2, 0)", "qty (3) > 2 = 1; qty * 2 = 6", nil},
+		{"subtotal / x", "", ErrZeroDivision},
+	}
+	for _, tc := range cases {
+		r, err := New(tc.in)
+		if err != nil {
+			t.Errorf("can not convert [%v], err %v", tc.in, err)
+			continue
+		}
+		x, err := r.Explain(vars)
+		if !errors.Is(err, tc.err) {
+			t.Errorf("[%v] err should be %v but %v", tc.in, tc.err, err)
+			continue
+		}
+		if x.String() != tc.explain {
+			t.Errorf("[%v] explanation should be %q but %q", tc.in, tc.explain, x)
+		}
+	}
+
+	r, err := New("subtotal / x + 1", WithZeroDivisionValue(big.NewRat(0, 1)), WithPooling(true))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "subtotal (120) / x (0) = 0; + 1 = 1"
+	if x, err := r.Explain(vars); err != nil || x.String() != want {
+		t.Errorf("explanation should be %q but %q, err %v", want, x, err)
+	}
+}
@@ -0,0 +1,86 @@
+package rpn
+
+import (
+	"math/big"
+	"strings"
+)
+
+// RegisterExpr registers expr under name so other expressions can reference
+// it like a value, e.g. RegisterExpr("vat", "0.2") makes "100 * vat" valid.
+// Registering an existing name replaces it for expressions built afterwards.
+// expr is parsed with opts, such as WithSemanticsVersion, and evaluated with
+// the options of the expression referencing it.
+// It is safe to call concurrently with parsing and evaluation.
+func RegisterExpr(name, expr string, opts ...Option) error {
+	if !isName(name) || snapshot().typeOfToken(name) != tokenTypeIdentifier {
+		return ErrInvalidName
+	}
+	r, err := New(expr, opts...)
+	if err != nil {
+		return err
+	}
+	return update(func(g *registry) error {
+		if g.typeOfToken(name) != tokenTypeIdentifier {
+			return ErrInvalidName
+		}
+		g.exprs[name] = r
+		return nil
+	})
+}
+
+// expand evaluates the named expression with the options of the current
+// evaluation, failing if it refers back to itself
+func (e *evaluator) expand(name string) (*big.Rat, error) {
+	r, err := e.enter(name)
+	if err != nil {
+		return nil, err
+	}
+	defer e.leave()
+	return e.run(r.prog)
+}
+
+// frame is a named expression being evaluated and the local variables of
+// the evaluation it interrupts
+type frame struct {
+	name    string
+	locals  []*big.Rat
+	flocals []float64
+}
+
+// enter looks up the named expression about to be evaluated, leave must be
+// called once it has been. The named expression does not see the local
+// variables bound so far.
+func (e *evaluator) enter(name string) (*RPN, error) {
+	r, ok := e.reg.namedExpr(name, e.opts.caseMode)
+	if !ok {
+		return nil, ErrUndefined
+	}
+	for _, f := range e.expanding {
+		if f.name == name {
+			return nil, ErrCyclicExpression
+		}
+	}
+	e.expanding = append(e.expanding, frame{name, e.locals, e.flocals})
+	e.locals, e.flocals = nil, nil
+	return r, nil
+}
+
+func (e *evaluator) leave() {
+	f := e.expanding[len(e.expanding)-1]
+	e.locals, e.flocals = f.locals, f.flocals
+	e.expanding = e.expanding[:len(e.expanding)-1]
+}
+
+// namedExpr looks up the named expression name, regardless of case in the
+// CaseInsensitive mode
+func (g *registry) namedExpr(name string, c CaseSensitivity) (*RPN, bool) {
+	r, ok := g.exprs[name]
+	if !ok && c == CaseInsensitive {
+		for k, kr := range g.exprs {
+			if strings.EqualFold(k, name) {
+				return kr, true
+			}
+		}
+	}
+	return r, ok
+}
@@ -0,0 +1,90 @@
+package rpn
+
+import (
+	"errors"
+	"math/big"
+	"testing"
+)
+
+func TestRegisterExpr(t *testing.T) {
+	for name, expr := range map[string]string{
+		"exprVat":   "0.2",
+		"exprNet":   "round(99.5)",
+		"exprGross": "exprNet * (1 + exprVat)",
+		"exprLoopA": "exprLoopB + 1",
+		"exprLoopB": "2 * exprLoopA",
+	} {
+		if err := RegisterExpr(name, expr); err != nil {
+			t.Fatalf("register %v = [%v], err %v", name, expr, err)
+		}
+	}
+
+	cases := []struct {
+		in     string
+		result *big.Rat
+		err    error
+	}{
+		{"exprGross - exprNet", big.NewRat(20, 1), nil},
+		{"-exprVat", big.NewRat(-1, 5), nil},
+		{"exprLoopA", nil, ErrCyclicExpression},
+		{"exprGross * undefined", nil, ErrUndefined},
+	}
+	for _, tc := range cases {
+		r, err := New(tc.in)
+		if err != nil {
+			t.Errorf("can not convert infix notation [%v], err %v", tc.in, err)
+			continue
+		}
+		result, err := r.Result()
+		if !errors.Is(err, tc.err) {
+			t.Errorf("[%v] error should be %v but %v", tc.in, tc.err, err)
+			continue
+		}
+		if err == nil && result.Cmp(tc.result) != 0 {
+			t.Errorf("[%v] result should be %v but %v", tc.in, tc.result, result)
+		}
+	}
+}
+
+func TestRegisterExprInvalid(t *testing.T) {
+	cases := []struct {
+		name string
+		expr string
+		err  error
+	}{
+		{"sin", "1", ErrInvalidName},
+		{"div", "1", ErrInvalidName},
+		{"1x", "1", ErrInvalidName},
+		{"x y", "1", ErrInvalidName},
+		{"exprBroken", "(1", ErrUnrecognizedExpression},
+	}
+	for _, tc := range cases {
+		if err := RegisterExpr(tc.name, tc.expr); !errors.Is(err, tc.err) {
+			t.Errorf("register %v = [%v] error should be %v but %v", tc.name, tc.expr, tc.err, err)
+		}
+	}
+}
+
+func TestRegisterExprOptions(t *testing.T) {
+	if err := RegisterExpr("exprPowLeft", "2 ^ 3 ^ 2"); err != nil {
+		t.Fatal(err)
+	}
+	if err := RegisterExpr("exprPowRight", "2 ^ 3 ^ 2", WithSemanticsVersion(Semantics2)); err != nil {
+		t.Fatal(err)
+	}
+	for in, want := range map[string]*big.Rat{
+		"exprPowLeft":  big.NewRat(64, 1),
+		"exprPowRight": big.NewRat(512, 1),
+	} {
+		for _, v := range []SemanticsVersion{Semantics1, Semantics2} {
+			r, err := New(in, WithSemanticsVersion(v))
+			if err != nil {
+				t.Fatal(err)
+			}
+			result, err := r.Result()
+			if err != nil || result.Cmp(want) != 0 {
+				t.Errorf("[%v] with semantics %v should be %v but %v, err %v", in, v, want, result, err)
+			}
+		}
+	}
+}
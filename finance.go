@@ -0,0 +1,168 @@
+package rpn
+
+import (
+	"math"
+	"math/big"
+)
+
+// The financial functions follow the conventions of spreadsheets: money paid
+// out is negative and payments are due at the end of each period. Results
+// are rounded according to the precision context, or to financeDigits
+// significant digits with the configured rounding mode if it is exact, so
+// round(pmt(r, n, pv), 2) rounds the same way.
+
+// maxPeriods bounds the number of periods of the financial functions
+const maxPeriods = 10000
+
+// financeDigits is the number of significant digits financial results are
+// rounded to without a precision context, that of a decimal128
+const financeDigits = 34
+
+// financial rounds the result v of a financial function, the exact results
+// of many periods having hundreds of digits
+func financial(o *options, v *big.Rat) *big.Rat {
+	c := o.precision
+	if c.exact() {
+		c = PrecisionContext{Digits: financeDigits, Mode: o.rounding}
+	}
+	return c.round(v, v)
+}
+
+// growth returns (1 + rate)^nper, nper being a whole number of periods
+func growth(rate, nper *big.Rat) (*big.Rat, error) {
+	if !nper.IsInt() || nper.Num().CmpAbs(big.NewInt(maxPeriods)) > 0 {
+		return nil, ErrInvalidArgument
+	}
+	base := new(big.Rat).Add(ratOne, rate)
+	if base.Sign() == 0 {
+		return nil, ErrZeroDivision
+	}
+	n := nper.Num().Int64()
+	if n < 0 {
+		base.Inv(base)
+		n = -n
+	}
+	num := new(big.Int).Exp(base.Num(), big.NewInt(n), nil)
+	den := new(big.Int).Exp(base.Denom(), big.NewInt(n), nil)
+	return new(big.Rat).SetFrac(num, den), nil
+}
+
+// optional returns the optional argument i, zero if it is missing
+func optional(args []*big.Rat, i int) *big.Rat {
+	if i < len(args) {
+		return args[i]
+	}
+	return ratZero
+}
+
+// annuity returns the factor ((1 + rate)^nper - 1) / rate applied to the
+// payments, nper if rate is zero
+func annuity(rate, nper, g *big.Rat) *big.Rat {
+	if rate.Sign() == 0 {
+		return new(big.Rat).Set(nper)
+	}
+	a := new(big.Rat).Sub(g, ratOne)
+	return a.Quo(a, rate)
+}
+
+// pmt(rate, nper, pv, fv) returns the payment per period repaying pv, and
+// leaving the optional future value fv, over nper periods
+func pmt(o *options, args []*big.Rat) (*big.Rat, error) {
+	rate, nper, pv, fv := args[0], args[1], args[2], optional(args, 3)
+	g, err := growth(rate, nper)
+	if err != nil {
+		return nil, err
+	}
+	a := annuity(rate, nper, g)
+	if a.Sign() == 0 {
+		return nil, ErrZeroDivision
+	}
+	v := new(big.Rat).Mul(pv, g)
+	v.Add(v, fv)
+	return financial(o, v.Neg(v.Quo(v, a))), nil
+}
+
+// fv(rate, nper, pmt, pv) returns the future value of the payments pmt over
+// nper periods and the optional present value pv
+func fv(o *options, args []*big.Rat) (*big.Rat, error) {
+	rate, nper, payment, pv := args[0], args[1], args[2], optional(args, 3)
+	g, err := growth(rate, nper)
+	if err != nil {
+		return nil, err
+	}
+	v := new(big.Rat).Mul(pv, g)
+	v.Add(v, new(big.Rat).Mul(payment, annuity(rate, nper, g)))
+	return financial(o, v.Neg(v)), nil
+}
+
+// pv(rate, nper, pmt, fv) returns the present value of the payments pmt over
+// nper periods and the optional future value fv
+func pv(o *options, args []*big.Rat) (*big.Rat, error) {
+	rate, nper, payment, fv := args[0], args[1], args[2], optional(args, 3)
+	g, err := growth(rate, nper)
+	if err != nil {
+		return nil, err
+	}
+	v := new(big.Rat).Mul(payment, annuity(rate, nper, g))
+	v.Add(v, fv)
+	v.Quo(v, g)
+	return financial(o, v.Neg(v)), nil
+}
+
+// npv(rate, v1, v2, ...) returns the net present value of the cash flows
+// v1, v2, ... at the end of the periods 1, 2, ...
+func npv(o *options, args []*big.Rat) (*big.Rat, error) {
+	rate := args[0]
+	if len(args)-1 > maxPeriods {
+		return nil, ErrInvalidArgument
+	}
+	base := new(big.Rat).Add(ratOne, rate)
+	if base.Sign() == 0 {
+		return nil, ErrZeroDivision
+	}
+	discount := new(big.Rat).Inv(base)
+	factor := new(big.Rat).Set(discount)
+	sum := new(big.Rat)
+	for _, v := range args[1:] {
+		sum.Add(sum, new(big.Rat).Mul(v, factor))
+		factor.Mul(factor, discount)
+	}
+	return financial(o, sum), nil
+}
+
+// irr(v0, v1, ...) returns the internal rate of return of the cash flows
+// v0, v1, ... at the start of the periods 0, 1, ..., the rate making their
+// net present value zero, found with Newton's method from 10%
+func irr(o *options, args []*big.Rat) (*big.Rat, error) {
+	flows := make([]float64, len(args))
+	pos, neg := false, false
+	for i, v := range args {
+		flows[i], _ = v.Float64()
+		pos = pos || v.Sign() > 0
+		neg = neg || v.Sign() < 0
+	}
+	if !pos || !neg || len(flows) > maxPeriods {
+		return nil, ErrInvalidArgument
+	}
+	rate := 0.1
+	for i := 0; i < solveSteps; i++ {
+		f, df, d := 0.0, 0.0, 1.0
+		for t, v := range flows {
+			f += v / d
+			df -= float64(t) * v / (d * (1 + rate))
+			d *= 1 + rate
+		}
+		if df == 0 || math.IsNaN(f) || math.IsInf(f, 0) {
+			break
+		}
+		next := rate - f/df
+		if math.Abs(next-rate) <= solveTolerance*math.Max(1, math.Abs(rate)) {
+			return setFinite(new(big.Rat), next)
+		}
+		if next <= -1 {
+			next = (rate - 1) / 2
+		}
+		rate = next
+	}
+	return nil, ErrInvalidArgument
+}
@@ -0,0 +1,92 @@
+package rpn
+
+import (
+	"errors"
+	"math/big"
+	"testing"
+)
+
+func TestFinance(t *testing.T) {
+	cases := []struct {
+		in     string
+		result *big.Rat
+		err    error
+	}{
+		{"pmt(0, 4, 100)", big.NewRat(-25, 1), nil},
+		{"pmt(0.1, 2, 100)", big.NewRat(-1210, 21), nil},
+		{"pmt(0.1, 2, 0, 210)", big.NewRat(-100, 1), nil},
+		{"round(pmt(0.05 / 12, 360, 200000), 2)", big.NewRat(-107364, 100), nil},
+		{"pmt(0.1, 2.5, 100)", nil, ErrInvalidArgument},
+		{"pmt(-1, 2, 100)", nil, ErrZeroDivision},
+		{"fv(0.1, 2, -100)", big.NewRat(210, 1), nil},
+		{"fv(0.1, 2, 0, -100)", big.NewRat(121, 1), nil},
+		{"fv(0, 3, -10, -5)", big.NewRat(35, 1), nil},
+		{"pv(0.1, 2, -100)", big.NewRat(21000, 121), nil},
+		{"pv(0, 3, -10, -5)", big.NewRat(35, 1), nil},
+		{"pv(0.1, 2, 0, 121)", big.NewRat(-100, 1), nil},
+		{"npv(0.1, 110, 121)", big.NewRat(200, 1), nil},
+		{"npv(0, 1, 2, 3)", big.NewRat(6, 1), nil},
+		{"npv(-1, 1)", nil, ErrZeroDivision},
+		{"irr(-100, 110)", big.NewRat(1, 10), nil},
+		{"irr(-100, 0, 121)", big.NewRat(1, 10), nil},
+		{"irr(100, 10)", nil, ErrInvalidArgument},
+	}
+	for _, tc := range cases {
+		r, err := New(tc.in)
+		if err != nil {
+			t.Errorf("can not convert [%v], err %v", tc.in, err)
+			continue
+		}
+		result, err := r.Result()
+		if !errors.Is(err, tc.err) {
+			t.Errorf("[%v] err should be %v but %v", tc.in, tc.err, err)
+			continue
+		}
+		if err != nil {
+			continue
+		}
+		// irr is found numerically
+		d := new(big.Rat).Sub(result, tc.result)
+		if d.Abs(d).Cmp(big.NewRat(1, 1000000000000)) > 0 {
+			t.Errorf("[%v] result should be %v but %v", tc.in, tc.result.RatString(), result.RatString())
+		}
+	}
+}
+
+func TestFinanceRounding(t *testing.T) {
+	limit := new(big.Int).Exp(big.NewInt(10), big.NewInt(financeDigits), nil)
+	cases := []struct {
+		in     string
+		opts   []Option
+		result string
+	}{
+		{"pmt(0.1, 2, 100)", nil, "-57.61904761904761904761904761904762"},
+		{"pmt(0.1, 2, 100)", []Option{WithRoundingMode(big.ToZero)}, "-57.61904761904761904761904761904761"},
+		{"pmt(0.05 / 12, 360, 200000)", []Option{WithPrecision(PrecisionContext{Digits: 6})}, "-1073.64"},
+		{"pv(0.1, 2, -100)", []Option{WithPrecision(PrecisionContext{Digits: 3, Mode: big.ToZero})}, "173"},
+	}
+	for _, tc := range cases {
+		r, err := New(tc.in, tc.opts...)
+		if err != nil {
+			t.Fatal(err)
+		}
+		want, _ := new(big.Rat).SetString(tc.result)
+		result, err := r.Result()
+		if err != nil || result.Cmp(want) != 0 {
+			t.Errorf("[%v] result should be %v but %v, err %v", tc.in, tc.result, result, err)
+		}
+	}
+	for _, in := range []string{"pmt(0.05 / 12, 360, 200000)", "fv(0.01, 600, -10)", "pv(0.01, 600, -10)", "npv(0.03, 1, 2, 3, 4, 5, 6, 7)"} {
+		r, err := New(in)
+		if err != nil {
+			t.Fatal(err)
+		}
+		result, err := r.Result()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if result.Num().CmpAbs(limit) >= 0 && result.Denom().Cmp(limit) >= 0 {
+			t.Errorf("[%v] should be rounded to %d digits but %v", in, financeDigits, result.RatString())
+		}
+	}
+}
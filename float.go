@@ -0,0 +1,221 @@
+package rpn
+
+import (
+	"math"
+	"math/big"
+	"strings"
+)
+
+// EvalFloat64 evaluates the expression in float64 arithmetic, see
+// Program.EvalFloat64
+func (r *RPN) EvalFloat64(vars map[string]float64) (float64, error) {
+	return r.prog.EvalFloat64(vars)
+}
+
+// EvalFloat64 evaluates the program in float64 arithmetic with identifiers
+// bound to the values in vars. It trades the exactness of Eval for speed and
+// follows IEEE 754: -0 is kept, 0^0 is 1, 0*Inf is NaN and results too large
+// to represent are infinite. Dividing by zero, including raising 0 to a
+// negative power, follows the ZeroDivision policy.
+func (p *Program) EvalFloat64(vars map[string]float64) (float64, error) {
+	e := &evaluator{opts: &p.opts, memo: p.memo, reg: p.reg, fvars: vars}
+	return e.runFloat(p)
+}
+
+// runFloat executes the code of p in float64 arithmetic
+func (e *evaluator) runFloat(p *Program) (float64, error) {
+	regs := make([]float64, p.regs)
+	s := make([]float64, 0, p.stack)
+	for pc := 0; pc < len(p.code); {
+		in := p.code[pc]
+		pc++
+		if in.op.counted() {
+			if err := e.spend(); err != nil {
+				return 0, err
+			}
+		}
+		switch in.op {
+		case opConst:
+			s = append(s, p.fconsts[in.arg])
+		case opLoad:
+			name := p.names[in.arg]
+			if v, ok := e.fvariable(name); ok {
+				s = append(s, v)
+				continue
+			}
+			v, err := e.expandFloat(name)
+			if err != nil {
+				return 0, err
+			}
+			s = append(s, v)
+		case opStore:
+			regs[in.arg] = s[len(s)-1]
+		case opLoadReg:
+			s = append(s, regs[in.arg])
+		case opCapture:
+		case opLocal:
+			s = append(s, e.flocals[in.arg])
+		case opForm:
+			n := len(s) - in.argc
+			sub := p.subs[in.arg]
+			v, err := sub.form.evalFloat(e, sub.body, s[n:])
+			if err != nil {
+				return 0, err
+			}
+			s = append(s[:n], v)
+		case opBool:
+			s[len(s)-1] = floatBool(s[len(s)-1] != 0)
+		case opJumpFalse, opJumpTrue:
+			x := s[len(s)-1]
+			s = s[:len(s)-1]
+			if (x != 0) == (in.op == opJumpTrue) {
+				s = append(s, floatBool(in.op == opJumpTrue))
+				pc = in.arg
+			}
+		case opSkip:
+			if s[len(s)-1] == 0 {
+				pc = in.arg
+			}
+			s = s[:len(s)-1]
+		case opJump:
+			pc = in.arg
+		case opNoMatch:
+			return 0, ErrInvalidArgument
+		case opNeg:
+			s[len(s)-1] = -s[len(s)-1]
+		case opCall:
+			n := len(s) - in.argc
+			v, err := e.callFloat(p.funcNames[in.arg], p.funcs[in.arg], s[n:])
+			if err != nil {
+				return 0, err
+			}
+			s = append(s[:n], v)
+		default:
+			x, y := s[len(s)-2], s[len(s)-1]
+			v, err := e.binaryFloat(in.op, x, y)
+			if err != nil {
+				return 0, err
+			}
+			s = append(s[:len(s)-2], v)
+		}
+	}
+	return s[len(s)-1], nil
+}
+
+// fvariable returns the value of the variable name in float mode and
+// whether it is bound
+func (e *evaluator) fvariable(name string) (float64, bool) {
+	if v, ok := e.fvars[name]; ok {
+		return v, true
+	}
+	if e.opts.caseMode == CaseInsensitive {
+		for k, v := range e.fvars {
+			if strings.EqualFold(k, name) {
+				return v, true
+			}
+		}
+	}
+	if v := e.cell(name); v != nil {
+		f, _ := v.Float64()
+		return f, true
+	}
+	return 0, false
+}
+
+// expandFloat evaluates the named expression in float64 arithmetic. The
+// names inf and nan, in any case, are +Inf and NaN unless bound.
+func (e *evaluator) expandFloat(name string) (float64, error) {
+	if _, ok := e.reg.namedExpr(name, e.opts.caseMode); !ok {
+		switch strings.ToLower(name) {
+		case "inf":
+			return math.Inf(1), nil
+		case "nan":
+			return math.NaN(), nil
+		}
+	}
+	r, err := e.enter(name)
+	if err != nil {
+		return 0, err
+	}
+	defer e.leave()
+	return e.runFloat(r.prog)
+}
+
+// binaryFloat applies a binary operator in float64 arithmetic, dividing by
+// zero or taking a remainder of it follows the configured policy, raising
+// a negative number to a fractional power fails from Semantics2 on
+func (e *evaluator) binaryFloat(op opcode, x, y float64) (float64, error) {
+	if y == 0 && (op == opDiv || op == opFloorDiv || op == opMod) || op == opPow && x == 0 && y < 0 {
+		switch e.opts.zeroDiv {
+		case ZeroDivisionValue:
+			f, _ := e.opts.zeroValue.Float64()
+			return f, nil
+		case ZeroDivisionError:
+			return 0, ErrZeroDivision
+		}
+	}
+	switch op {
+	case opAdd:
+		return x + y, nil
+	case opSub:
+		return x - y, nil
+	case opMul:
+		return x * y, nil
+	case opDiv:
+		return x / y, nil
+	case opFloorDiv:
+		return math.Floor(x / y), nil
+	case opMod:
+		return math.Mod(x, y), nil
+	case opPow:
+		v := math.Pow(x, y)
+		if e.opts.semantics >= Semantics2 && math.IsNaN(v) && !math.IsNaN(x) && !math.IsNaN(y) {
+			return 0, ErrInvalidArgument
+		}
+		return v, nil
+	}
+	if op >= opEq && op <= opGe {
+		// comparisons involving NaN only hold for !=
+		if math.IsNaN(x) || math.IsNaN(y) {
+			return floatBool(op == opNe), nil
+		}
+		c := 0
+		if x < y {
+			c = -1
+		} else if x > y {
+			c = 1
+		}
+		return floatBool(compare(op, c)), nil
+	}
+	return 0, ErrUnrecognizedExpression
+}
+
+// floatBool returns 1 if b holds and 0 otherwise
+func floatBool(b bool) float64 {
+	if b {
+		return 1
+	}
+	return 0
+}
+
+// callFloat calls fn in float64 arithmetic. Functions without a float64
+// implementation are called with exact arguments, they yield NaN when given
+// an infinite or NaN argument.
+func (e *evaluator) callFloat(name string, fn function, args []float64) (float64, error) {
+	if fn.fcall != nil {
+		return e.fcall(name, fn, args), nil
+	}
+	rargs := make([]*big.Rat, len(args))
+	for i, f := range args {
+		if math.IsInf(f, 0) || math.IsNaN(f) {
+			return math.NaN(), nil
+		}
+		rargs[i] = new(big.Rat).SetFloat64(f)
+	}
+	rv, err := e.call(name, fn, rargs)
+	if err != nil {
+		return 0, err
+	}
+	f, _ := rv.Float64()
+	return f, nil
+}
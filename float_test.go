@@ -0,0 +1,205 @@
+package rpn
+
+import (
+	"errors"
+	"math"
+	"math/big"
+	"testing"
+)
+
+func TestEvalFloat64(t *testing.T) {
+	for _, tc := range testCase {
+		if !tc.canConv || !tc.canCalc {
+			continue
+		}
+		r, err := New(tc.in)
+		if err != nil {
+			t.Errorf("can not convert [%v], err %v", tc.in, err)
+			continue
+		}
+		f, err := r.EvalFloat64(nil)
+		if err != nil {
+			t.Errorf("[%v] err %v", tc.in, err)
+			continue
+		}
+		want, _ := tc.result.Float64()
+		if math.Abs(f-want) > 1e-9*math.Max(1, math.Abs(want)) {
+			t.Errorf("[%v] result should be %v but %v", tc.in, want, f)
+		}
+	}
+
+	r, err := New("x * 2 + y")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if f, err := r.EvalFloat64(map[string]float64{"x": 1.5, "y": 1}); err != nil || f != 4 {
+		t.Errorf("result should be 4 but %v, err %v", f, err)
+	}
+	if _, err := r.EvalFloat64(map[string]float64{"x": 1}); !errors.Is(err, ErrUndefined) {
+		t.Errorf("err should be %v but %v", ErrUndefined, err)
+	}
+}
+
+func TestZeroDivision(t *testing.T) {
+	inf, nan := math.Inf(1), math.NaN()
+	cases := []struct {
+		in     string
+		opts   []Option
+		result *big.Rat // exact result, nil for an error
+		float  float64
+		err    bool // float mode fails
+	}{
+		{"1 / 0", nil, nil, 0, true},
+		{"1 // 0", nil, nil, 0, true},
+		{"8 % 0", nil, nil, 0, true},
+		{"8 % (1 - 1)", nil, nil, 0, true},
+		{"8 % 0", []Option{WithZeroDivision(ZeroDivisionIEEE)}, nil, nan, false},
+		{"8 % 0", []Option{WithZeroDivisionValue(big.NewRat(7, 1))}, big.NewRat(7, 1), 7, false},
+		{"1 / 0", []Option{WithZeroDivision(ZeroDivisionIEEE)}, nil, inf, false},
+		{"-1 / 0", []Option{WithZeroDivision(ZeroDivisionIEEE)}, nil, -inf, false},
+		{"0 / 0", []Option{WithZeroDivision(ZeroDivisionIEEE)}, nil, nan, false},
+		{"1 / 0 + 1", []Option{WithZeroDivision(ZeroDivisionIEEE)}, nil, inf, false},
+		{"1 div 0", []Option{WithZeroDivision(ZeroDivisionIEEE)}, nil, inf, false},
+		{"1 / 0 + 1", []Option{WithZeroDivisionValue(big.NewRat(-1, 2))}, big.NewRat(1, 2), 0.5, false},
+		{"4 // (2 - 2)", []Option{WithZeroDivisionValue(new(big.Rat))}, new(big.Rat), 0, false},
+		{"3 / 2", []Option{WithZeroDivisionValue(new(big.Rat))}, big.NewRat(3, 2), 1.5, false},
+	}
+	for _, tc := range cases {
+		r, err := New(tc.in, tc.opts...)
+		if err != nil {
+			t.Errorf("can not convert [%v], err %v", tc.in, err)
+			continue
+		}
+		result, err := r.Result()
+		if tc.result == nil {
+			if !errors.Is(err, ErrZeroDivision) {
+				t.Errorf("[%v] err should be %v but %v", tc.in, ErrZeroDivision, err)
+			}
+		} else if err != nil || result.Cmp(tc.result) != 0 {
+			t.Errorf("[%v] result should be %v but %v, err %v", tc.in, tc.result, result, err)
+		}
+
+		f, err := r.EvalFloat64(nil)
+		switch {
+		case tc.err:
+			if !errors.Is(err, ErrZeroDivision) {
+				t.Errorf("[%v] float err should be %v but %v", tc.in, ErrZeroDivision, err)
+			}
+		case err != nil:
+			t.Errorf("[%v] float err %v", tc.in, err)
+		case math.IsNaN(tc.float) && !math.IsNaN(f), !math.IsNaN(tc.float) && f != tc.float:
+			t.Errorf("[%v] float result should be %v but %v", tc.in, tc.float, f)
+		}
+	}
+}
+
+func TestCornerCases(t *testing.T) {
+	inf, nan := math.Inf(1), math.NaN()
+	cases := []struct {
+		in     string
+		opts   []Option
+		result *big.Rat // exact result, nil for err
+		err    error
+		float  float64
+	}{
+		{"-0", nil, new(big.Rat), nil, math.Copysign(0, -1)},
+		{"0 * -1", nil, new(big.Rat), nil, math.Copysign(0, -1)},
+		{"-0 + 0", nil, new(big.Rat), nil, 0},
+		{"0 ^ 0", nil, big.NewRat(1, 1), nil, 1},
+		{"0 ^ 2", nil, new(big.Rat), nil, 0},
+		{"0 ^ (0 - 1)", nil, nil, ErrZeroDivision, 0},
+		{"0 ^ (0 - 1)", []Option{WithZeroDivision(ZeroDivisionIEEE)}, nil, ErrZeroDivision, inf},
+		{"0 * (1 / 0)", []Option{WithZeroDivision(ZeroDivisionIEEE)}, nil, ErrZeroDivision, nan},
+		{"2 ^ 10000", nil, nil, ErrOverflow, inf},
+		{"(0 - 8) ^ 0.5", nil, nil, ErrInvalidArgument, nan},
+		{"sqrt(-1)", nil, nil, ErrInvalidArgument, nan},
+		{"ln(0)", nil, nil, ErrInvalidArgument, -inf},
+	}
+	for _, tc := range cases {
+		r, err := New(tc.in, tc.opts...)
+		if err != nil {
+			t.Errorf("can not convert [%v], err %v", tc.in, err)
+			continue
+		}
+		result, err := r.Result()
+		if tc.result == nil {
+			if !errors.Is(err, tc.err) {
+				t.Errorf("[%v] err should be %v but %v", tc.in, tc.err, err)
+			}
+		} else if err != nil || result.Cmp(tc.result) != 0 {
+			t.Errorf("[%v] result should be %v but %v, err %v", tc.in, tc.result, result, err)
+		}
+
+		f, err := r.EvalFloat64(nil)
+		if tc.err == ErrZeroDivision && len(tc.opts) == 0 {
+			if !errors.Is(err, ErrZeroDivision) {
+				t.Errorf("[%v] float err should be %v but %v", tc.in, ErrZeroDivision, err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("[%v] float err %v", tc.in, err)
+			continue
+		}
+		if math.IsNaN(tc.float) != math.IsNaN(f) || !math.IsNaN(f) &&
+			(f != tc.float || math.Signbit(f) != math.Signbit(tc.float)) {
+			t.Errorf("[%v] float result should be %v but %v", tc.in, tc.float, f)
+		}
+	}
+}
+
+func TestFloatSpecialValues(t *testing.T) {
+	cases := []struct {
+		in   string
+		vars map[string]float64
+		want float64
+	}{
+		{"inf", nil, math.Inf(1)},
+		{"-inf", nil, math.Inf(-1)},
+		{"-INF + 1", nil, math.Inf(-1)},
+		{"nan", nil, math.NaN()},
+		{"isnan(nan)", nil, 1},
+		{"isnan(inf - inf)", nil, 1},
+		{"isnan(x)", map[string]float64{"x": 1}, 0},
+		{"isinf(-inf)", nil, 1},
+		{"isinf(1 / 0)", nil, 1},
+		{"isinf(nan)", nil, 0},
+		{"isfinite(x)", map[string]float64{"x": math.NaN()}, 0},
+		{"isfinite(2)", nil, 1},
+		{"pw(isnan(x), 0, x)", map[string]float64{"x": math.NaN()}, 0},
+		{"inf", map[string]float64{"inf": 3}, 3},
+	}
+	for _, tc := range cases {
+		r, err := New(tc.in, WithZeroDivision(ZeroDivisionIEEE))
+		if err != nil {
+			t.Errorf("can not convert [%v], err %v", tc.in, err)
+			continue
+		}
+		f, err := r.EvalFloat64(tc.vars)
+		if err != nil {
+			t.Errorf("[%v] err %v", tc.in, err)
+			continue
+		}
+		if f != tc.want && !(math.IsNaN(f) && math.IsNaN(tc.want)) {
+			t.Errorf("[%v] result should be %v but %v", tc.in, tc.want, f)
+		}
+	}
+
+	// exact numbers are finite
+	for in, want := range map[string]int64{"isnan(1)": 0, "isinf(1 / 3)": 0, "isfinite(2)": 1} {
+		r, err := New(in)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if rv, err := r.Result(); err != nil || rv.Cmp(big.NewRat(want, 1)) != 0 {
+			t.Errorf("[%v] result should be %v but %v, err %v", in, want, rv, err)
+		}
+	}
+	r, err := New("inf")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := r.Result(); !errors.Is(err, ErrUndefined) {
+		t.Errorf("inf should be undefined in exact evaluations but err %v", err)
+	}
+}
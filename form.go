@@ -0,0 +1,166 @@
+package rpn
+
+import (
+	"math"
+	"math/big"
+	"strings"
+)
+
+// maxIterations bounds the number of times a form evaluates its body
+const maxIterations = 1000000
+
+// form is a builtin function binding a local variable named by one of its
+// arguments within its body argument. The body is compiled into a program of
+// its own, evaluated as often as the form needs, so common subexpressions
+// are never shared across it.
+type form struct {
+	name int // index of the argument naming the variable
+	body int // index of the body argument
+	// eval and evalFloat are given the values of the other arguments, eval
+	// must return a value nothing else refers to
+	eval      func(e *evaluator, body *Program, args []*big.Rat) (*big.Rat, error)
+	evalFloat func(e *evaluator, body *Program, args []float64) (float64, error)
+}
+
+// forms are called like functions, the functions table holds their arity
+var forms = map[string]*form{
+	"with": {0, 2, func(e *evaluator, body *Program, args []*big.Rat) (*big.Rat, error) {
+		return e.bind(body, args[0])
+	}, func(e *evaluator, body *Program, args []float64) (float64, error) {
+		return e.bindFloat(body, args[0])
+	}},
+	"sum":  seriesForm(false),
+	"prod": seriesForm(true),
+	// integrate(body, x, a, b) and solve(body, x, guess)
+	"integrate": numericForm(integrate),
+	"solve":     numericForm(solve),
+}
+
+// seriesForm builds sum(i, lo, hi, body) or prod(i, lo, hi, body), adding or
+// multiplying body for i from lo to hi. The bounds must be integers, an
+// empty range yields 0 or 1.
+func seriesForm(prod bool) *form {
+	return &form{0, 3, func(e *evaluator, body *Program, args []*big.Rat) (*big.Rat, error) {
+		if !args[0].IsInt() || !args[1].IsInt() {
+			return nil, ErrInvalidArgument
+		}
+		lo, hi := args[0].Num(), args[1].Num()
+		if new(big.Int).Sub(hi, lo).Cmp(big.NewInt(maxIterations)) >= 0 {
+			return nil, ErrInvalidArgument
+		}
+		acc := new(big.Rat)
+		if prod {
+			acc.SetInt64(1)
+		}
+		for i := new(big.Int).Set(lo); i.Cmp(hi) <= 0; i.Add(i, big.NewInt(1)) {
+			v, err := e.bind(body, new(big.Rat).SetInt(i))
+			if err != nil {
+				return nil, err
+			}
+			if prod {
+				acc.Mul(acc, v)
+			} else {
+				acc.Add(acc, v)
+			}
+		}
+		return acc, nil
+	}, func(e *evaluator, body *Program, args []float64) (float64, error) {
+		lo, hi := args[0], args[1]
+		if math.IsInf(lo, 0) || math.IsInf(hi, 0) ||
+			lo != math.Trunc(lo) || hi != math.Trunc(hi) || hi-lo >= maxIterations {
+			return 0, ErrInvalidArgument
+		}
+		acc := 0.0
+		if prod {
+			acc = 1
+		}
+		for i := lo; i <= hi; i++ {
+			v, err := e.bindFloat(body, i)
+			if err != nil {
+				return 0, err
+			}
+			if prod {
+				acc *= v
+			} else {
+				acc += v
+			}
+		}
+		return acc, nil
+	}}
+}
+
+// sub is a form called by a program together with its compiled body
+type sub struct {
+	form *form
+	body *Program
+}
+
+// formOf returns the form n calls, nil if it is not a form
+func (c *compiler) formOf(n *node) *form {
+	return forms[c.builtin(n)]
+}
+
+// builtin returns the lower case name of the function n calls if the
+// compiler implements it, such as a form. A function registered under its
+// name replaces it.
+func (c *compiler) builtin(n *node) string {
+	if n.tok.tp != tokenTypeFunction {
+		return ""
+	}
+	name := strings.ToLower(n.tok.v)
+	if fn, ok := c.p.reg.functions[name]; !ok || fn.call != nil {
+		return ""
+	}
+	return name
+}
+
+// form emits a call of f, the arguments other than the variable name and the
+// body are evaluated before the call
+func (c *compiler) form(n *node, f *form) error {
+	name := n.args[f.name]
+	if name.tok.tp != tokenTypeIdentifier {
+		return ErrUnrecognizedExpression
+	}
+	argc := 0
+	for i, arg := range n.args {
+		if i == f.name || i == f.body {
+			continue
+		}
+		if err := c.emit(arg); err != nil {
+			return err
+		}
+		argc++
+	}
+	scope := append(c.scope[:len(c.scope):len(c.scope)], name.tok.v)
+	body := &Program{results: 1, opts: c.p.opts, memo: c.p.memo, reg: c.p.reg}
+	bc := newCompiler(body, len(c.uses), scope)
+	bc.count(n.args[f.body])
+	if err := bc.emit(n.args[f.body]); err != nil {
+		return err
+	}
+	body.stack = maxStack(body)
+	c.p.subs = append(c.p.subs, sub{f, body})
+	c.p.code = append(c.p.code, instr{op: opForm, arg: len(c.p.subs) - 1, argc: argc, tok: n.tok})
+	return nil
+}
+
+// bind evaluates body with its local variable bound to v, each evaluation
+// counts as an operation
+func (e *evaluator) bind(body *Program, v *big.Rat) (*big.Rat, error) {
+	if err := e.spend(); err != nil {
+		return nil, err
+	}
+	e.locals = append(e.locals, v)
+	defer func() { e.locals = e.locals[:len(e.locals)-1] }()
+	return e.run(body)
+}
+
+// bindFloat evaluates body in float mode with its local variable bound to v
+func (e *evaluator) bindFloat(body *Program, v float64) (float64, error) {
+	if err := e.spend(); err != nil {
+		return 0, err
+	}
+	e.flocals = append(e.flocals, v)
+	defer func() { e.flocals = e.flocals[:len(e.flocals)-1] }()
+	return e.runFloat(body)
+}
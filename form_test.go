@@ -0,0 +1,136 @@
+package rpn
+
+import (
+	"errors"
+	"math/big"
+	"testing"
+)
+
+func TestWith(t *testing.T) {
+	for name, expr := range map[string]string{
+		"withinner": "with(t, 2, t * 10)",
+		"withouter": "s",
+	} {
+		if err := RegisterExpr(name, expr); err != nil {
+			t.Fatalf("register %v = [%v], err %v", name, expr, err)
+		}
+	}
+
+	vars := map[string]*big.Rat{"a": big.NewRat(2, 1), "b": big.NewRat(3, 1), "s": big.NewRat(7, 1)}
+	cases := []struct {
+		in     string
+		code   string
+		result *big.Rat
+		err    error
+	}{
+		{"with(s, a + b, s * s - 2 * s)", "a b + with", big.NewRat(15, 1), nil},
+		{"with(s, 2, with(t, s + 1, s * t))", "2 with", big.NewRat(6, 1), nil},
+		{"with(a, 1, a) + a", "1 with a +", big.NewRat(3, 1), nil},
+		// the s + 1 bound inside is not the s + 1 outside
+		{"with(s, 1, s + 1) + (s + 1)", "1 with s 1 + +", big.NewRat(10, 1), nil},
+		{"with(s, 1, s + withinner)", "1 with", big.NewRat(21, 1), nil},
+		// named expressions do not see local variables
+		{"with(s, 1, withouter)", "1 with", big.NewRat(7, 1), nil},
+		{"WITH(s, 1 / 0, s)", "", nil, ErrZeroDivision},
+		{"with(s, 1, s / 0)", "", nil, ErrZeroDivision},
+	}
+	for _, pratt := range []bool{false, true} {
+		var opts []Option
+		if pratt {
+			opts = append(opts, WithPrattParser())
+		}
+		for _, tc := range cases {
+			r, err := New(tc.in, opts...)
+			if err != nil {
+				t.Errorf("can not convert [%v], err %v", tc.in, err)
+				continue
+			}
+			p, _ := r.Compile()
+			if code := disassemble(p); tc.code != "" && code != tc.code {
+				t.Errorf("[%v] code should be %v but %v", tc.in, tc.code, code)
+			}
+			result, err := r.Eval(vars)
+			if !errors.Is(err, tc.err) {
+				t.Errorf("[%v] err should be %v but %v", tc.in, tc.err, err)
+				continue
+			}
+			if tc.err == nil && result.Cmp(tc.result) != 0 {
+				t.Errorf("[%v] result should be %v but %v", tc.in, tc.result, result)
+			}
+			fvars := make(map[string]float64)
+			for k, v := range vars {
+				fvars[k], _ = v.Float64()
+			}
+			f, err := r.EvalFloat64(fvars)
+			if !errors.Is(err, tc.err) {
+				t.Errorf("[%v] float err should be %v but %v", tc.in, tc.err, err)
+				continue
+			}
+			if tc.err != nil {
+				continue
+			}
+			if want, _ := tc.result.Float64(); f != want {
+				t.Errorf("[%v] float result should be %v but %v", tc.in, want, f)
+			}
+		}
+	}
+
+	for _, in := range []string{"with(1, 2, 3)", "with(a + b, 2, 3)", "with(s, 2)"} {
+		if _, err := New(in); !errors.Is(err, ErrUnrecognizedExpression) {
+			t.Errorf("[%v] err should be %v but %v", in, ErrUnrecognizedExpression, err)
+		}
+	}
+}
+
+func TestSeries(t *testing.T) {
+	vars := map[string]*big.Rat{"n": big.NewRat(5, 1), "i": big.NewRat(100, 1)}
+	cases := []struct {
+		in     string
+		result *big.Rat
+		err    error
+	}{
+		{"sum(i, 1, 100, i^2)", big.NewRat(338350, 1), nil},
+		{"prod(k, 1, n, k)", big.NewRat(120, 1), nil},
+		{"sum(k, 1, n, 1 / k)", big.NewRat(137, 60), nil},
+		{"sum(k, 1, 0, k)", new(big.Rat), nil},
+		{"prod(k, 1, 0, k)", big.NewRat(1, 1), nil},
+		{"sum(k, 0 - 2, 2, k)", new(big.Rat), nil},
+		{"sum(j, 1, 3, prod(k, 1, j, k))", big.NewRat(9, 1), nil},
+		{"sum(k, 1, 3, k) + i", big.NewRat(106, 1), nil},
+		{"sum(k, 1, 3, k * n)", big.NewRat(30, 1), nil},
+		{"sum(k, 1, 2.5, k)", nil, ErrInvalidArgument},
+		{"sum(k, 1, 10000000, k)", nil, ErrInvalidArgument},
+		{"sum(k, 0, 1, 1 / k)", nil, ErrZeroDivision},
+	}
+	fvars := map[string]float64{"n": 5, "i": 100}
+	for _, tc := range cases {
+		r, err := New(tc.in)
+		if err != nil {
+			t.Errorf("can not convert [%v], err %v", tc.in, err)
+			continue
+		}
+		result, err := r.Eval(vars)
+		if !errors.Is(err, tc.err) || tc.err == nil && result.Cmp(tc.result) != 0 {
+			t.Errorf("[%v] result should be %v, %v but %v, %v", tc.in, tc.result, tc.err, result, err)
+		}
+		f, err := r.EvalFloat64(fvars)
+		if !errors.Is(err, tc.err) {
+			t.Errorf("[%v] float err should be %v but %v", tc.in, tc.err, err)
+			continue
+		}
+		if tc.err != nil {
+			continue
+		}
+		if want, _ := tc.result.Float64(); f-want > 1e-12 || want-f > 1e-12 {
+			t.Errorf("[%v] float result should be %v but %v", tc.in, want, f)
+		}
+	}
+
+	r, err := New("sum(k, 1, 1000, k)", WithMaxOperations(100))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := r.Result(); !errors.Is(err, ErrBudgetExceeded) {
+		t.Errorf("err should be %v but %v", ErrBudgetExceeded, err)
+	}
+}
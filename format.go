@@ -0,0 +1,155 @@
+package rpn
+
+import (
+	"math/big"
+	"strings"
+	"unicode/utf8"
+)
+
+// FormatOptions controls how FormatResult renders a result
+type FormatOptions struct {
+	// Decimals is the number of digits after the decimal separator, -1 for
+	// none and 0 for as many as an exact decimal has, otherwise 6 with
+	// trailing zeros dropped
+	Decimals int
+	Group    bool   // group the digits of the integer part in thousands
+	Locale   string // language tag choosing the separators, such as "de" or "de-CH", English if empty
+	Width    int    // minimum width in characters, padded with spaces on the left
+}
+
+// separators lists the decimal and the group separator of the supported
+// locales, by language and by language and region. Groups separated by
+// spaces use no-break spaces.
+var separators = map[string][2]string{
+	"en":    {".", ","},
+	"ja":    {".", ","},
+	"zh":    {".", ","},
+	"ko":    {".", ","},
+	"he":    {".", ","},
+	"de":    {",", "."},
+	"es":    {",", "."},
+	"it":    {",", "."},
+	"nl":    {",", "."},
+	"pt":    {",", "."},
+	"da":    {",", "."},
+	"id":    {",", "."},
+	"tr":    {",", "."},
+	"el":    {",", "."},
+	"fr":    {",", "\u202f"},
+	"ru":    {",", "\u00a0"},
+	"uk":    {",", "\u00a0"},
+	"pl":    {",", "\u00a0"},
+	"cs":    {",", "\u00a0"},
+	"sk":    {",", "\u00a0"},
+	"sv":    {",", "\u00a0"},
+	"fi":    {",", "\u00a0"},
+	"nb":    {",", "\u00a0"},
+	"de-ch": {".", "’"},
+	"it-ch": {".", "’"},
+	"fr-ch": {",", "\u202f"},
+	"de-at": {",", "\u00a0"},
+	"pt-br": {",", "."},
+	"es-mx": {".", ","},
+}
+
+// FormatResult renders v in decimal notation with the separators of a
+// locale, failing with ErrInvalidArgument if the locale is not supported.
+// Digits are rounded half away from zero.
+func FormatResult(v *big.Rat, o FormatOptions) (string, error) {
+	seps, ok := localeSeparators(o.Locale)
+	if !ok {
+		return "", ErrInvalidArgument
+	}
+	var s string
+	switch {
+	case o.Decimals < 0:
+		s = v.FloatString(0)
+	case o.Decimals > 0:
+		s = v.FloatString(o.Decimals)
+	default:
+		if decimals, ok := DecimalPlaces(v); ok {
+			s = v.FloatString(decimals)
+		} else {
+			s = strings.TrimSuffix(strings.TrimRight(v.FloatString(6), "0"), ".")
+		}
+	}
+	sign := ""
+	if strings.HasPrefix(s, "-") {
+		sign, s = "-", s[1:]
+		if strings.Trim(s, "0.") == "" {
+			// rounded to zero
+			sign = ""
+		}
+	}
+	intPart, frac := s, ""
+	if i := strings.IndexByte(s, '.'); i >= 0 {
+		intPart, frac = s[:i], s[i+1:]
+	}
+	if o.Group {
+		intPart = group(intPart, seps[1])
+	}
+	s = sign + intPart
+	if frac != "" {
+		s += seps[0] + frac
+	}
+	if n := utf8.RuneCountInString(s); n < o.Width {
+		s = strings.Repeat(" ", o.Width-n) + s
+	}
+	return s, nil
+}
+
+// DecimalPlaces returns the number of digits after the decimal point v is
+// written with exactly, false if its decimal expansion does not terminate
+func DecimalPlaces(v *big.Rat) (int, bool) {
+	d := new(big.Int).Set(v.Denom())
+	decimals := 0
+	for _, f := range []int64{2, 5} {
+		m := new(big.Int)
+		for n := 0; ; n++ {
+			q, r := new(big.Int).QuoRem(d, big.NewInt(f), m)
+			if r.Sign() != 0 {
+				if n > decimals {
+					decimals = n
+				}
+				break
+			}
+			d = q
+		}
+	}
+	return decimals, d.Cmp(big.NewInt(1)) == 0
+}
+
+// localeSeparators returns the separators of the locale tag, falling back
+// from language and region to the language
+func localeSeparators(tag string) ([2]string, bool) {
+	if tag == "" {
+		return separators["en"], true
+	}
+	tag = strings.ToLower(strings.Replace(tag, "_", "-", -1))
+	if seps, ok := separators[tag]; ok {
+		return seps, true
+	}
+	if i := strings.IndexByte(tag, '-'); i >= 0 {
+		tag = tag[:i]
+	}
+	seps, ok := separators[tag]
+	return seps, ok
+}
+
+// group inserts sep between each group of three digits of digits
+func group(digits, sep string) string {
+	if len(digits) <= 3 {
+		return digits
+	}
+	var b strings.Builder
+	head := len(digits) % 3
+	if head == 0 {
+		head = 3
+	}
+	b.WriteString(digits[:head])
+	for i := head; i < len(digits); i += 3 {
+		b.WriteString(sep)
+		b.WriteString(digits[i : i+3])
+	}
+	return b.String()
+}
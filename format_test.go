@@ -0,0 +1,54 @@
+package rpn
+
+import (
+	"errors"
+	"math/big"
+	"testing"
+)
+
+func TestFormatResult(t *testing.T) {
+	cases := []struct {
+		v    *big.Rat
+		o    FormatOptions
+		want string
+	}{
+		{big.NewRat(1234567, 4), FormatOptions{}, "308641.75"},
+		{big.NewRat(1234567, 4), FormatOptions{Group: true}, "308,641.75"},
+		{big.NewRat(1234567, 4), FormatOptions{Group: true, Locale: "de"}, "308.641,75"},
+		{big.NewRat(1234567, 4), FormatOptions{Group: true, Locale: "de_CH"}, "308’641.75"},
+		{big.NewRat(1234567, 4), FormatOptions{Group: true, Locale: "fr-FR"}, "308\u202f641,75"},
+		{big.NewRat(1234567, 4), FormatOptions{Decimals: -1, Group: true}, "308,642"},
+		{big.NewRat(-1234567, 1), FormatOptions{Group: true, Locale: "de"}, "-1.234.567"},
+		{big.NewRat(2, 3), FormatOptions{}, "0.666667"},
+		{big.NewRat(1, 4), FormatOptions{Decimals: 3, Locale: "es"}, "0,250"},
+		{big.NewRat(-1, 1000), FormatOptions{Decimals: 2}, "0.00"},
+		{big.NewRat(42, 1), FormatOptions{Width: 6}, "    42"},
+		{big.NewRat(12345, 1), FormatOptions{Group: true, Locale: "de-CH", Width: 8}, "  12’345"},
+		{big.NewRat(123, 1), FormatOptions{Group: true}, "123"},
+	}
+	for _, tc := range cases {
+		if got, err := FormatResult(tc.v, tc.o); err != nil || got != tc.want {
+			t.Errorf("%v with %+v should be %q but %q, err %v", tc.v, tc.o, tc.want, got, err)
+		}
+	}
+	if _, err := FormatResult(big.NewRat(1, 1), FormatOptions{Locale: "xx"}); !errors.Is(err, ErrInvalidArgument) {
+		t.Errorf("error should be %v but %v", ErrInvalidArgument, err)
+	}
+}
+
+func TestDecimalPlaces(t *testing.T) {
+	for _, tc := range []struct {
+		v        *big.Rat
+		decimals int
+		ok       bool
+	}{
+		{big.NewRat(3, 1), 0, true},
+		{big.NewRat(-1, 40), 3, true},
+		{big.NewRat(1, 1024), 10, true},
+		{big.NewRat(2, 3), 0, false},
+	} {
+		if decimals, ok := DecimalPlaces(tc.v); ok != tc.ok || ok && decimals != tc.decimals {
+			t.Errorf("%v should have %v decimals, %v, but %v, %v", tc.v, tc.decimals, tc.ok, decimals, ok)
+		}
+	}
+}
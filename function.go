@@ -0,0 +1,229 @@
+package rpn
+
+import (
+	"hash/fnv"
+	"math"
+	"math/big"
+)
+
+// maxScale bounds the digits argument of the rounding functions
+const maxScale = 1000
+
+// function describes a builtin function and the number of arguments it
+// accepts. In float mode fcall is used if set, otherwise the arguments are
+// converted to call the exact implementation.
+type function struct {
+	minArgs int
+	maxArgs int
+	call    func(o *options, args []*big.Rat) (*big.Rat, error)
+	fcall   func(o *options, args []float64) float64
+}
+
+var functions = map[string]function{
+	"abs": {1, 1, func(o *options, args []*big.Rat) (*big.Rat, error) { return new(big.Rat).Abs(args[0]), nil },
+		func(o *options, args []float64) float64 { return math.Abs(args[0]) }},
+	"sin":        angleFunc(math.Sin, angleArg),
+	"cos":        angleFunc(math.Cos, angleArg),
+	"tan":        angleFunc(math.Tan, angleArg),
+	"ln":         floatFunc(math.Log),
+	"log":        {1, 2, logFunc, logFloat64},
+	"arcsin":     angleFunc(math.Asin, angleResult),
+	"arccos":     angleFunc(math.Acos, angleResult),
+	"arctan":     angleFunc(math.Atan, angleResult),
+	"atan2":      {2, 2, atan2Func, atan2Float64},
+	"wrap":       {3, 3, wrapFunc, wrapFloat64},
+	"normangle":  {1, 1, normAngle, normAngleFloat64},
+	"deg2rad":    floatFunc(func(x float64) float64 { return x * math.Pi / 180 }),
+	"rad2deg":    floatFunc(func(x float64) float64 { return x * 180 / math.Pi }),
+	"hypot":      {2, 2, hypot, func(o *options, args []float64) float64 { return math.Hypot(args[0], args[1]) }},
+	"sqrt":       {1, 1, sqrtFunc, floatFunc(math.Sqrt).fcall},
+	"round":      roundFunc(nil),
+	"floor":      roundFunc(func(*options) big.RoundingMode { return big.ToNegativeInf }),
+	"ceil":       roundFunc(func(*options) big.RoundingMode { return big.ToPositiveInf }),
+	"ifgt":       {4, 4, nil, nil},
+	"ifge":       {4, 4, nil, nil},
+	"iflt":       {4, 4, nil, nil},
+	"ifle":       {4, 4, nil, nil},
+	"ifeq":       {4, 4, nil, nil},
+	"ifne":       {4, 4, nil, nil},
+	"case":       {2, -1, nil, nil},
+	"between":    {3, 3, betweenFunc, nil},
+	"with":       {3, 3, nil, nil},
+	"capture":    {2, 2, nil, nil},
+	"sum":        {4, 4, nil, nil},
+	"prod":       {4, 4, nil, nil},
+	"integrate":  {4, 4, nil, nil},
+	"solve":      {3, 3, nil, nil},
+	"piecewise":  {2, -1, nil, nil},
+	"pw":         {2, -1, nil, nil},
+	"sumall":     {1, -1, sumAll, nil},
+	"variance":   {2, -1, variance, nil},
+	"stdev":      {2, -1, stdev, nil},
+	"percentile": {2, -1, percentile, nil},
+	"corr":       {4, -1, corr, nil},
+	"pmt":        {3, 4, pmt, nil},
+	"fv":         {3, 4, fv, nil},
+	"pv":         {3, 4, pv, nil},
+	"npv":        {2, -1, npv, nil},
+	"irr":        {2, -1, irr, nil},
+	"fib":        {1, 1, fib, nil},
+	"binom":      {2, 2, binom, nil},
+	"pow10":      {1, 1, pow10Func, nil},
+	"rand":       {1, 1, randFunc, nil},
+	"isnan":      floatCheck(func(f float64) bool { return math.IsNaN(f) }, false),
+	"isinf":      floatCheck(func(f float64) bool { return math.IsInf(f, 0) }, false),
+	"isfinite":   floatCheck(func(f float64) bool { return !math.IsNaN(f) && !math.IsInf(f, 0) }, true),
+}
+
+// floatFunc adapts a float64 function of one argument, arguments outside its
+// domain such as sqrt(-1) or ln(0) are invalid
+func floatFunc(fn func(float64) float64) function {
+	return function{1, 1, func(o *options, args []*big.Rat) (*big.Rat, error) {
+		f, _ := args[0].Float64()
+		f = fn(f)
+		if math.IsNaN(f) || math.IsInf(f, 0) {
+			return nil, ErrInvalidArgument
+		}
+		return new(big.Rat).SetFloat64(f), nil
+	}, func(o *options, args []float64) float64 {
+		return fn(args[0])
+	}}
+}
+
+// angleUse tells whether a function takes or yields an angle, which
+// WithAngleUnit converts from or to radians
+type angleUse uint8
+
+const (
+	angleNone angleUse = iota
+	angleArg
+	angleResult
+)
+
+// angleFunc adapts a trigonometric float64 function of one argument, which
+// takes or yields an angle in the unit selected by WithAngleUnit
+func angleFunc(fn func(float64) float64, use angleUse) function {
+	return function{1, 1, func(o *options, args []*big.Rat) (*big.Rat, error) {
+		f, _ := args[0].Float64()
+		f = o.applyAngle(use, fn, f)
+		if math.IsNaN(f) || math.IsInf(f, 0) {
+			return nil, ErrInvalidArgument
+		}
+		return new(big.Rat).SetFloat64(f), nil
+	}, func(o *options, args []float64) float64 {
+		return o.applyAngle(use, fn, args[0])
+	}}
+}
+
+// applyAngle applies fn to f converting its angle argument or result
+// between radians and the configured unit
+func (o *options) applyAngle(use angleUse, fn func(float64) float64, f float64) float64 {
+	if o.angle != Degrees || use == angleNone {
+		return fn(f)
+	}
+	if use == angleArg {
+		return snapAngle(f, fn(f*math.Pi/180))
+	}
+	return fn(f) * 180 / math.Pi
+}
+
+// randFunc returns a pseudo-random number in [0, 1) determined by its
+// argument and the seed given to WithRandSeed, so that rand(1) and rand(2)
+// differ while two evaluations of rand(1) agree
+func randFunc(o *options, args []*big.Rat) (*big.Rat, error) {
+	h := fnv.New64a()
+	h.Write([]byte(args[0].RatString()))
+	// splitmix64 finalizer
+	x := h.Sum64() ^ uint64(o.seed)
+	x += 0x9e3779b97f4a7c15
+	x = (x ^ x>>30) * 0xbf58476d1ce4e5b9
+	x = (x ^ x>>27) * 0x94d049bb133111eb
+	x ^= x >> 31
+	return big.NewRat(int64(x>>11), 1<<53), nil
+}
+
+// floatCheck builds a function returning 1 if its argument satisfies check
+// and 0 otherwise. Exact numbers are finite, exact evaluations return 1 if
+// finite is set and 0 otherwise.
+func floatCheck(check func(float64) bool, finite bool) function {
+	return function{1, 1, func(o *options, args []*big.Rat) (*big.Rat, error) {
+		return ratBool(finite), nil
+	}, func(o *options, args []float64) float64 {
+		return floatBool(check(args[0]))
+	}}
+}
+
+// betweenFunc returns 1 if lo <= x <= hi and 0 otherwise
+func betweenFunc(o *options, args []*big.Rat) (*big.Rat, error) {
+	if args[0].Cmp(args[1]) >= 0 && args[0].Cmp(args[2]) <= 0 {
+		return big.NewRat(1, 1), nil
+	}
+	return new(big.Rat), nil
+}
+
+// roundFunc builds a function rounding its first argument to the number of
+// decimal digits given by the optional second argument. A nil mode uses the
+// rounding mode configured on the expression.
+func roundFunc(mode func(*options) big.RoundingMode) function {
+	return function{1, 2, func(o *options, args []*big.Rat) (*big.Rat, error) {
+		digits := 0
+		if len(args) > 1 {
+			if !args[1].IsInt() || args[1].Num().CmpAbs(big.NewInt(maxScale)) > 0 {
+				return nil, ErrInvalidArgument
+			}
+			digits = int(args[1].Num().Int64())
+		}
+		m := o.rounding
+		if mode != nil {
+			m = mode(o)
+		}
+		return roundRat(args[0], digits, m), nil
+	}, nil}
+}
+
+// roundRat rounds x to the given number of decimal digits, negative digits
+// round to the left of the decimal point
+func roundRat(x *big.Rat, digits int, mode big.RoundingMode) *big.Rat {
+	abs := digits
+	if abs < 0 {
+		abs = -abs
+	}
+	scale := new(big.Rat).SetInt(new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(abs)), nil))
+	y := new(big.Rat)
+	if digits >= 0 {
+		y.Mul(x, scale)
+	} else {
+		y.Quo(x, scale)
+	}
+	y.SetInt(roundInt(y, mode))
+	if digits >= 0 {
+		return y.Quo(y, scale)
+	}
+	return y.Mul(y, scale)
+}
+
+// roundInt rounds x to an integer according to mode
+func roundInt(x *big.Rat, mode big.RoundingMode) *big.Int {
+	q, r := new(big.Int).QuoRem(x.Num(), x.Denom(), new(big.Int))
+	if r.Sign() == 0 {
+		return q
+	}
+	away := false
+	switch mode {
+	case big.ToZero:
+	case big.AwayFromZero:
+		away = true
+	case big.ToNegativeInf:
+		away = x.Sign() < 0
+	case big.ToPositiveInf:
+		away = x.Sign() > 0
+	case big.ToNearestEven, big.ToNearestAway:
+		// compare the doubled remainder with the denominator to find the nearest
+		c := new(big.Int).Lsh(new(big.Int).Abs(r), 1).Cmp(x.Denom())
+		away = c > 0 || (c == 0 && (mode == big.ToNearestAway || q.Bit(0) == 1))
+	}
+	if away {
+		q.Add(q, big.NewInt(int64(x.Sign())))
+	}
+	return q
+}
@@ -0,0 +1,176 @@
+package rpn
+
+import (
+	"strings"
+	"unicode/utf8"
+)
+
+// lex splits expr into tokens recording their positions, characters which
+// do not start any token become tokens of unknown type. Words are typed by
+// typeOf.
+func lex(expr string, typeOf func(string) uint8) []*token {
+	tokens := make([]*token, 0, len(expr)/2)
+	for i := 0; ; {
+		tok, end := nextToken(expr, i, typeOf)
+		if tok == nil {
+			return tokens
+		}
+		tokens = append(tokens, tok)
+		i = end
+	}
+}
+
+// nextToken returns the first token of expr from offset i on and the offset
+// it ends at, or nil if only whitespace is left. It looks at most two bytes
+// past the end of the token.
+func nextToken(expr string, i int, typeOf func(string) uint8) (*token, int) {
+	for i < len(expr) && (expr[i] == ' ' || expr[i] == '\t' || expr[i] == '\n' || expr[i] == '\r') {
+		i++
+	}
+	if i == len(expr) {
+		return nil, i
+	}
+	c := expr[i]
+	switch {
+	case isDigit(c):
+		j := i + scanNumeral(expr[i:])
+		return &token{tp: tokenTypeOperand, v: expr[i:j], pos: i}, j
+	case isLetter(c):
+		j := i + scanName(expr[i:])
+		return &token{tp: typeOf(expr[i:j]), v: expr[i:j], pos: i}, j
+	}
+
+	if i+1 < len(expr) {
+		op := expr[i : i+2]
+		if _, ok := operators[op]; ok {
+			return &token{tp: tokenTypeOperator, v: op, pos: i}, i + 2
+		}
+	}
+	_, size := utf8.DecodeRuneInString(expr[i:])
+	v := expr[i : i+size]
+	tp := tokenTypeUnknown
+	if strings.Contains("()", v) {
+		tp = tokenTypeParenthesis
+	} else if v == "," {
+		tp = tokenTypeSeparator
+	} else if _, ok := operators[v]; ok && v != "@" {
+		tp = tokenTypeOperator
+	}
+	return &token{tp: tp, v: v, pos: i}, i + size
+}
+
+func isDigit(c byte) bool {
+	return '0' <= c && c <= '9'
+}
+
+func isLetter(c byte) bool {
+	return 'a' <= c && c <= 'z' || 'A' <= c && c <= 'Z' || c == '_'
+}
+
+// isBlank reports whether c is ASCII whitespace
+func isBlank(c rune) bool {
+	return c == ' ' || c == '\t' || c == '\n' || c == '\f' || c == '\r'
+}
+
+// scanNumeral returns the length of the numeral starting s, such as 12 or
+// 1.5, zero if there is none
+func scanNumeral(s string) int {
+	i := 0
+	for i < len(s) && isDigit(s[i]) {
+		i++
+	}
+	if i > 0 && i+1 < len(s) && s[i] == '.' && isDigit(s[i+1]) {
+		i += 2
+		for i < len(s) && isDigit(s[i]) {
+			i++
+		}
+	}
+	return i
+}
+
+// scanName returns the length of the name starting s, zero if there is none
+func scanName(s string) int {
+	if len(s) == 0 || !isLetter(s[0]) {
+		return 0
+	}
+	i := 1
+	for i < len(s) && (isLetter(s[i]) || isDigit(s[i])) {
+		i++
+	}
+	return i
+}
+
+// isNumeral reports whether s is a numeral
+func isNumeral(s string) bool {
+	return len(s) > 0 && scanNumeral(s) == len(s)
+}
+
+// isName reports whether s is a valid name
+func isName(s string) bool {
+	return len(s) > 0 && scanName(s) == len(s)
+}
+
+// spaceWords surrounds each name and numeral of expr with spaces
+func spaceWords(expr string) string {
+	var b strings.Builder
+	b.Grow(len(expr) + len(expr)/2)
+	for i := 0; i < len(expr); {
+		n := scanName(expr[i:])
+		if n == 0 {
+			n = scanNumeral(expr[i:])
+		}
+		if n == 0 {
+			b.WriteByte(expr[i])
+			i++
+			continue
+		}
+		b.WriteByte(' ')
+		b.WriteString(expr[i : i+n])
+		b.WriteByte(' ')
+		i += n
+	}
+	return b.String()
+}
+
+// unaryContext holds the characters after which a sign is unary, as is a
+// sign starting the expression or following the operator div
+const unaryContext = "-+^%*/!~=<>&|(,×÷"
+
+// markUnaryMinus replaces each unary minus of expr by " @" and drops each
+// unary plus, so that signs may repeat: "- -5" and "+-5" are "@ @5" and
+// " @5". A sign following a binary operator or another sign is unary.
+func markUnaryMinus(expr string) string {
+	if strings.IndexByte(expr, '-') < 0 && strings.IndexByte(expr, '+') < 0 {
+		return expr
+	}
+	var b strings.Builder
+	b.Grow(len(expr) + 16)
+	copied := 0 // bytes of expr copied
+	for j := 0; j < len(expr); j++ {
+		if expr[j] != '-' && expr[j] != '+' {
+			continue
+		}
+		k := j
+		for k > 0 && isBlank(rune(expr[k-1])) {
+			k--
+		}
+		unary := k == 0
+		if c, _ := utf8.DecodeLastRuneInString(expr[:k]); k > 0 && strings.ContainsRune(unaryContext, c) {
+			unary = true
+		} else if strings.HasSuffix(expr[:k], "div") && (k == 3 || !isLetter(expr[k-4]) && !isDigit(expr[k-4])) {
+			unary = true
+		}
+		if !unary {
+			continue
+		}
+		b.WriteString(expr[copied:j])
+		if expr[j] == '-' {
+			b.WriteString(" @")
+		} else {
+			b.WriteByte(' ')
+		}
+		copied = j + 1
+	}
+	b.WriteString(expr[copied:])
+	return b.String()
+}
@@ -0,0 +1,65 @@
+package rpn
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"unicode/utf8"
+)
+
+// continuations blanks each backslash ending a line, possibly followed by
+// blanks, so long formulas may be continued on the next line as in shell
+// scripts. Offsets in the expression are kept.
+func continuations(expr string) string {
+	if !strings.Contains(expr, "\\") {
+		return expr
+	}
+	b := []byte(expr)
+	for i, c := range b {
+		if c != '\\' {
+			continue
+		}
+		j := i + 1
+		for j < len(b) && (b[j] == ' ' || b[j] == '\t' || b[j] == '\r') {
+			j++
+		}
+		if j == len(b) || b[j] == '\n' {
+			b[i] = ' '
+		}
+	}
+	return string(b)
+}
+
+// locate sets the line and column of a SyntaxError or PolicyError in err
+// if src spans several lines, and returns err
+func locate(err error, src string) error {
+	var serr *SyntaxError
+	var perr *PolicyError
+	switch {
+	case errors.As(err, &serr):
+		serr.Line, serr.Col = lineCol(src, serr.Pos)
+	case errors.As(err, &perr):
+		perr.Line, perr.Col = lineCol(src, perr.Pos)
+	}
+	return err
+}
+
+// lineCol returns the line and column of the byte offset pos in src,
+// counting from 1 and the column in characters, and 0 and 0 unless src
+// spans several lines
+func lineCol(src string, pos int) (int, int) {
+	if !strings.Contains(src, "\n") || pos < 0 || pos > len(src) {
+		return 0, 0
+	}
+	start := strings.LastIndexByte(src[:pos], '\n') + 1
+	return strings.Count(src[:start], "\n") + 1, utf8.RuneCountInString(src[start:pos]) + 1
+}
+
+// position describes where an error is, by line and column if line is
+// known and otherwise by the byte offset pos
+func position(pos, line, col int) string {
+	if line > 0 {
+		return fmt.Sprintf("at line %d, column %d", line, col)
+	}
+	return fmt.Sprintf("at offset %d", pos)
+}
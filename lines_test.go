@@ -0,0 +1,84 @@
+package rpn
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestMultiLine(t *testing.T) {
+	cases := []struct {
+		in      string
+		postfix string
+	}{
+		{"price *\n  (1 + rate)\n", "price 1 rate + *"},
+		{"a + \\\n  b", "a b +"},
+		{"a + \\  \r\n-b", "a b @ +"},
+		{"a +\n-b", "a b @ +"},
+	}
+	for _, opts := range [][]Option{nil, {WithPrattParser()}} {
+		for _, tc := range cases {
+			r, err := New(tc.in, opts...)
+			if err != nil {
+				t.Errorf("[%q] %v", tc.in, err)
+				continue
+			}
+			if postfix := strings.Join(r.Postfix(), " "); postfix != tc.postfix {
+				t.Errorf("[%q] postfix should be %v but %v", tc.in, tc.postfix, postfix)
+			}
+		}
+	}
+	if _, err := New("a \\ b"); err == nil {
+		t.Errorf("a backslash within a line should fail")
+	}
+}
+
+func TestSyntaxErrorLine(t *testing.T) {
+	cases := []struct {
+		in  string
+		err string
+	}{
+		{"1 +\n\t2 *", "unexpected end of expression at line 2, column 5"},
+		{"1 + * 2", "unexpected \"*\" at offset 4"},
+		{"sum(\n  a,\n  ×)", "unexpected \"×\" at line 3, column 3"},
+	}
+	for _, tc := range cases {
+		_, err := New(tc.in, WithPrattParser())
+		var serr *SyntaxError
+		if !errors.As(err, &serr) || err.Error() != tc.err {
+			t.Errorf("[%q] error should be %v but %v", tc.in, tc.err, err)
+		}
+	}
+	if err := CheckBalanced("(a +\n  b))"); err == nil || err.Line != 2 || err.Col != 5 {
+		t.Errorf("error should be at line 2, column 5 but %v", err)
+	}
+	if _, errs := ParseTolerant("1 +\n* 2"); len(errs) != 1 || errs[0].Line != 2 || errs[0].Col != 1 {
+		t.Errorf("error should be at line 2, column 1 but %v", errs)
+	}
+}
+
+func TestErrorLines(t *testing.T) {
+	for _, opts := range [][]Option{nil, {WithPrattParser()}} {
+		_, err := New("1 +\n  sqrt(4)", append(opts, WithDeniedFunctions("sqrt"))...)
+		if err == nil || err.Error() != "sqrt is not allowed at line 2, column 3" {
+			t.Errorf("policy error should be at line 2, column 3 but %v", err)
+		}
+		r, err := New("x +\n  ok", opts...)
+		if err != nil {
+			t.Fatal(err)
+		}
+		errs := r.Check(Schema{"x": TypeNumber, "ok": TypeBool})
+		if len(errs) != 1 || errs[0].Error() != "+ expects a number but is given a boolean at line 1, column 3" {
+			t.Errorf("type error should be at line 1, column 3 but %v", errs)
+		}
+		warnings, err := Lint("x /\n  x", opts...)
+		if err != nil || len(warnings) != 1 || warnings[0].Line != 1 || warnings[0].Col != 3 ||
+			warnings[0].String() != "x / x is 1 unless x is 0 at line 1, column 3 (self-division)" {
+			t.Errorf("warning should be at line 1, column 3 but %v, err %v", warnings, err)
+		}
+	}
+	r, _ := New("x + ok")
+	if errs := r.Check(Schema{"x": TypeNumber, "ok": TypeBool}); len(errs) != 1 || errs[0].Line != 0 {
+		t.Errorf("a single line should not be located by line but %v", errs)
+	}
+}
@@ -0,0 +1,208 @@
+package rpn
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// LintWarning is a suspicious construct found by Lint, Pos and End are the
+// byte offsets of the offending tokens as far as they are known. Line and
+// Col locate Pos in expressions spanning several lines like those of
+// SyntaxError, they are 0 otherwise.
+type LintWarning struct {
+	Pos  int
+	End  int
+	Rule string // the rule broken, such as "redundant-parentheses"
+	Msg  string
+	Line int
+	Col  int
+}
+
+func (w *LintWarning) String() string {
+	return fmt.Sprintf("%s %s (%s)", w.Msg, position(w.Pos, w.Line, w.Col), w.Rule)
+}
+
+// Lint parses expr with opts and returns warnings about constructs which
+// are valid but likely not what was meant:
+//
+//	redundant-parentheses  parentheses which do not change the expression
+//	self-division          x / x, which is 1 unless x is 0
+//	float-equality         == or != on a value computed in float64
+//	unused-binding         with(name, value, body) where body ignores name
+//	precedence             -2 ^ 2, which is -(2 ^ 2)
+func Lint(expr string, opts ...Option) ([]*LintWarning, error) {
+	r, err := New(expr, opts...)
+	if err != nil {
+		return nil, err
+	}
+	l := &linter{r: r}
+	l.parentheses()
+	l.negatedPowers()
+	roots, _, err := buildTree(r.postfix, 1, r.opts.caseMode == CaseInsensitive)
+	if err == nil {
+		l.walk(roots[0])
+	}
+	sort.SliceStable(l.warnings, func(i, j int) bool {
+		return l.warnings[i].Pos < l.warnings[j].Pos
+	})
+	for _, w := range l.warnings {
+		w.Line, w.Col = lineCol(expr, w.Pos)
+	}
+	return l.warnings, nil
+}
+
+type linter struct {
+	r        *RPN
+	warnings []*LintWarning
+}
+
+func (l *linter) warn(pos, end int, rule, msg string) {
+	l.warnings = append(l.warnings, &LintWarning{Pos: pos, End: end, Rule: rule, Msg: msg})
+}
+
+// parentheses warns about each pair of grouping parentheses whose removal
+// leaves the postfix notation unchanged, outer pairs first
+func (l *linter) parentheses() {
+	want := joinTokens(l.r.postfix)
+	infix := l.r.infix
+	for i := 0; i < len(infix); i++ {
+		if infix[i].v != "(" || i > 0 && infix[i-1].tp == tokenTypeFunction {
+			continue
+		}
+		j := closing(infix, i)
+		if j < 0 {
+			return
+		}
+		s := make([]*token, 0, len(infix)-2)
+		s = append(append(append(s, infix[:i]...), infix[i+1:j]...), infix[j+1:]...)
+		postfix, err := l.r.parse(s, 0)
+		if err != nil || joinTokens(postfix) != want {
+			continue
+		}
+		l.warn(infix[i].pos, infix[j].pos+1, "redundant-parentheses", "redundant parentheses")
+		infix = s
+		i--
+	}
+}
+
+// closing returns the index of the parenthesis closing the one at i, -1 if
+// there is none
+func closing(infix []*token, i int) int {
+	depth := 0
+	for j := i; j < len(infix); j++ {
+		switch infix[j].v {
+		case "(":
+			depth++
+		case ")":
+			depth--
+			if depth == 0 {
+				return j
+			}
+		}
+	}
+	return -1
+}
+
+func joinTokens(tokens []*token) string {
+	s := make([]string, len(tokens))
+	for i, tok := range tokens {
+		s[i] = tok.v
+	}
+	return strings.Join(s, " ")
+}
+
+// negatedPowers warns about a unary minus directly applied to the base of a
+// power, which binds looser than ^
+func (l *linter) negatedPowers() {
+	infix := l.r.infix
+	for i, tok := range infix {
+		if !negation(infix, i) {
+			continue
+		}
+		j := i + 1
+		for j < len(infix) && negation(infix, j) {
+			j++
+		}
+		if j < len(infix) && infix[j].tp == tokenTypeFunction {
+			j++
+		}
+		if j < len(infix) && infix[j].v == "(" {
+			j = closing(infix, j)
+			if j < 0 {
+				return
+			}
+		}
+		if j+1 < len(infix) && (infix[j+1].v == "^" || infix[j+1].v == "**") {
+			l.warn(tok.pos, infix[j+1].pos+len(infix[j+1].v), "precedence",
+				"the power binds tighter than the sign, which negates it")
+		}
+	}
+}
+
+// negation reports whether the token at i is a unary minus, which the Pratt
+// parser only tells from its context
+func negation(infix []*token, i int) bool {
+	if infix[i].v == "@" {
+		return true
+	}
+	return infix[i].v == "-" && (i == 0 || infix[i-1].tp == tokenTypeOperator ||
+		infix[i-1].v == "(" || infix[i-1].tp == tokenTypeSeparator)
+}
+
+// walk applies the rules on the syntax tree to n and its subtrees
+func (l *linter) walk(n *node) {
+	p := l.r.prog
+	tok := n.tok
+	switch {
+	case (tok.v == "/" || tok.v == "÷") && n.args[0].id == n.args[1].id && !l.literal(n.args[0]):
+		l.warn(tok.pos, tok.pos+len(tok.v), "self-division",
+			p.format(n)+" is 1 unless "+p.format(n.args[0])+" is 0")
+	case tok.v == "==" || tok.v == "!=":
+		if l.float(n.args[0]) || l.float(n.args[1]) {
+			l.warn(tok.pos, tok.pos+len(tok.v), "float-equality",
+				p.format(n)+" compares a float64 result exactly")
+		}
+	case tok.tp == tokenTypeFunction && strings.EqualFold(tok.v, "with") && p.reg.functions["with"].call == nil:
+		if !l.uses(n.args[2], n.args[0].tok.v) {
+			l.warn(tok.pos, tok.pos+len(tok.v), "unused-binding", n.args[0].tok.v+" is never used")
+		}
+	}
+	for _, arg := range n.args {
+		l.walk(arg)
+	}
+}
+
+// literal reports whether n is a number, possibly negated
+func (l *linter) literal(n *node) bool {
+	for n.tok.v == "@" {
+		n = n.args[0]
+	}
+	return n.tok.tp == tokenTypeOperand
+}
+
+// float reports whether computing n goes through float64
+func (l *linter) float(n *node) bool {
+	if l.r.prog.float(n) {
+		return true
+	}
+	for _, arg := range n.args {
+		if l.float(arg) {
+			return true
+		}
+	}
+	return false
+}
+
+// uses reports whether n refers to name
+func (l *linter) uses(n *node, name string) bool {
+	if n.tok.tp == tokenTypeIdentifier && l.r.opts.sameName(n.tok.v, name) {
+		return true
+	}
+	for _, arg := range n.args {
+		if l.uses(arg, name) {
+			return true
+		}
+	}
+	return false
+}
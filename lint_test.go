@@ -0,0 +1,53 @@
+package rpn
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestLint(t *testing.T) {
+	cases := []struct {
+		in       string
+		warnings []LintWarning
+	}{
+		{"(a + b) * c", nil},
+		{"(a * b) + c", []LintWarning{{Pos: 0, End: 7, Rule: "redundant-parentheses", Msg: "redundant parentheses"}}},
+		{"((a + b)) * (c)", []LintWarning{
+			{Pos: 0, End: 9, Rule: "redundant-parentheses", Msg: "redundant parentheses"},
+			{Pos: 12, End: 15, Rule: "redundant-parentheses", Msg: "redundant parentheses"},
+		}},
+		{"sqrt((x))", []LintWarning{{Pos: 5, End: 8, Rule: "redundant-parentheses", Msg: "redundant parentheses"}}},
+		{"(x + 1) / (x + 1)", []LintWarning{{Pos: 8, End: 9, Rule: "self-division", Msg: "(x + 1) / (x + 1) is 1 unless x + 1 is 0"}}},
+		{"2 / 2", nil},
+		{"sqrt(2) ^ 2 == 2", []LintWarning{{Pos: 12, End: 14, Rule: "float-equality", Msg: "sqrt(2) ^ 2 == 2 compares a float64 result exactly"}}},
+		{"0.1 + 0.2 == 0.3", nil},
+		{"with(s, a + b, a * 2)", []LintWarning{{Pos: 0, End: 4, Rule: "unused-binding", Msg: "s is never used"}}},
+		{"with(s, a + b, s * s)", nil},
+		{"-2 ^ 2", []LintWarning{{Pos: 0, End: 4, Rule: "precedence", Msg: "the power binds tighter than the sign, which negates it"}}},
+		{"-abs(x) ** 2 + 2 ^ -1", []LintWarning{{Pos: 0, End: 10, Rule: "precedence", Msg: "the power binds tighter than the sign, which negates it"}}},
+		{"(-2) ^ 2", nil},
+	}
+	for _, opts := range [][]Option{nil, {WithPrattParser()}} {
+		for _, tc := range cases {
+			warnings, err := Lint(tc.in, opts...)
+			if err != nil {
+				t.Errorf("[%v] %v", tc.in, err)
+				continue
+			}
+			var got []LintWarning
+			for _, w := range warnings {
+				got = append(got, *w)
+			}
+			if !reflect.DeepEqual(got, tc.warnings) {
+				t.Errorf("[%v] with %d options warnings should be %+v but %+v", tc.in, len(opts), tc.warnings, got)
+			}
+		}
+	}
+	warnings, err := Lint("-(2) ^ 2")
+	if err != nil || len(warnings) != 2 || warnings[0].Rule != "precedence" || warnings[1].Rule != "redundant-parentheses" {
+		t.Errorf("warnings should be precedence and redundant-parentheses but %v, err %v", warnings, err)
+	}
+	if _, err := Lint("1 +"); err == nil {
+		t.Errorf("an invalid expression should fail")
+	}
+}
@@ -0,0 +1,30 @@
+package rpn
+
+import "math/big"
+
+// parseLiteral converts a number literal as produced by the tokenisers,
+// digits optionally followed by a point and more digits, into an exact Rat.
+// Unlike fmt.Sscan it accepts no other form, such as fractions, exponents,
+// signs or other bases.
+func parseLiteral(s string) (*big.Rat, error) {
+	point := -1
+	for i := 0; i < len(s); i++ {
+		switch {
+		case isDigit(s[i]):
+		case s[i] == '.' && point < 0 && i > 0 && i < len(s)-1:
+			point = i
+		default:
+			return nil, ErrUnrecognizedExpression
+		}
+	}
+	if s == "" {
+		return nil, ErrUnrecognizedExpression
+	}
+	digits, scale := s, 0
+	if point >= 0 {
+		digits, scale = s[:point]+s[point+1:], len(s)-point-1
+	}
+	num, _ := new(big.Int).SetString(digits, 10)
+	den := new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(scale)), nil)
+	return new(big.Rat).SetFrac(num, den), nil
+}
@@ -0,0 +1,45 @@
+package rpn
+
+import (
+	"math/big"
+	"strings"
+	"testing"
+)
+
+func TestParseLiteral(t *testing.T) {
+	valid := []struct {
+		in string
+		v  *big.Rat
+	}{
+		{"0", big.NewRat(0, 1)},
+		{"007", big.NewRat(7, 1)},
+		{"1.50", big.NewRat(3, 2)},
+		{"0.001", big.NewRat(1, 1000)},
+		{"123456789012345678901234567890", func() *big.Rat {
+			v, _ := new(big.Rat).SetString("123456789012345678901234567890")
+			return v
+		}()},
+	}
+	for _, tc := range valid {
+		v, err := parseLiteral(tc.in)
+		if err != nil {
+			t.Errorf("[%v] should be accepted, err %v", tc.in, err)
+			continue
+		}
+		if v.Cmp(tc.v) != 0 {
+			t.Errorf("[%v] should be %v but %v", tc.in, tc.v, v)
+		}
+	}
+
+	// forms fmt.Sscan would accept or that must never reach the evaluator
+	invalid := []string{
+		"", ".", "1.", ".5", "1..2", "1.2.3", "1/0", "3/4", "1e400", "1E5",
+		"0x10", "0b1", "0o7", "-1", "+1", " 1", "1 ", "1_000", "Inf", "NaN",
+		"١", "1\x00", strings.Repeat("9", 10) + "e" + strings.Repeat("9", 10),
+	}
+	for _, in := range invalid {
+		if v, err := parseLiteral(in); err == nil {
+			t.Errorf("[%q] should be rejected but %v", in, v)
+		}
+	}
+}
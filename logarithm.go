@@ -0,0 +1,55 @@
+package rpn
+
+import (
+	"math"
+	"math/big"
+)
+
+// maxLogPower bounds the powers logFunc checks for an exact result, beyond
+// it x overflows float64 for any base of at least 2
+const maxLogPower = 1100
+
+// logFunc returns log(x, b), the logarithm of x to the base b, 10 if it is
+// omitted. The result is exact if x is an integer power of b, such as
+// log(1000) or log(1/8, 2), otherwise it is computed in float64.
+func logFunc(o *options, args []*big.Rat) (*big.Rat, error) {
+	x, b := args[0], big.NewRat(10, 1)
+	if len(args) > 1 {
+		b = args[1]
+	}
+	if x.Sign() <= 0 || b.Sign() <= 0 || b.Cmp(ratOne) == 0 {
+		return nil, ErrInvalidArgument
+	}
+	xf, _ := x.Float64()
+	bf, _ := b.Float64()
+	f := logFloat(xf, bf)
+	if math.IsNaN(f) || math.IsInf(f, 0) {
+		return nil, ErrInvalidArgument
+	}
+	if k := math.Round(f); math.Abs(f-k) < 1e-9 && math.Abs(k) <= maxLogPower {
+		if powInt(b, int64(k)).Cmp(x) == 0 {
+			return new(big.Rat).SetInt64(int64(k)), nil
+		}
+	}
+	return new(big.Rat).SetFloat64(f), nil
+}
+
+// logFloat64 is logFunc in float64
+func logFloat64(o *options, args []float64) float64 {
+	if len(args) > 1 {
+		return logFloat(args[0], args[1])
+	}
+	return math.Log10(args[0])
+}
+
+// logFloat returns the logarithm of x to the base b, using the functions of
+// the bases 2 and 10 for their accuracy
+func logFloat(x, b float64) float64 {
+	switch b {
+	case 10:
+		return math.Log10(x)
+	case 2:
+		return math.Log2(x)
+	}
+	return math.Log(x) / math.Log(b)
+}
@@ -0,0 +1,52 @@
+package rpn
+
+import (
+	"errors"
+	"math"
+	"math/big"
+	"testing"
+)
+
+func TestLog(t *testing.T) {
+	cases := []struct {
+		in     string
+		result *big.Rat // nil if inexact
+		float  float64
+		err    error
+	}{
+		{"log(1000)", big.NewRat(3, 1), 3, nil},
+		{"log(0.01)", big.NewRat(-2, 1), -2, nil},
+		{"log(8, 2)", big.NewRat(3, 1), 3, nil},
+		{"log(1 / 8, 2)", big.NewRat(-3, 1), -3, nil},
+		{"log(2 ^ 100, 2)", big.NewRat(100, 1), 100, nil},
+		{"log(8 / 27, 2 / 3)", big.NewRat(3, 1), 3, nil},
+		{"log(1, 7)", big.NewRat(0, 1), 0, nil},
+		{"log(10, 3)", nil, math.Log(10) / math.Log(3), nil},
+		{"log(2)", nil, math.Log10(2), nil},
+		{"log(0)", nil, 0, ErrInvalidArgument},
+		{"log(-1, 2)", nil, 0, ErrInvalidArgument},
+		{"log(5, 1)", nil, 0, ErrInvalidArgument},
+		{"log(5, 0)", nil, 0, ErrInvalidArgument},
+	}
+	for _, tc := range cases {
+		r, err := New(tc.in)
+		if err != nil {
+			t.Errorf("can not convert [%v], err %v", tc.in, err)
+			continue
+		}
+		result, err := r.Result()
+		if !errors.Is(err, tc.err) {
+			t.Errorf("[%v] error should be %v but %v", tc.in, tc.err, err)
+			continue
+		}
+		if err != nil {
+			continue
+		}
+		if f, _ := result.Float64(); tc.result != nil && result.Cmp(tc.result) != 0 || math.Abs(f-tc.float) > 1e-12 {
+			t.Errorf("[%v] result should be %v (%v) but %v", tc.in, tc.result, tc.float, result)
+		}
+		if f, err := r.EvalFloat64(nil); err != nil || math.Abs(f-tc.float) > 1e-12 {
+			t.Errorf("[%v] float result should be %v but %v, err %v", tc.in, tc.float, f, err)
+		}
+	}
+}
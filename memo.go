@@ -0,0 +1,104 @@
+package rpn
+
+import (
+	"hash/fnv"
+	"math"
+	"math/big"
+	"strconv"
+	"strings"
+)
+
+// memoShards is the number of independently locked parts of a memo table,
+// so that evaluations of many rows in parallel seldom wait for each other
+const memoShards = 32
+
+// memoSize is the number of results a memo table keeps, the least recently
+// used being evicted beyond it
+const memoSize = 1 << 16
+
+// memo caches function results keyed by function name and arguments, it is
+// shared by all evaluations of an expression
+type memo struct {
+	shards [memoShards]*lru
+}
+
+func newMemo() *memo {
+	m := &memo{}
+	for i := range m.shards {
+		m.shards[i] = newLRU(memoSize / memoShards)
+	}
+	return m
+}
+
+// shard returns the part of the table holding key
+func (m *memo) shard(key string) *lru {
+	h := fnv.New32a()
+	h.Write([]byte(key))
+	return m.shards[h.Sum32()%memoShards]
+}
+
+func (m *memo) get(key string) (*big.Rat, bool) {
+	return m.shard(key).get(key)
+}
+
+func (m *memo) put(key string, rv *big.Rat) {
+	m.shard(key).put(key, rv)
+}
+
+func memoKey(name string, args []*big.Rat) string {
+	var b strings.Builder
+	b.WriteString(name)
+	for _, arg := range args {
+		b.WriteByte(' ')
+		b.WriteString(arg.RatString())
+	}
+	return b.String()
+}
+
+// floatMemoKey is the key of a call in float64 arithmetic, ~ keeping it
+// apart from exact calls with the same arguments
+func floatMemoKey(name string, args []float64) string {
+	var b strings.Builder
+	b.WriteString(name)
+	b.WriteByte('~')
+	for _, arg := range args {
+		b.WriteByte(' ')
+		b.WriteString(strconv.FormatFloat(arg, 'g', -1, 64))
+	}
+	return b.String()
+}
+
+// call applies fn to args, consulting the memo table when enabled
+func (e *evaluator) call(name string, fn function, args []*big.Rat) (*big.Rat, error) {
+	if e.memo == nil {
+		return fn.call(e.opts, args)
+	}
+	key := memoKey(name, args)
+	if rv, ok := e.memo.get(key); ok {
+		return rv, nil
+	}
+	rv, err := fn.call(e.opts, args)
+	if err != nil {
+		return nil, err
+	}
+	e.memo.put(key, rv)
+	return rv, nil
+}
+
+// fcall applies the float64 implementation of fn to args, consulting the memo
+// table when enabled. Results which are not finite are not kept.
+func (e *evaluator) fcall(name string, fn function, args []float64) float64 {
+	if e.memo == nil {
+		return fn.fcall(e.opts, args)
+	}
+	key := floatMemoKey(name, args)
+	if rv, ok := e.memo.get(key); ok {
+		f, _ := rv.Float64()
+		return f
+	}
+	f := fn.fcall(e.opts, args)
+	if !math.IsInf(f, 0) && !math.IsNaN(f) {
+		e.memo.put(key, new(big.Rat).SetFloat64(f))
+	}
+	return f
+}
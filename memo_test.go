@@ -0,0 +1,138 @@
+package rpn
+
+import (
+	"fmt"
+	"math"
+	"math/big"
+	"sync"
+	"testing"
+)
+
+func TestMemoization(t *testing.T) {
+	calls := 0
+	err := RegisterFunction("count", 1, 1, func(args []*big.Rat) (*big.Rat, error) {
+		calls++
+		return args[0], nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cases := []struct {
+		opts  []Option
+		calls int
+	}{
+		{nil, 3},
+		{[]Option{WithMemoization()}, 2},
+	}
+	for _, tc := range cases {
+		calls = 0
+		r, err := New("count(2) + Count(1 + 1) + count(3)", tc.opts...)
+		if err != nil {
+			t.Fatal(err)
+		}
+		result, err := r.Result()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if result.Cmp(big.NewRat(7, 1)) != 0 {
+			t.Errorf("result should be 7 but %v", result)
+		}
+		if calls != tc.calls {
+			t.Errorf("function should be called %d times but %d", tc.calls, calls)
+		}
+	}
+}
+
+func TestMemoizationConcurrent(t *testing.T) {
+	var mu sync.Mutex
+	calls := 0
+	err := RegisterFunction("slowln", 1, 1, func(args []*big.Rat) (*big.Rat, error) {
+		mu.Lock()
+		calls++
+		mu.Unlock()
+		return new(big.Rat).Mul(args[0], args[0]), nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	p, err := Compile("slowln(x) + 1", WithMemoization())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for x := int64(0); x < 4; x++ {
+		if _, err := p.Eval(map[string]*big.Rat{"x": big.NewRat(x, 1)}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	const rows = 1000
+	var wg sync.WaitGroup
+	errs := make(chan error, rows)
+	for i := 0; i < rows; i++ {
+		wg.Add(1)
+		go func(x int64) {
+			defer wg.Done()
+			rv, err := p.Eval(map[string]*big.Rat{"x": big.NewRat(x, 1)})
+			if err == nil && rv.Cmp(big.NewRat(x*x+1, 1)) != 0 {
+				err = fmt.Errorf("slowln(%d) + 1 should be %d but %v", x, x*x+1, rv)
+			}
+			errs <- err
+		}(int64(i % 4))
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	if calls != 4 {
+		t.Errorf("function should be called once per distinct argument but %d times", calls)
+	}
+}
+
+func TestMemoizationBounded(t *testing.T) {
+	p, err := Compile("x * 2 + round(x / 3)", WithMemoization())
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := int64(0); i < 2*memoSize; i++ {
+		if _, err := p.Eval(map[string]*big.Rat{"x": big.NewRat(i, 1)}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if n := memoLen(p.memo); n > memoSize {
+		t.Errorf("memo should keep at most %d results but %d", memoSize, n)
+	}
+}
+
+func TestMemoizationFloat(t *testing.T) {
+	p, err := Compile("ln(x) + ln(x)", WithMemoization())
+	if err != nil {
+		t.Fatal(err)
+	}
+	rv, err := p.EvalFloat64(map[string]float64{"x": 2})
+	if err != nil || rv != 2*math.Ln2 {
+		t.Errorf("result should be %v but %v, err %v", 2*math.Ln2, rv, err)
+	}
+	if n := memoLen(p.memo); n != 1 {
+		t.Errorf("memo should hold 1 result but %d", n)
+	}
+	rvs, err := p.EvalFloat64Batch(map[string][]float64{"x": {2, 3, 2, 0}})
+	if err != nil || rvs[1] != 2*math.Log(3) || !math.IsInf(rvs[3], -1) {
+		t.Errorf("results should be ln(2), ln(3), ln(2) and -Inf doubled but %v, err %v", rvs, err)
+	}
+	// infinite results are not kept
+	if n := memoLen(p.memo); n != 2 {
+		t.Errorf("memo should hold 2 results but %d", n)
+	}
+	if _, err := p.Eval(map[string]*big.Rat{"x": big.NewRat(2, 1)}); err != nil {
+		t.Fatal(err)
+	}
+	// exact calls are kept apart from float64 ones
+	if n := memoLen(p.memo); n != 3 {
+		t.Errorf("memo should hold 3 results but %d", n)
+	}
+}
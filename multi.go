@@ -0,0 +1,53 @@
+package rpn
+
+import "math/big"
+
+// CompileAll parses expr holding comma separated expressions, such as
+// "a + b, a - b, a * b", and compiles them into a single Program. A
+// subexpression shared by several of them is evaluated once.
+func CompileAll(expr string, opts ...Option) (*Program, error) {
+	r := configure(opts)
+	var err error
+	if r.infix, err = r.tokens(expr); err != nil {
+		return nil, locate(err, expr)
+	}
+	results, start, depth := 0, 0, 0
+	for i := 0; i <= len(r.infix); i++ {
+		end := len(expr)
+		if i < len(r.infix) {
+			t := r.infix[i]
+			if t.v == "(" {
+				depth++
+			} else if t.v == ")" {
+				depth--
+			}
+			if t.tp != tokenTypeSeparator || depth != 0 {
+				continue
+			}
+			end = t.pos
+		}
+		postfix, err := r.parse(r.infix[start:i], end)
+		if err != nil {
+			return nil, locate(err, expr)
+		}
+		r.postfix = append(r.postfix, postfix...)
+		results++
+		start = i + 1
+	}
+	if r.opts.memoize {
+		r.memo = newMemo()
+	}
+	return compile(r, results)
+}
+
+// Results returns the number of expressions in the program
+func (p *Program) Results() int {
+	return p.results
+}
+
+// EvalAll evaluates the program with identifiers bound to the values in
+// vars, returning the value of each of its expressions in order
+func (p *Program) EvalAll(vars map[string]*big.Rat) ([]*big.Rat, error) {
+	e := &evaluator{opts: &p.opts, memo: p.memo, reg: p.reg, vars: vars}
+	return e.runAll(p)
+}
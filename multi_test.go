@@ -0,0 +1,78 @@
+package rpn
+
+import (
+	"errors"
+	"math/big"
+	"testing"
+)
+
+func TestCompileAll(t *testing.T) {
+	cases := []struct {
+		in      string
+		code    string
+		results []*big.Rat
+		err     error
+	}{
+		{"a + b, a - b, a * b", "a b + a b - a b *",
+			[]*big.Rat{big.NewRat(5, 1), big.NewRat(1, 1), big.NewRat(6, 1)}, nil},
+		{"sqrt(a * 3), sqrt(a * 3) + 1", "a 3 * sqrt store0 load0 1 +",
+			[]*big.Rat{big.NewRat(3, 1), big.NewRat(4, 1)}, nil},
+		{"round(a / 2, 1), ifgt(a, b, a, b)", "a 2 / 1 round a b > skip11 a jmp12 b",
+			[]*big.Rat{big.NewRat(3, 2), big.NewRat(3, 1)}, nil},
+		{"b", "b", []*big.Rat{big.NewRat(2, 1)}, nil},
+		{"a, , b", "", nil, ErrUnrecognizedExpression},
+		{"a, (b", "", nil, ErrUnrecognizedExpression},
+		{"a, b / 0", "", nil, ErrZeroDivision},
+	}
+	vars := map[string]*big.Rat{"a": big.NewRat(3, 1), "b": big.NewRat(2, 1)}
+	for _, pratt := range []bool{false, true} {
+		var opts []Option
+		if pratt {
+			opts = append(opts, WithPrattParser())
+		}
+		for _, tc := range cases {
+			p, err := CompileAll(tc.in, opts...)
+			if err != nil {
+				if !errors.Is(err, tc.err) {
+					t.Errorf("[%v] err should be %v but %v", tc.in, tc.err, err)
+				}
+				continue
+			}
+			if tc.code != "" {
+				if code := disassemble(p); code != tc.code {
+					t.Errorf("[%v] code should be %v but %v", tc.in, tc.code, code)
+				}
+			}
+			results, err := p.EvalAll(vars)
+			if tc.err != nil {
+				if !errors.Is(err, tc.err) {
+					t.Errorf("[%v] err should be %v but %v", tc.in, tc.err, err)
+				}
+				continue
+			}
+			if err != nil || len(results) != len(tc.results) || p.Results() != len(tc.results) {
+				t.Errorf("[%v] results should be %v but %v, err %v", tc.in, tc.results, results, err)
+				continue
+			}
+			for i := range results {
+				if results[i].Cmp(tc.results[i]) != 0 {
+					t.Errorf("[%v] result %v should be %v but %v", tc.in, i, tc.results[i], results[i])
+				}
+			}
+			if last, err := p.Eval(vars); err != nil || last.Cmp(tc.results[len(tc.results)-1]) != 0 {
+				t.Errorf("[%v] last result should be %v but %v, err %v", tc.in, tc.results[len(tc.results)-1], last, err)
+			}
+		}
+	}
+
+	// results must not share memory with the constant pool or each other
+	p, err := CompileAll("1, 1, 1 + 0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	results, _ := p.EvalAll(nil)
+	results[0].SetInt64(5)
+	if results[1].Cmp(big.NewRat(1, 1)) != 0 || p.consts[0].Cmp(big.NewRat(1, 1)) != 0 {
+		t.Errorf("results should not be shared but %v, constants %v", results, p.consts)
+	}
+}
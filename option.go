@@ -0,0 +1,257 @@
+package rpn
+
+import (
+	"math/big"
+	"strings"
+)
+
+// Option configures how an expression is evaluated
+type Option func(*options)
+
+type options struct {
+	rounding   big.RoundingMode
+	memoize    bool
+	pratt      bool
+	cacheSize  int
+	maxOps     int
+	pooling    bool
+	zeroDiv    ZeroDivision
+	caseMode   CaseSensitivity
+	implicit   bool
+	zeroValue  *big.Rat
+	semantics  SemanticsVersion
+	promql     bool
+	cells      CellResolver // nil unless cell references are enabled
+	angle      AngleUnit
+	seed       int64
+	synonyms   map[string]string // operators rewritten while parsing, nil to keep them
+	brackets   bool
+	precision  PrecisionContext
+	onToken    func(Token)
+	policy     policy
+	consts     ConstResolver // nil unless constants are inlined
+	unaryMinus string        // the name unary minus is shown as, "@" if empty
+}
+
+func defaultOptions() options {
+	return options{
+		rounding:  big.ToNearestAway,
+		semantics: Semantics1,
+	}
+}
+
+// WithRoundingMode sets the rounding mode used by round(x, n), the default
+// rounds half away from zero
+func WithRoundingMode(mode big.RoundingMode) Option {
+	return func(o *options) {
+		o.rounding = mode
+	}
+}
+
+// WithMemoization caches function results by their arguments, so repeated
+// calls such as ln(x) in one expression are computed once, in exact and in
+// float64 arithmetic. Cached results are reused by later evaluations of the
+// expression, including concurrent ones, which pays off when evaluating many
+// rows with few distinct arguments. The least recently used are evicted once
+// 65536 are kept. Registered functions are assumed to be pure.
+func WithMemoization() Option {
+	return func(o *options) {
+		o.memoize = true
+	}
+}
+
+// WithPrattParser parses with a precedence climbing parser instead of the
+// shunting-yard algorithm. It produces the same postfix notation, but detects
+// unary minus from context and rejects misplaced operands, commas and
+// functions called without parentheses.
+func WithPrattParser() Option {
+	return func(o *options) {
+		o.pratt = true
+	}
+}
+
+// WithEvalCache keeps the results of up to size evaluations with different
+// variable bindings, evicting the least recently used
+func WithEvalCache(size int) Option {
+	return func(o *options) {
+		o.cacheSize = size
+	}
+}
+
+// WithMaxOperations aborts evaluations applying more than n operators and
+// functions, including those of named expressions, with ErrBudgetExceeded.
+// Choosing a branch or short-circuiting a condition is not an operation.
+func WithMaxOperations(n int) Option {
+	return func(o *options) {
+		o.maxOps = n
+	}
+}
+
+// WithPooling reuses the memory of intermediate results within an evaluation
+// and across evaluations through a pool, reducing garbage collection under
+// high throughput. Functions must then neither modify nor keep their
+// arguments, which may be reused once the function returns.
+func WithPooling(enable bool) Option {
+	return func(o *options) {
+		o.pooling = enable
+	}
+}
+
+// ZeroDivision selects what dividing by zero, including taking the remainder
+// of a division by zero, evaluates to
+type ZeroDivision uint8
+
+const (
+	// ZeroDivisionError fails the evaluation with ErrZeroDivision
+	ZeroDivisionError ZeroDivision = iota
+	// ZeroDivisionIEEE evaluates to +Inf, -Inf or NaN like float64 division
+	// in float mode, exact evaluations fail as with ZeroDivisionError
+	ZeroDivisionIEEE
+	// ZeroDivisionValue evaluates to the value given to WithZeroDivisionValue
+	ZeroDivisionValue
+)
+
+// WithZeroDivision selects what dividing by zero evaluates to, the default
+// is ZeroDivisionError
+func WithZeroDivision(policy ZeroDivision) Option {
+	return func(o *options) {
+		o.zeroDiv = policy
+	}
+}
+
+// WithZeroDivisionValue makes dividing by zero evaluate to v
+func WithZeroDivisionValue(v *big.Rat) Option {
+	v = new(big.Rat).Set(v)
+	return func(o *options) {
+		o.zeroDiv = ZeroDivisionValue
+		o.zeroValue = v
+	}
+}
+
+// CaseSensitivity selects which names match regardless of case
+type CaseSensitivity uint8
+
+const (
+	// CaseInsensitiveFunctions matches function names regardless of case
+	// and other names exactly, the default
+	CaseInsensitiveFunctions CaseSensitivity = iota
+	// CaseSensitive matches all names exactly, functions as registered
+	CaseSensitive
+	// CaseInsensitive matches all names regardless of case
+	CaseInsensitive
+)
+
+// WithCaseSensitivity selects which names match regardless of case: those
+// of functions, variables, named expressions and names bound by forms. Which
+// of several variables or named expressions differing only in case matches
+// a name regardless of case is unspecified.
+func WithCaseSensitivity(c CaseSensitivity) Option {
+	return func(o *options) {
+		o.caseMode = c
+	}
+}
+
+// sameName reports whether the names of variables a and b match
+func (o *options) sameName(a, b string) bool {
+	return a == b || o.caseMode == CaseInsensitive && strings.EqualFold(a, b)
+}
+
+// WithImplicitMultiplication multiplies operands written next to each other,
+// such as "2 x", "a b", "2(x + 1)" or "(a)(b)". Names end at whitespace, so
+// "ab" remains the single identifier ab while "a b" is a * b. The implied
+// multiplication has the precedence of *, "1 / 2 x" is (1 / 2) * x.
+func WithImplicitMultiplication() Option {
+	return func(o *options) {
+		o.implicit = true
+	}
+}
+
+// SemanticsVersion selects the semantics expressions are parsed and
+// evaluated with, later versions fix behavior stored expressions may rely on
+type SemanticsVersion uint8
+
+const (
+	// Semantics1 is the original semantics, the default: ^ and ** are left
+	// associative, the remainder of % is computed in float64 and operators
+	// yield NaN outside of their domain in float mode
+	Semantics1 SemanticsVersion = 1 + iota
+	// Semantics2 makes ^ and ** right associative so 2^3^2 is 2^9, computes
+	// the remainder of % exactly and fails operators applied outside of their
+	// domain in float mode with ErrInvalidArgument, such as (0-8)^0.5
+	Semantics2
+
+	// SemanticsLatest is the latest semantics version
+	SemanticsLatest = Semantics2
+)
+
+// WithSemanticsVersion selects the semantics version, so fixes can be
+// adopted per caller without changing the results of stored expressions.
+// Named expressions are parsed with the version passed to RegisterExpr,
+// Semantics1 if none was, and evaluated with the version of the caller.
+func WithSemanticsVersion(v SemanticsVersion) Option {
+	return func(o *options) {
+		o.semantics = v
+	}
+}
+
+// rightPow reports whether ^ and ** are right associative
+func (o *options) rightPow() bool {
+	return o.semantics >= Semantics2 || o.promql
+}
+
+// associativity returns the associativity of the operator op, ^ and ** being
+// right associative if rightPow is set
+func associativity(op string, rightPow bool) int8 {
+	if rightPow && (op == "^" || op == "**") {
+		return associativeRight
+	}
+	return operators[op][1]
+}
+
+// AngleUnit selects the unit of the angles trigonometric functions take and
+// yield
+type AngleUnit uint8
+
+const (
+	Radians AngleUnit = iota // the default
+	Degrees
+)
+
+// WithAngleUnit makes sin, cos and tan take angles, arcsin, arccos, arctan
+// and atan2 yield them and normangle wrap them in the unit u. In degrees sin,
+// cos and tan of multiples of 15 are exact where the result is rational, so
+// that sin(180) is 0 and tan(90) is invalid.
+func WithAngleUnit(u AngleUnit) Option {
+	return func(o *options) {
+		o.angle = u
+	}
+}
+
+// WithRandSeed seeds rand(n), which otherwise uses the seed 0
+func WithRandSeed(seed int64) Option {
+	return func(o *options) {
+		o.seed = seed
+	}
+}
+
+// WithTokenCallback calls fn with each token of the expression in infix
+// order once it is split into tokens and before it is parsed, so that names
+// can be counted or functions vetted without a second pass. Tokens are
+// given as the parser sees them, after signs, bars, synonyms, cell ranges
+// and implicit multiplication are rewritten. Args is zero for functions,
+// whose arguments are yet to be counted.
+func WithTokenCallback(fn func(Token)) Option {
+	return func(o *options) {
+		o.onToken = fn
+	}
+}
+
+// WithUnaryMinusName shows unary minus as name, such as "neg", instead of
+// "@" in Postfix, Tokens, PostfixTokens and the tokens given to
+// WithTokenCallback, so that consumers of the postfix notation need not know
+// the marker used internally. Expressions are parsed as before.
+func WithUnaryMinusName(name string) Option {
+	return func(o *options) {
+		o.unaryMinus = name
+	}
+}
@@ -0,0 +1,160 @@
+package rpn
+
+import "strings"
+
+// Plan describes how a program is evaluated, so formulas can be tuned
+type Plan struct {
+	Instructions int      // instructions of the code, bodies of forms included
+	Operations   int      // operations counted against WithMaxOperations taking every branch, bodies of forms once
+	Cost         int      // estimated evaluation cost in the units of Complexity
+	Temporaries  []string // subexpressions computed once and kept in a register
+	Constants    []string // subexpressions of constants only, computed on every evaluation as they are not folded
+	Float        []string // operations computed in float64 and converted back when evaluated exactly
+}
+
+// floatFunctions are the builtin functions computed in float64 by Eval
+var floatFunctions = map[string]bool{
+	"sin": true, "cos": true, "tan": true, "ln": true, "log": true,
+	"arcsin": true, "arccos": true, "arctan": true, "atan2": true, "hypot": true, "normangle": true, "deg2rad": true, "rad2deg": true, "sqrt": true,
+	"stdev": true, "corr": true, "irr": true, "integrate": true, "solve": true,
+}
+
+// Plan returns the execution plan of the program. The compiler does not fold
+// constants, which would change the float64 results and the operations
+// counted, so Constants lists those worth folding by hand.
+func (p *Program) Plan() Plan {
+	w := &planner{p: p, floats: make(map[int]bool), consts: make(map[int]bool)}
+	p.describe(&w.pl)
+	for _, root := range p.tree {
+		w.constant(root, w.walk(root))
+	}
+	return w.pl
+}
+
+// describe adds the code of p and of the bodies of its forms to pl
+func (p *Program) describe(pl *Plan) {
+	pl.Instructions += len(p.code)
+	for _, in := range p.code {
+		if in.op.counted() {
+			pl.Operations++
+		}
+		switch in.op {
+		case opConst, opLoad, opLoadReg, opLocal:
+			pl.Cost += costOperand
+		case opCall, opForm:
+			pl.Cost += costFunction
+		default:
+			if in.op >= opNeg && in.op <= opGe {
+				pl.Cost += operatorCosts[in.tok.v]
+			}
+		}
+	}
+	for _, n := range p.temps {
+		pl.Temporaries = append(pl.Temporaries, p.format(n))
+	}
+	for _, sub := range p.subs {
+		sub.body.describe(pl)
+	}
+}
+
+// planner walks the trees of a program, structurally equal subtrees are
+// reported once
+type planner struct {
+	p      *Program
+	pl     Plan
+	floats map[int]bool // subtrees checked for float64 operations
+	consts map[int]bool // subtrees reported as constants
+}
+
+// walk adds the float64 operations of the tree n to the plan and the largest
+// constant subexpressions below it. It reports whether n is constant.
+func (w *planner) walk(n *node) bool {
+	constant := n.tok.tp == tokenTypeOperand || n.tok.tp == tokenTypeOperator ||
+		n.tok.tp == tokenTypeFunction && forms[strings.ToLower(n.tok.v)] == nil
+	consts := make([]bool, len(n.args))
+	for i, arg := range n.args {
+		consts[i] = w.walk(arg)
+		constant = constant && consts[i]
+	}
+	if !constant {
+		for i, arg := range n.args {
+			w.constant(arg, consts[i])
+		}
+	}
+	if !w.floats[n.id] && w.p.float(n) {
+		w.pl.Float = append(w.pl.Float, w.p.format(n))
+	}
+	w.floats[n.id] = true
+	return constant
+}
+
+// constant adds n to the constants of the plan if it is constant and more
+// than a literal
+func (w *planner) constant(n *node, constant bool) {
+	literal := len(n.args) == 0 || n.tok.v == "@" && len(n.args[0].args) == 0
+	if constant && !literal && !w.consts[n.id] {
+		w.consts[n.id] = true
+		w.pl.Constants = append(w.pl.Constants, w.p.format(n))
+	}
+}
+
+// float reports whether the exact evaluation of n goes through float64
+func (p *Program) float(n *node) bool {
+	exact := p.opts.precision.exact()
+	switch n.tok.tp {
+	case tokenTypeOperator:
+		switch opcodes[n.tok.v] {
+		case opPow:
+			// integer powers are exact given a precision
+			y := n.args[1]
+			if exact || y.tok.tp != tokenTypeOperand {
+				return true
+			}
+			v, err := parseLiteral(y.tok.v)
+			return err != nil || !v.IsInt()
+		case opMod:
+			return p.opts.semantics < Semantics2
+		}
+	case tokenTypeFunction:
+		name := strings.ToLower(n.tok.v)
+		if name == "sqrt" {
+			// rational roots are exact, others are computed to a precision
+			return exact && !square(n.args[0])
+		}
+		return floatFunctions[name]
+	}
+	return false
+}
+
+// square reports whether n is a number whose square root is rational
+func square(n *node) bool {
+	if n.tok.tp != tokenTypeOperand {
+		return false
+	}
+	v, err := parseLiteral(n.tok.v)
+	return err == nil && v.Sign() >= 0 && ratSqrt(v) != nil
+}
+
+// format returns the infix notation of n
+func (p *Program) format(n *node) string {
+	return formatNode(n, p.opts.rightPow())
+}
+
+// formatNode returns the infix notation of n, with the parentheses needed
+// and unary minus written as "-"
+func formatNode(n *node, rightPow bool) string {
+	var b strings.Builder
+	var prev *token
+	for _, tok := range infixOf(n, rightPow, nil) {
+		v := tok.v
+		if v == "@" {
+			v = "-"
+		}
+		if prev != nil && prev.v != "(" && prev.v != "@" && v != ")" && v != "," && prev.tp != tokenTypeFunction {
+			b.WriteByte(' ')
+		}
+		b.WriteString(v)
+		prev = tok
+	}
+	return b.String()
+}
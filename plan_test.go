@@ -0,0 +1,48 @@
+package rpn
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestPlan(t *testing.T) {
+	cases := []struct {
+		in   string
+		opts []Option
+		plan Plan
+	}{
+		{"(x + 1) * (x + 1) + 2 ^ 3 * x", nil, Plan{Instructions: 12, Operations: 5, Cost: 20,
+			Temporaries: []string{"x + 1"}, Constants: []string{"2 ^ 3"}, Float: []string{"2 ^ 3"}}},
+		{"sqrt(2) * -3 + sin(x) % 2", nil, Plan{Instructions: 10, Operations: 6, Cost: 32,
+			Constants: []string{"sqrt(2) * -3"}, Float: []string{"sqrt(2)", "sin(x)", "sin(x) % 2"}}},
+		{"sqrt(2) * -3 + sin(x) % 2", []Option{WithPrecision(PrecisionContext{Digits: 10}), WithSemanticsVersion(Semantics2)},
+			Plan{Instructions: 10, Operations: 6, Cost: 32, Constants: []string{"sqrt(2) * -3"}, Float: []string{"sin(x)"}}},
+		{"sqrt(2.25) + sqrt(x)", nil, Plan{Instructions: 5, Operations: 3, Cost: 19,
+			Constants: []string{"sqrt(2.25)"}, Float: []string{"sqrt(x)"}}},
+		{"x ^ 2 + x ^ 0.5", []Option{WithPrecision(PrecisionContext{Bits: 53})},
+			Plan{Instructions: 7, Operations: 3, Cost: 21, Float: []string{"x ^ 0.5"}}},
+		{"sum(i, 1, n, i ^ 2 + 1 / 3)", nil, Plan{Instructions: 10, Operations: 4, Cost: 27,
+			Constants: []string{"1 / 3"}, Float: []string{"i ^ 2"}}},
+		{"-x + 1", nil, Plan{Instructions: 4, Operations: 2, Cost: 4}},
+	}
+	for _, tc := range cases {
+		p, err := Compile(tc.in, tc.opts...)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if plan := p.Plan(); !reflect.DeepEqual(plan, tc.plan) {
+			t.Errorf("[%v] plan should be %+v but %+v", tc.in, tc.plan, plan)
+		}
+	}
+}
+
+func TestPlanCompileAll(t *testing.T) {
+	p, err := CompileAll("1 + 2, x * (1 + 2)")
+	if err != nil {
+		t.Fatal(err)
+	}
+	plan := p.Plan()
+	if !reflect.DeepEqual(plan.Temporaries, []string{"1 + 2"}) || !reflect.DeepEqual(plan.Constants, []string{"1 + 2"}) {
+		t.Errorf("plan should share 1 + 2 but %+v", plan)
+	}
+}
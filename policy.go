@@ -0,0 +1,112 @@
+package rpn
+
+import "strings"
+
+// PolicyError is a function or operator the options do not allow, Pos and
+// End are the byte offsets of its use as far as they are known. In
+// expressions spanning several lines Line and Col locate Pos as well like
+// those of SyntaxError, they are 0 otherwise.
+type PolicyError struct {
+	Pos  int
+	End  int
+	Name string
+	Line int
+	Col  int
+}
+
+func (e *PolicyError) Error() string {
+	return e.Name + " is not allowed " + position(e.Pos, e.Line, e.Col)
+}
+
+// Unwrap makes every PolicyError match ErrNotAllowed
+func (e *PolicyError) Unwrap() error {
+	return ErrNotAllowed
+}
+
+// policy restricts the functions and operators expressions may use, a nil
+// set allowing all
+type policy struct {
+	allowedFuncs map[string]bool
+	deniedFuncs  map[string]bool
+	allowedOps   map[string]bool
+	deniedOps    map[string]bool
+}
+
+// WithAllowedFunctions rejects expressions calling functions other than
+// names with a PolicyError. Function names match regardless of case, like
+// the registry does, forms such as sum are functions too.
+func WithAllowedFunctions(names ...string) Option {
+	set := nameSet(names, strings.ToLower)
+	return func(o *options) {
+		o.policy.allowedFuncs = set
+	}
+}
+
+// WithDeniedFunctions rejects expressions calling any of names with a
+// PolicyError
+func WithDeniedFunctions(names ...string) Option {
+	set := nameSet(names, strings.ToLower)
+	return func(o *options) {
+		o.policy.deniedFuncs = set
+	}
+}
+
+// WithAllowedOperators rejects expressions applying operators other than
+// ops with a PolicyError. Synonyms such as × and * are the same operator,
+// and allowing - allows negation as well.
+func WithAllowedOperators(ops ...string) Option {
+	set := nameSet(ops, canonicalOperator)
+	return func(o *options) {
+		o.policy.allowedOps = set
+	}
+}
+
+// WithDeniedOperators rejects expressions applying any of ops with a
+// PolicyError, denying ^ denies ** and denying - denies negation as well
+func WithDeniedOperators(ops ...string) Option {
+	set := nameSet(ops, canonicalOperator)
+	return func(o *options) {
+		o.policy.deniedOps = set
+	}
+}
+
+func nameSet(names []string, canonical func(string) string) map[string]bool {
+	set := make(map[string]bool, len(names))
+	for _, name := range names {
+		set[canonical(name)] = true
+	}
+	return set
+}
+
+// canonicalOperator returns the operator op stands for, - for negation
+func canonicalOperator(op string) string {
+	if op == "@" {
+		return "-"
+	}
+	if c, ok := DefaultSynonyms()[op]; ok {
+		return c
+	}
+	return op
+}
+
+// check returns a PolicyError for the first token of postfix not allowed
+func (p *policy) check(postfix []*token) error {
+	for _, t := range postfix {
+		var allowed, denied map[string]bool
+		name := t.v
+		switch t.tp {
+		case tokenTypeFunction:
+			allowed, denied = p.allowedFuncs, p.deniedFuncs
+			name = strings.ToLower(name)
+		case tokenTypeOperator:
+			allowed, denied = p.allowedOps, p.deniedOps
+			name = canonicalOperator(name)
+		default:
+			continue
+		}
+		if allowed != nil && !allowed[name] || denied[name] {
+			return &PolicyError{Pos: t.pos, End: t.pos + len(t.v), Name: opName(t)}
+		}
+	}
+	return nil
+}
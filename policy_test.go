@@ -0,0 +1,92 @@
+package rpn
+
+import (
+	"errors"
+	"math/big"
+	"testing"
+)
+
+func TestPolicy(t *testing.T) {
+	cases := []struct {
+		in   string
+		opts []Option
+		err  *PolicyError
+	}{
+		{"round(x, 2) + abs(y)", []Option{WithAllowedFunctions("round", "abs")}, nil},
+		{"ROUND(x) + 1", []Option{WithAllowedFunctions("round")}, nil},
+		{"round(x) + sqrt(y)", []Option{WithAllowedFunctions("round")}, &PolicyError{Pos: 11, End: 15, Name: "sqrt"}},
+		{"1 + |y|", []Option{WithAllowedFunctions("round")}, &PolicyError{Pos: 4, End: 7, Name: "abs"}},
+		{"rand(1) * 2", []Option{WithDeniedFunctions("rand")}, &PolicyError{Pos: 0, End: 4, Name: "rand"}},
+		{"sum(i, 1, 3, i)", []Option{WithDeniedFunctions("SUM")}, &PolicyError{Pos: 0, End: 3, Name: "sum"}},
+		{"2 ** 3", []Option{WithDeniedOperators("^")}, &PolicyError{Pos: 2, End: 4, Name: "**"}},
+		{"2 × 3", []Option{WithDeniedOperators("*")}, &PolicyError{Pos: 2, End: 4, Name: "×"}},
+		{"-x", []Option{WithDeniedOperators("-")}, &PolicyError{Pos: 0, End: 1, Name: "-"}},
+		{"a + b * 2", []Option{WithAllowedOperators("+", "*")}, nil},
+		{"a + b / 2", []Option{WithAllowedOperators("+", "*")}, &PolicyError{Pos: 6, End: 7, Name: "/"}},
+		{"a > 1 && b", []Option{WithAllowedOperators(">"), WithAllowedFunctions()}, &PolicyError{Pos: 6, End: 8, Name: "&&"}},
+		{"1 + 2 + sqrt(4)", []Option{WithDeniedFunctions("sqrt")}, &PolicyError{Pos: 8, End: 12, Name: "sqrt"}},
+	}
+	for _, tc := range cases {
+		_, err := New(tc.in, append(tc.opts, WithPrattParser())...)
+		if tc.err == nil {
+			if err != nil {
+				t.Errorf("[%v] err %v", tc.in, err)
+			}
+			continue
+		}
+		var perr *PolicyError
+		if !errors.As(err, &perr) || *perr != *tc.err {
+			t.Errorf("[%v] err should be %v but %v", tc.in, tc.err, err)
+		}
+		if !errors.Is(err, ErrNotAllowed) {
+			t.Errorf("[%v] err %v should be %v", tc.in, err, ErrNotAllowed)
+		}
+		if _, err := New(tc.in, tc.opts...); !errors.As(err, &perr) || *perr != *tc.err {
+			t.Errorf("[%v] shunting-yard err should be %v but %v", tc.in, tc.err, err)
+		}
+	}
+
+	if _, err := New("1 + 2 + sqrt(4)", WithDeniedFunctions("sqrt")); err == nil || err.Error() != "sqrt is not allowed at offset 8" {
+		t.Errorf("err should be at offset 8 but %v", err)
+	}
+	if _, err := B().Call("sqrt", 2).Build(WithDeniedFunctions("sqrt")); !errors.Is(err, ErrNotAllowed) {
+		t.Errorf("built expression err should be %v but %v", ErrNotAllowed, err)
+	}
+	if _, err := CompileAll("1, 2 ^ 2", WithDeniedOperators("^")); !errors.Is(err, ErrNotAllowed) {
+		t.Errorf("CompileAll err should be %v but %v", ErrNotAllowed, err)
+	}
+}
+
+func TestPolicyCaseSensitive(t *testing.T) {
+	twice := func(args []*big.Rat) (*big.Rat, error) {
+		return new(big.Rat).Add(args[0], args[0]), nil
+	}
+	if err := RegisterFunction("policyTwice", 1, 1, twice); err != nil {
+		t.Fatal(err)
+	}
+	for _, opts := range [][]Option{
+		{WithAllowedFunctions("policyTwice")},
+		{WithAllowedFunctions("POLICYTWICE")},
+		{WithDeniedFunctions("sqrt")},
+	} {
+		r, err := New("policyTwice(2)", append(opts, WithCaseSensitivity(CaseSensitive))...)
+		if err != nil {
+			t.Errorf("policyTwice should be allowed but %v", err)
+			continue
+		}
+		if result, err := r.Result(); err != nil || result.Cmp(big.NewRat(4, 1)) != 0 {
+			t.Errorf("result should be 4 but %v, err %v", result, err)
+		}
+	}
+	for _, opts := range [][]Option{
+		{WithDeniedFunctions("policyTwice")},
+		{WithDeniedFunctions("policytwice")},
+		{WithAllowedFunctions("sqrt")},
+	} {
+		_, err := New("policyTwice(2)", append(opts, WithCaseSensitivity(CaseSensitive))...)
+		var perr *PolicyError
+		if !errors.As(err, &perr) || perr.Name != "policyTwice" {
+			t.Errorf("policyTwice should not be allowed but %v", err)
+		}
+	}
+}
@@ -0,0 +1,86 @@
+package rpn
+
+import (
+	"math/big"
+	"sync"
+)
+
+var (
+	ratPool   = sync.Pool{New: func() interface{} { return new(big.Rat) }}
+	stackPool = sync.Pool{New: func() interface{} { return new(stack) }}
+)
+
+// stack is the evaluation stack of the virtual machine. Results allocated by
+// the evaluation are marked as owned: no register, constant or caller refers
+// to them, so with pooling their memory is reused once they are consumed.
+type stack struct {
+	vals    []*big.Rat
+	owned   []bool
+	pooling bool
+}
+
+func newStack(pooling bool, size int) *stack {
+	if !pooling {
+		return &stack{vals: make([]*big.Rat, 0, size), owned: make([]bool, 0, size)}
+	}
+	s := stackPool.Get().(*stack)
+	s.pooling = true
+	return s
+}
+
+// free returns a pooled stack to the pool, the values left on it escape
+func (s *stack) free() {
+	if !s.pooling {
+		return
+	}
+	for i := range s.vals {
+		s.vals[i] = nil
+	}
+	s.vals, s.owned = s.vals[:0], s.owned[:0]
+	stackPool.Put(s)
+}
+
+func (s *stack) push(v *big.Rat, owned bool) {
+	s.vals = append(s.vals, v)
+	s.owned = append(s.owned, owned)
+}
+
+func (s *stack) pop() (*big.Rat, bool) {
+	n := len(s.vals) - 1
+	v, owned := s.vals[n], s.owned[n]
+	s.vals, s.owned = s.vals[:n], s.owned[:n]
+	return v, owned
+}
+
+// drop removes the top n values, releasing the owned ones other than keep
+func (s *stack) drop(n int, keep *big.Rat) {
+	for i := len(s.vals) - n; i < len(s.vals); i++ {
+		if s.pooling && s.owned[i] && s.vals[i] != keep {
+			ratPool.Put(s.vals[i])
+		}
+	}
+	s.vals, s.owned = s.vals[:len(s.vals)-n], s.owned[:len(s.owned)-n]
+}
+
+// dst returns where to store the result of an operation on the popped
+// operands x and y, reusing an owned operand
+func (s *stack) dst(x *big.Rat, xo bool, y *big.Rat, yo bool) *big.Rat {
+	if !s.pooling {
+		return new(big.Rat)
+	}
+	switch {
+	case xo:
+		return x
+	case yo:
+		return y
+	}
+	return ratPool.Get().(*big.Rat)
+}
+
+// release returns a consumed operand to the pool unless it is not owned or
+// holds the result z
+func (s *stack) release(v *big.Rat, owned bool, z *big.Rat) {
+	if s.pooling && owned && v != z {
+		ratPool.Put(v)
+	}
+}
@@ -0,0 +1,88 @@
+package rpn
+
+import (
+	"math/big"
+	"sync"
+	"testing"
+)
+
+func TestPooling(t *testing.T) {
+	for _, tc := range testCase {
+		if !tc.canConv {
+			continue
+		}
+		r, err := New(tc.in, WithPooling(true))
+		if err != nil {
+			t.Fatal(err)
+		}
+		for i := 0; i < 3; i++ {
+			result, err := r.Eval(nil)
+			if err != nil {
+				if tc.canCalc {
+					t.Error(err)
+				}
+				continue
+			}
+			if result.Cmp(tc.result) != 0 {
+				t.Errorf("[%v] result should be %v but %v", tc.in, tc.result, result)
+			}
+		}
+	}
+}
+
+func TestPoolingShared(t *testing.T) {
+	r, err := New("(x + 1) * (x + 1) - -(x + 1) + case(1, x) + 2 * 2", WithPooling(true))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var wg sync.WaitGroup
+	for i := int64(0); i < 8; i++ {
+		wg.Add(1)
+		go func(i int64) {
+			defer wg.Done()
+			x := big.NewRat(i, 1)
+			var results []*big.Rat
+			for j := 0; j < 50; j++ {
+				result, err := r.Eval(map[string]*big.Rat{"x": x})
+				if err != nil {
+					t.Error(err)
+					return
+				}
+				results = append(results, result)
+			}
+			want := big.NewRat((i+1)*(i+1)+(i+1)+i+4, 1)
+			for _, result := range results {
+				if result.Cmp(want) != 0 {
+					t.Errorf("x = %v result should be %v but %v", i, want, result)
+					return
+				}
+			}
+			if x.Cmp(big.NewRat(i, 1)) != 0 {
+				t.Errorf("variable x has been modified to %v", x)
+			}
+		}(i)
+	}
+	wg.Wait()
+}
+
+func BenchmarkPooling(b *testing.B) {
+	for _, pooling := range []bool{false, true} {
+		r, err := New("(x * 3 + 1) / 2 - x * x * x + (x - 1) * (x + 1)", WithPooling(pooling))
+		if err != nil {
+			b.Fatal(err)
+		}
+		vars := map[string]*big.Rat{"x": big.NewRat(7, 3)}
+		name := "alloc"
+		if pooling {
+			name = "pool"
+		}
+		b.Run(name, func(b *testing.B) {
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				if _, err := r.Eval(vars); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
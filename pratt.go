@@ -0,0 +1,238 @@
+package rpn
+
+import "fmt"
+
+// pratt is a precedence climbing parser building a syntax tree
+type pratt struct {
+	input    []*token
+	i        int
+	end      int  // length of the expression, the position of its end
+	recover  bool // skip over syntax errors instead of stopping
+	errs     []*SyntaxError
+	reg      *registry
+	rightPow bool // ^ and ** are right associative
+}
+
+func parsePratt(input []*token, end int, reg *registry, rightPow bool) ([]*token, error) {
+	p := &pratt{input: input, end: end, reg: reg, rightPow: rightPow}
+	root, err := p.parse()
+	if err != nil {
+		return nil, err
+	}
+	return flatten(root, make([]*token, 0, len(input))), nil
+}
+
+// flatten appends the postfix notation of the tree rooted at n to output
+func flatten(n *node, output []*token) []*token {
+	for _, arg := range n.args {
+		output = flatten(arg, output)
+	}
+	return append(output, n.tok)
+}
+
+func (p *pratt) parse() (*node, error) {
+	root, err := p.expr(0)
+	if err != nil {
+		return nil, err
+	}
+	for p.peek() != nil {
+		// keep what follows the stray token in the tree of a recovered parse
+		t := p.next()
+		if err := p.report(p.errorAt(t, "unexpected %s")); err != nil {
+			return nil, err
+		}
+		bad := &node{tok: &token{tp: tokenTypeUnknown, pos: t.pos}}
+		if p.peek() == nil {
+			bad.args = []*node{root}
+			break
+		}
+		rest, err := p.expr(0)
+		if err != nil {
+			return nil, err
+		}
+		bad.args = []*node{root, rest}
+		root = bad
+	}
+	return root, nil
+}
+
+func (p *pratt) peek() *token {
+	if p.i < len(p.input) {
+		return p.input[p.i]
+	}
+	return nil
+}
+
+func (p *pratt) next() *token {
+	t := p.peek()
+	if t != nil {
+		p.i++
+	}
+	return t
+}
+
+// errorAt builds a syntax error at t, nil meaning the end of the expression
+func (p *pratt) errorAt(t *token, format string) *SyntaxError {
+	err := &SyntaxError{Pos: p.end, End: p.end}
+	desc := "end of expression"
+	if t != nil {
+		err.Pos, err.End = t.pos, t.pos+len(t.v)
+		desc = fmt.Sprintf("%q", t.v)
+	}
+	err.Msg = fmt.Sprintf(format, desc)
+	return err
+}
+
+// report returns err unless recovering, then it is recorded and parsing goes
+// on. Errors at the position of the previous one are follow-up errors and
+// not recorded.
+func (p *pratt) report(err *SyntaxError) error {
+	if !p.recover {
+		return err
+	}
+	if len(p.errs) == 0 || p.errs[len(p.errs)-1].Pos != err.Pos {
+		p.errs = append(p.errs, err)
+	}
+	return nil
+}
+
+// fail reports a syntax error at t. When recovering it skips to the next
+// comma or closing parenthesis at the current nesting level and yields an
+// invalid node.
+func (p *pratt) fail(t *token, format string) (*node, error) {
+	serr := p.errorAt(t, format)
+	if err := p.report(serr); err != nil {
+		return nil, err
+	}
+	for depth := 0; ; p.i++ {
+		t := p.peek()
+		if t == nil || depth == 0 && (t.v == "," || t.v == ")") {
+			break
+		}
+		if t.v == "(" {
+			depth++
+		} else if t.v == ")" {
+			depth--
+		}
+	}
+	return &node{tok: &token{tp: tokenTypeUnknown, pos: serr.Pos}}, nil
+}
+
+// expr parses an expression whose binary operators bind tighter than minPrec
+func (p *pratt) expr(minPrec int8) (*node, error) {
+	left, err := p.prefix()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		t := p.peek()
+		if t == nil || t.tp != tokenTypeOperator {
+			return left, nil
+		}
+		prec, as := operators[t.v][0], associativity(t.v, p.rightPow)
+		if prec <= minPrec {
+			return left, nil
+		}
+		p.i++
+		if as == associativeRight {
+			prec--
+		}
+		right, err := p.expr(prec)
+		if err != nil {
+			return nil, err
+		}
+		left = &node{tok: t, args: []*node{left, right}}
+	}
+}
+
+// prefix parses an operand, a parenthesised expression, a function call or
+// a prefix expression with a sign, a unary plus leaving it unchanged
+func (p *pratt) prefix() (*node, error) {
+	t := p.next()
+	if t == nil {
+		return p.fail(nil, "unexpected %s")
+	}
+	switch t.tp {
+	case tokenTypeOperand, tokenTypeIdentifier:
+		return &node{tok: t}, nil
+	case tokenTypeOperator:
+		if t.v != "-" && t.v != "+" {
+			break
+		}
+		arg, err := p.expr(operators["@"][0] - 1)
+		if err != nil || t.v == "+" {
+			return arg, err
+		}
+		neg := &token{tp: tokenTypeOperator, v: "@", pos: t.pos}
+		return &node{tok: neg, args: []*node{arg}}, nil
+	case tokenTypeParenthesis:
+		if t.v != "(" {
+			break
+		}
+		n, err := p.expr(0)
+		if err != nil {
+			return nil, err
+		}
+		if next := p.peek(); next == nil || next.v != ")" {
+			if _, err := p.fail(next, "expected \")\" but found %s"); err != nil {
+				return nil, err
+			}
+			if next = p.peek(); next == nil || next.v != ")" {
+				return n, nil
+			}
+		}
+		p.i++
+		return n, nil
+	case tokenTypeFunction:
+		return p.call(t)
+	case tokenTypeUnknown:
+		return p.fail(t, "unknown token %s")
+	}
+	p.i--
+	return p.fail(t, "unexpected %s")
+}
+
+// call parses the parenthesised argument list of function t
+func (p *pratt) call(t *token) (*node, error) {
+	if next := p.peek(); next == nil || next.v != "(" {
+		return p.fail(next, "expected \"(\" but found %s")
+	}
+	p.i++
+	n := &node{tok: t}
+	if next := p.peek(); next != nil && next.v == ")" {
+		p.i++
+	} else {
+		for {
+			arg, err := p.expr(0)
+			if err != nil {
+				return nil, err
+			}
+			n.args = append(n.args, arg)
+			next := p.next()
+			if next != nil && next.tp == tokenTypeSeparator {
+				continue
+			}
+			if next != nil && next.v == ")" {
+				break
+			}
+			if next != nil {
+				p.i--
+			}
+			if _, err := p.fail(next, "expected \",\" or \")\" but found %s"); err != nil {
+				return nil, err
+			}
+			if next = p.next(); next == nil || next.v == ")" {
+				break
+			}
+		}
+	}
+	if !p.reg.validArity(t.v, len(n.args)) {
+		err := &SyntaxError{Pos: t.pos, End: t.pos + len(t.v),
+			Msg: fmt.Sprintf("wrong number of arguments to %s", t.v)}
+		if err := p.report(err); err != nil {
+			return nil, err
+		}
+	}
+	t.argc = len(n.args)
+	return n, nil
+}
@@ -0,0 +1,78 @@
+package rpn
+
+import (
+	"errors"
+	"math/big"
+	"testing"
+)
+
+func TestPrattParser(t *testing.T) {
+	for _, tc := range testCase {
+		r, err := New(tc.in, WithPrattParser())
+		if err != nil {
+			if tc.canConv {
+				t.Errorf("can not convert infix notation [%v], err %v", tc.in, err)
+			}
+			continue
+		}
+		if !tc.canConv {
+			t.Errorf("infix [%v] should not be converted", tc.in)
+			continue
+		}
+		if !equal(tc.postfix, r.Postfix()) {
+			t.Errorf("infix [%v] postfix should be %v but %v", tc.in, tc.postfix, r.Postfix())
+			continue
+		}
+		if result, err := r.Result(); err != nil {
+			if tc.canCalc {
+				t.Error(err)
+			}
+		} else if result.Cmp(tc.result) != 0 {
+			t.Errorf("postfix %v result should be %v but %v", tc.postfix, tc.result, result)
+		}
+	}
+}
+
+func TestPrattParserUnary(t *testing.T) {
+	cases := []struct {
+		in      string
+		postfix []string
+		result  *big.Rat
+	}{
+		{"--2", []string{"2", "@", "@"}, big.NewRat(2, 1)},
+		{"-2^2", []string{"2", "2", "^", "@"}, big.NewRat(-4, 1)},
+		{"2^-1*3", []string{"2", "1", "@", "^", "3", "*"}, big.NewRat(3, 2)},
+		{"1 - -(2 - 3)", []string{"1", "2", "3", "-", "@", "-"}, big.NewRat(0, 1)},
+		{"case(1, -1)", []string{"1", "1", "@", "case"}, big.NewRat(-1, 1)},
+	}
+	for _, tc := range cases {
+		r, err := New(tc.in, WithPrattParser())
+		if err != nil {
+			t.Errorf("can not convert infix notation [%v], err %v", tc.in, err)
+			continue
+		}
+		if !equal(tc.postfix, r.Postfix()) {
+			t.Errorf("infix [%v] postfix should be %v but %v", tc.in, tc.postfix, r.Postfix())
+			continue
+		}
+		result, err := r.Result()
+		if err != nil {
+			t.Error(err)
+			continue
+		}
+		if result.Cmp(tc.result) != 0 {
+			t.Errorf("[%v] result should be %v but %v", tc.in, tc.result, result)
+		}
+	}
+}
+
+func TestPrattParserInvalid(t *testing.T) {
+	for _, in := range []string{
+		"", "2 3", "sin 3", "1 +", "* 2", "(1))", "abs(1,)", "round(1 2)",
+		"abs(", "()", "1 @ 2", "1 $ 2",
+	} {
+		if _, err := New(in, WithPrattParser()); !errors.Is(err, ErrUnrecognizedExpression) {
+			t.Errorf("infix [%v] error should be %v but %v", in, ErrUnrecognizedExpression, err)
+		}
+	}
+}
@@ -0,0 +1,115 @@
+package rpn
+
+import (
+	"math"
+	"math/big"
+)
+
+// PrecisionContext rounds the result of every operator and function applied
+// in exact evaluations, either to Bits significant bits like a big.Float or
+// to Digits significant decimal digits, with the rounding mode Mode. Powers
+// with integer exponents are then computed exactly before rounding and
+// square roots to the precision, other functions are still computed in
+// float64. Numbers and variables are used as given. The zero context, the
+// default, leaves results exact.
+type PrecisionContext struct {
+	Bits   uint
+	Digits int
+	Mode   big.RoundingMode
+}
+
+// WithPrecision rounds the results of operators and functions according to
+// the precision context c. Evaluations in float64 are not affected.
+func WithPrecision(c PrecisionContext) Option {
+	return func(o *options) {
+		o.precision = c
+	}
+}
+
+// exact reports whether c leaves results exact
+func (c PrecisionContext) exact() bool {
+	return c.Bits == 0 && c.Digits <= 0
+}
+
+// bits returns the precision in bits of c, enough for its digits if it is
+// given in digits
+func (c PrecisionContext) bits() uint {
+	if c.Bits > 0 {
+		return c.Bits
+	}
+	return uint(math.Ceil(float64(c.Digits)*math.Log2(10))) + 8
+}
+
+// round sets z to x rounded according to c and returns z
+func (c PrecisionContext) round(z, x *big.Rat) *big.Rat {
+	switch {
+	case x.Sign() == 0 || c.exact():
+		return z.Set(x)
+	case c.Bits > 0:
+		f := new(big.Float).SetPrec(c.Bits).SetMode(c.Mode).SetRat(x)
+		f.Rat(z)
+		return z
+	}
+	return z.Set(roundRat(x, c.Digits-1-msd(x), c.Mode))
+}
+
+// arith applies the binary operator op to x and y setting z like binary,
+// rounding the result according to the precision context
+func (e *evaluator) arith(op opcode, z, x, y *big.Rat) (*big.Rat, error) {
+	c := e.opts.precision
+	if c.exact() {
+		return binary(op, z, x, y, e.opts.semantics)
+	}
+	if op == opPow && y.IsInt() && y.Num().CmpAbs(big.NewInt(maxIntArg)) <= 0 {
+		if x.Sign() == 0 && y.Sign() < 0 {
+			return nil, ErrZeroDivision
+		}
+		return c.round(z, powInt(x, y.Num().Int64())), nil
+	}
+	rv, err := binary(op, z, x, y, e.opts.semantics)
+	if err != nil || op >= opEq {
+		return rv, err
+	}
+	return c.round(rv, rv), nil
+}
+
+// rounded returns v rounded according to the precision context, v itself
+// if it is exact
+func (e *evaluator) rounded(v *big.Rat) *big.Rat {
+	if e.opts.precision.exact() {
+		return v
+	}
+	return e.opts.precision.round(new(big.Rat), v)
+}
+
+// powInt returns x raised to the integer power n exactly
+func powInt(x *big.Rat, n int64) *big.Rat {
+	abs := n
+	if abs < 0 {
+		abs = -abs
+	}
+	num := new(big.Int).Exp(x.Num(), big.NewInt(abs), nil)
+	den := new(big.Int).Exp(x.Denom(), big.NewInt(abs), nil)
+	if n < 0 {
+		num, den = den, num
+	}
+	return new(big.Rat).SetFrac(num, den)
+}
+
+// sqrtFunc returns the square root of its argument, exact if it is rational
+// such as sqrt(9/4), otherwise computed in float64 unless a precision context
+// asks for more
+func sqrtFunc(o *options, args []*big.Rat) (*big.Rat, error) {
+	if args[0].Sign() < 0 {
+		return nil, ErrInvalidArgument
+	}
+	if rv := ratSqrt(args[0]); rv != nil {
+		return o.precision.round(rv, rv), nil
+	}
+	if o.precision.exact() {
+		return floatFunc(math.Sqrt).call(o, args)
+	}
+	f := new(big.Float).SetPrec(o.precision.bits() + 2).SetRat(args[0])
+	rv, _ := f.Sqrt(f).Rat(nil)
+	return o.precision.round(rv, rv), nil
+}
@@ -0,0 +1,65 @@
+package rpn
+
+import (
+	"math/big"
+	"testing"
+)
+
+func TestPrecisionContext(t *testing.T) {
+	digits := func(n int, mode big.RoundingMode) Option {
+		return WithPrecision(PrecisionContext{Digits: n, Mode: mode})
+	}
+	cases := []struct {
+		in     string
+		opt    Option
+		result string
+	}{
+		{"1 / 3", nil, "1/3"},
+		{"1 / 3", digits(4, big.ToNearestEven), "3333/10000"},
+		{"2 / 3", digits(4, big.ToNearestEven), "6667/10000"},
+		{"2 / 3", digits(4, big.ToZero), "3333/5000"},
+		{"1 / 3 * 3", digits(4, big.ToNearestEven), "9999/10000"},
+		{"1 / 3 * 3", nil, "1"},
+		{"123456 + 1", digits(3, big.ToNearestAway), "123000"},
+		{"1 / 3", WithPrecision(PrecisionContext{Bits: 4, Mode: big.ToNearestEven}), "11/32"},
+		{"3 ^ 40", digits(50, big.ToNearestEven), "12157665459056928801"},
+		{"3 ^ 40", digits(5, big.ToNearestEven), "12158000000000000000"},
+		{"2 ^ -3", digits(5, big.ToNearestEven), "1/8"},
+		{"sqrt(2)", digits(30, big.ToNearestEven), "141421356237309504880168872421/100000000000000000000000000000"},
+		{"sqrt(2)", digits(3, big.ToNearestEven), "141/100"},
+		{"sqrt(9 / 4)", nil, "3/2"},
+		{"sqrt(0.0001)", nil, "1/100"},
+		{"sqrt(10000000000000000000200000000000000000001)", nil, "100000000000000000001"},
+		{"sqrt(123454321)", digits(3, big.ToNearestEven), "11100"},
+		{"round(2 / 3, 3)", digits(2, big.ToNearestEven), "67/100"},
+		{"1 / 3 > 0.3333", digits(4, big.ToNearestEven), "0"},
+	}
+	for _, tc := range cases {
+		var opts []Option
+		if tc.opt != nil {
+			opts = append(opts, tc.opt)
+		}
+		r, err := New(tc.in, opts...)
+		if err != nil {
+			t.Errorf("can not convert [%v], err %v", tc.in, err)
+			continue
+		}
+		rv, err := r.Result()
+		if err != nil {
+			t.Errorf("[%v] err %v", tc.in, err)
+			continue
+		}
+		if rv.RatString() != tc.result {
+			t.Errorf("[%v] result should be %v but %v", tc.in, tc.result, rv.RatString())
+		}
+	}
+
+	r, err := New("x / 3 + 1", digits(3, big.ToNearestEven))
+	if err != nil {
+		t.Fatal(err)
+	}
+	x, err := r.Explain(map[string]*big.Rat{"x": big.NewRat(1, 1)})
+	if want := "x (1) / 3 = 0.333; + 1 = 1.33"; err != nil || x.String() != want {
+		t.Errorf("explanation should be %q but %q, err %v", want, x, err)
+	}
+}
@@ -0,0 +1,58 @@
+package rpn
+
+import "math/big"
+
+// Profile bundles the settings results depend on, so that evaluations of an
+// expression with the same profile and variables format to the same string.
+// The zero Profile rounds half to even, DefaultProfile rounds like New.
+type Profile struct {
+	Precision     int              // decimals of formatted results
+	Rounding      big.RoundingMode // rounding of round(x, n) and formatted results
+	AngleUnit     AngleUnit
+	ZeroDivision  ZeroDivision
+	ZeroValue     *big.Rat         // what dividing by zero yields with ZeroDivisionValue
+	Seed          int64            // seed of rand(n)
+	MaxOperations int              // no limit if zero
+	Context       PrecisionContext // rounding of intermediate results, exact if zero
+}
+
+// DefaultProfile returns the profile of expressions created without options,
+// formatting results with 6 decimals
+func DefaultProfile() Profile {
+	return Profile{Precision: 6, Rounding: big.ToNearestAway}
+}
+
+// WithProfile applies the settings of p, options given after it override them
+func WithProfile(p Profile) Option {
+	return func(o *options) {
+		o.rounding = p.Rounding
+		o.angle = p.AngleUnit
+		o.zeroDiv = p.ZeroDivision
+		o.zeroValue = nil
+		if p.ZeroValue != nil {
+			o.zeroValue = new(big.Rat).Set(p.ZeroValue)
+		}
+		if o.zeroDiv == ZeroDivisionValue && o.zeroValue == nil {
+			o.zeroValue = new(big.Rat)
+		}
+		o.seed = p.Seed
+		o.maxOps = p.MaxOperations
+		o.precision = p.Context
+	}
+}
+
+// Format renders v in decimal with p.Precision decimals rounded with
+// p.Rounding, negative precisions round to the left of the decimal point
+func (p Profile) Format(v *big.Rat) string {
+	decimals := p.Precision
+	if decimals > maxScale {
+		decimals = maxScale
+	} else if decimals < -maxScale {
+		decimals = -maxScale
+	}
+	x := roundRat(v, decimals, p.Rounding)
+	if decimals < 0 {
+		decimals = 0
+	}
+	return x.FloatString(decimals)
+}
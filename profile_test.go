@@ -0,0 +1,92 @@
+package rpn
+
+import (
+	"errors"
+	"math"
+	"math/big"
+	"testing"
+)
+
+func TestProfile(t *testing.T) {
+	p := DefaultProfile()
+	p.Precision = 3
+	p.AngleUnit = Degrees
+	p.ZeroDivision = ZeroDivisionValue
+	p.ZeroValue = big.NewRat(-1, 1)
+	p.Seed = 42
+	cases := []struct {
+		in  string
+		out string
+	}{
+		{"sin(30)", "0.500"},
+		{"arctan(1)", "45.000"},
+		{"cos(0) / 0", "-1.000"},
+		{"2 / 3", "0.667"},
+		{"round(2.5)", "3.000"},
+		{"rand(1) == rand(1)", "1.000"},
+		{"rand(1) != rand(2)", "1.000"},
+	}
+	for _, tc := range cases {
+		r, err := New(tc.in, WithProfile(p))
+		if err != nil {
+			t.Errorf("can not convert [%v], err %v", tc.in, err)
+			continue
+		}
+		rv, err := r.Result()
+		if err != nil {
+			t.Errorf("[%v] err %v", tc.in, err)
+			continue
+		}
+		if got := p.Format(rv); got != tc.out {
+			t.Errorf("[%v] result should be %v but %v", tc.in, tc.out, got)
+		}
+	}
+
+	p.Rounding = big.ToNearestEven
+	p.Precision = 0
+	r, _ := New("round(2.5) + 0.5", WithProfile(p))
+	if rv, err := r.Result(); err != nil || p.Format(rv) != "2" {
+		t.Errorf("result should be 2 but %v, err %v", rv, err)
+	}
+	p.MaxOperations = 1
+	r, _ = New("x + 2 + x", WithProfile(p))
+	if _, err := r.Eval(map[string]*big.Rat{"x": ratOne}); !errors.Is(err, ErrBudgetExceeded) {
+		t.Errorf("err should be %v but %v", ErrBudgetExceeded, err)
+	}
+
+	r, _ = New("sin(90) + arcsin(1)", WithAngleUnit(Degrees))
+	if f, err := r.EvalFloat64(nil); err != nil || math.Abs(f-91) > 1e-9 {
+		t.Errorf("float result should be 91 but %v, err %v", f, err)
+	}
+}
+
+func TestRand(t *testing.T) {
+	eval := func(seed int64, n int64) *big.Rat {
+		r, err := New("rand(n)", WithRandSeed(seed))
+		if err != nil {
+			t.Fatal(err)
+		}
+		rv, err := r.Eval(map[string]*big.Rat{"n": big.NewRat(n, 1)})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return rv
+	}
+	seen := make(map[string]bool)
+	for n := int64(0); n < 100; n++ {
+		rv := eval(7, n)
+		if rv.Sign() < 0 || rv.Cmp(ratOne) >= 0 {
+			t.Errorf("rand(%d) should be in [0, 1) but %v", n, rv)
+		}
+		if rv.Cmp(eval(7, n)) != 0 {
+			t.Errorf("rand(%d) should not vary across evaluations", n)
+		}
+		seen[rv.RatString()] = true
+	}
+	if len(seen) != 100 {
+		t.Errorf("rand should yield 100 distinct values but %d", len(seen))
+	}
+	if eval(7, 1).Cmp(eval(8, 1)) == 0 {
+		t.Errorf("rand(1) should depend on the seed")
+	}
+}
@@ -0,0 +1,374 @@
+package rpn
+
+import (
+	"math/big"
+	"strconv"
+	"strings"
+)
+
+// Program is an expression compiled to code for a small stack machine,
+// subexpressions appearing more than once are evaluated once and kept in a
+// register
+type Program struct {
+	code      []instr
+	consts    []*big.Rat // constant pool, shared values must never be modified
+	fconsts   []float64  // the constant pool in float mode
+	names     []string
+	funcs     []function
+	funcNames []string
+	subs      []sub    // forms and the programs of their bodies
+	captures  []string // the names of the values captured
+	regs      int
+	stack     int     // the largest depth of the evaluation stack
+	temps     []*node // the subtree held in each register
+	tree      []*node // the roots of the expressions
+	results   int     // number of comma separated expressions
+	opts      options
+	memo      *memo
+	reg       *registry
+}
+
+// node is a subtree of the expression, structurally equal subtrees share an id
+type node struct {
+	tok  *token
+	args []*node
+	id   int
+}
+
+// Compile parses expr and compiles it into a Program
+func Compile(expr string, opts ...Option) (*Program, error) {
+	r, err := New(expr, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return r.Compile()
+}
+
+// Compile returns the Program the expression is evaluated with
+func (r *RPN) Compile() (*Program, error) {
+	return r.prog, nil
+}
+
+// compile compiles the postfix of r holding the given number of expressions
+func compile(r *RPN, results int) (*Program, error) {
+	roots, n, err := buildTree(r.postfix, results, r.opts.caseMode == CaseInsensitive)
+	if err != nil {
+		return nil, err
+	}
+	p := &Program{results: results, opts: r.opts, memo: r.memo, reg: r.reg, tree: roots}
+	c := newCompiler(p, n, nil)
+	for _, root := range roots {
+		c.count(root)
+	}
+	for _, root := range roots {
+		if err := c.emit(root); err != nil {
+			return nil, err
+		}
+	}
+	p.stack = maxStack(p)
+	return p, nil
+}
+
+// Run evaluates the program
+func (p *Program) Run() (*big.Rat, error) {
+	return p.Eval(nil)
+}
+
+// Eval evaluates the program with identifiers bound to the values in vars,
+// the result is never shared with the program or vars. A program compiled
+// by CompileAll yields the value of its last expression.
+func (p *Program) Eval(vars map[string]*big.Rat) (*big.Rat, error) {
+	e := &evaluator{opts: &p.opts, memo: p.memo, reg: p.reg, vars: vars}
+	return e.run(p)
+}
+
+// arity returns the number of operands a postfix token consumes
+func arity(tok *token) int {
+	switch tok.tp {
+	case tokenTypeOperator:
+		if tok.v == "@" {
+			return 1
+		}
+		return 2
+	case tokenTypeFunction:
+		return tok.argc
+	}
+	return 0
+}
+
+// buildTree turns postfix holding the given number of expressions into
+// trees, returning their roots and the number of distinct subtrees. Function
+// names are compared regardless of case, identifiers too if fold is set.
+func buildTree(postfix []*token, results int, fold bool) ([]*node, int, error) {
+	ids := make(map[string]int)
+	var stack []*node
+	for _, tok := range postfix {
+		n := arity(tok)
+		if len(stack) < n {
+			return nil, 0, ErrUnrecognizedExpression
+		}
+		nd := &node{tok: tok, args: append([]*node(nil), stack[len(stack)-n:]...)}
+		stack = stack[:len(stack)-n]
+
+		var key strings.Builder
+		key.WriteString(strconv.Itoa(int(tok.tp)))
+		key.WriteByte(' ')
+		if tok.tp == tokenTypeFunction || fold && tok.tp == tokenTypeIdentifier {
+			key.WriteString(strings.ToLower(tok.v))
+		} else {
+			key.WriteString(tok.v)
+		}
+		for _, arg := range nd.args {
+			key.WriteByte(' ')
+			key.WriteString(strconv.Itoa(arg.id))
+		}
+		id, ok := ids[key.String()]
+		if !ok {
+			id = len(ids)
+			ids[key.String()] = id
+		}
+		nd.id = id
+		stack = append(stack, nd)
+	}
+	if len(stack) != results {
+		return nil, 0, ErrUnrecognizedExpression
+	}
+	return stack, len(ids), nil
+}
+
+type compiler struct {
+	p      *Program
+	uses   []int          // number of times each subtree is needed
+	regs   map[int]int    // register holding each computed common subtree
+	names  map[string]int // index of each identifier in the names of p
+	consts map[string]int // index of each value in the constant pool of p
+	scope  []string       // local variables, the innermost last
+}
+
+// newCompiler returns a compiler emitting into p the code of a tree with n
+// distinct subtrees
+func newCompiler(p *Program, n int, scope []string) *compiler {
+	return &compiler{p: p, uses: make([]int, n), regs: make(map[int]int),
+		names: make(map[string]int), consts: make(map[string]int), scope: scope}
+}
+
+// count records how often each subtree is needed, subtrees of a repeated
+// subtree are only needed once. The bodies of forms are compiled apart.
+func (c *compiler) count(n *node) {
+	c.uses[n.id]++
+	if c.uses[n.id] > 1 {
+		return
+	}
+	f := c.formOf(n)
+	for i, arg := range n.args {
+		if f == nil || i != f.name && i != f.body {
+			c.count(arg)
+		}
+	}
+}
+
+func (c *compiler) emit(n *node) error {
+	if reg, ok := c.regs[n.id]; ok {
+		c.p.code = append(c.p.code, instr{op: opLoadReg, arg: reg})
+		return nil
+	}
+	if f := c.formOf(n); f != nil {
+		if err := c.form(n, f); err != nil {
+			return err
+		}
+	} else if name := c.builtin(n); name == "piecewise" || name == "pw" || name == "case" {
+		if err := c.piecewise(n); err != nil {
+			return err
+		}
+	} else if op, ok := ifOps[c.builtin(n)]; ok {
+		if err := c.ifCompare(n, op); err != nil {
+			return err
+		}
+	} else if c.builtin(n) == "capture" {
+		if err := c.capture(n); err != nil {
+			return err
+		}
+	} else if n.tok.tp == tokenTypeOperator && (n.tok.v == "&&" || n.tok.v == "||") {
+		if err := c.branch(n); err != nil {
+			return err
+		}
+	} else {
+		for _, arg := range n.args {
+			if err := c.emit(arg); err != nil {
+				return err
+			}
+		}
+		in, err := c.instr(n.tok)
+		if err != nil {
+			return err
+		}
+		c.p.code = append(c.p.code, in)
+	}
+	if len(n.args) > 0 && c.uses[n.id] > 1 {
+		c.regs[n.id] = c.p.regs
+		c.p.temps = append(c.p.temps, n)
+		c.p.code = append(c.p.code, instr{op: opStore, arg: c.p.regs})
+		c.p.regs++
+	}
+	return nil
+}
+
+// branch emits a boolean operator which skips its right operand once the
+// left one decides the result
+func (c *compiler) branch(n *node) error {
+	if err := c.emit(n.args[0]); err != nil {
+		return err
+	}
+	op := opJumpFalse
+	if n.tok.v == "||" {
+		op = opJumpTrue
+	}
+	jump := len(c.p.code)
+	c.p.code = append(c.p.code, instr{op: op, tok: n.tok})
+	first := c.p.regs
+	if err := c.emit(n.args[1]); err != nil {
+		return err
+	}
+	c.forget(first)
+	c.p.code = append(c.p.code, instr{op: opBool, tok: n.tok})
+	c.p.code[jump].arg = len(c.p.code)
+	return nil
+}
+
+// capture emits capture(name, value) recording value under name
+func (c *compiler) capture(n *node) error {
+	name := n.args[0]
+	if name.tok.tp != tokenTypeIdentifier {
+		return ErrUnrecognizedExpression
+	}
+	if err := c.emit(n.args[1]); err != nil {
+		return err
+	}
+	c.p.captures = append(c.p.captures, name.tok.v)
+	c.p.code = append(c.p.code, instr{op: opCapture, arg: len(c.p.captures) - 1, tok: n.tok})
+	return nil
+}
+
+// piecewise emits piecewise(c1, v1, c2, v2, ..., default) evaluating only the
+// conditions up to the first one which holds and its value, or the optional
+// default if none does
+func (c *compiler) piecewise(n *node) error {
+	first := c.p.regs
+	var jumps []int
+	for i := 0; i+1 < len(n.args); i += 2 {
+		if err := c.emit(n.args[i]); err != nil {
+			return err
+		}
+		skip := len(c.p.code)
+		c.p.code = append(c.p.code, instr{op: opSkip, tok: n.tok})
+		value := c.p.regs
+		if err := c.emit(n.args[i+1]); err != nil {
+			return err
+		}
+		c.forget(value)
+		jumps = append(jumps, len(c.p.code))
+		c.p.code = append(c.p.code, instr{op: opJump, tok: n.tok})
+		c.p.code[skip].arg = len(c.p.code)
+	}
+	if len(n.args)%2 == 1 {
+		if err := c.emit(n.args[len(n.args)-1]); err != nil {
+			return err
+		}
+	} else {
+		c.p.code = append(c.p.code, instr{op: opNoMatch, tok: n.tok})
+	}
+	for _, jump := range jumps {
+		c.p.code[jump].arg = len(c.p.code)
+	}
+	c.forget(first)
+	return nil
+}
+
+// ifOps are the comparisons of the functions ifXX(a, b, x, y), which yield
+// x if the comparison of a with b holds and y otherwise
+var ifOps = map[string]string{
+	"ifgt": ">", "ifge": ">=", "iflt": "<", "ifle": "<=", "ifeq": "==", "ifne": "!=",
+}
+
+// ifCompare emits ifXX(a, b, x, y) comparing a with b by the operator op,
+// only the value chosen is evaluated
+func (c *compiler) ifCompare(n *node, op string) error {
+	for _, arg := range n.args[:2] {
+		if err := c.emit(arg); err != nil {
+			return err
+		}
+	}
+	c.p.code = append(c.p.code, instr{op: opcodes[op], tok: &token{tp: tokenTypeOperator, v: op, pos: n.tok.pos}})
+	skip := len(c.p.code)
+	c.p.code = append(c.p.code, instr{op: opSkip, tok: n.tok})
+	value := c.p.regs
+	if err := c.emit(n.args[2]); err != nil {
+		return err
+	}
+	c.forget(value)
+	jump := len(c.p.code)
+	c.p.code = append(c.p.code, instr{op: opJump, tok: n.tok})
+	c.p.code[skip].arg = len(c.p.code)
+	if err := c.emit(n.args[3]); err != nil {
+		return err
+	}
+	c.p.code[jump].arg = len(c.p.code)
+	c.forget(value)
+	return nil
+}
+
+// forget drops the registers from first on, stored by code which may be
+// skipped, so that they are not loaded afterwards
+func (c *compiler) forget(first int) {
+	for id, reg := range c.regs {
+		if reg >= first {
+			delete(c.regs, id)
+		}
+	}
+}
+
+// instr translates a postfix token into an instruction
+func (c *compiler) instr(tok *token) (instr, error) {
+	p := c.p
+	switch tok.tp {
+	case tokenTypeOperand:
+		v, err := parseLiteral(tok.v)
+		if err != nil {
+			return instr{}, err
+		}
+		i, ok := c.consts[v.RatString()]
+		if !ok {
+			i = len(p.consts)
+			p.consts = append(p.consts, v)
+			f, _ := v.Float64()
+			p.fconsts = append(p.fconsts, f)
+			c.consts[v.RatString()] = i
+		}
+		return instr{op: opConst, arg: i, tok: tok}, nil
+	case tokenTypeIdentifier:
+		for i := len(c.scope) - 1; i >= 0; i-- {
+			if c.p.opts.sameName(c.scope[i], tok.v) {
+				return instr{op: opLocal, arg: i, tok: tok}, nil
+			}
+		}
+		i, ok := c.names[tok.v]
+		if !ok {
+			i = len(p.names)
+			p.names = append(p.names, tok.v)
+			c.names[tok.v] = i
+		}
+		return instr{op: opLoad, arg: i, tok: tok}, nil
+	case tokenTypeOperator:
+		if op, ok := opcodes[tok.v]; ok {
+			return instr{op: op, tok: tok}, nil
+		}
+	case tokenTypeFunction:
+		name := strings.ToLower(tok.v)
+		if fn, ok := p.reg.functions[name]; ok {
+			p.funcs = append(p.funcs, fn)
+			p.funcNames = append(p.funcNames, name)
+			return instr{op: opCall, arg: len(p.funcs) - 1, argc: tok.argc, tok: tok}, nil
+		}
+	}
+	return instr{}, ErrUnrecognizedExpression
+}
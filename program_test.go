@@ -0,0 +1,235 @@
+package rpn
+
+import (
+	"errors"
+	"math"
+	"math/big"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestCompile(t *testing.T) {
+	cases := []struct {
+		in     string
+		code   string
+		result *big.Rat
+	}{
+		{"(1 + 2) * 3", "1 2 + 3 *", big.NewRat(9, 1)},
+		{"(1 + 2) * (1 + 2) - (1+2)", "1 2 + store0 load0 * load0 -", big.NewRat(6, 1)},
+		{"sqrt(4*4) + (sqrt(4 * 4) + 1) * (sqrt(4*4) + 1)",
+			"4 4 * sqrt store0 load0 1 + store1 load1 * +", big.NewRat(29, 1)},
+		{"ABS(-2) + abs(-2)", "2 @ ABS store0 load0 +", big.NewRat(4, 1)},
+		{"round(1.25, 1) + round(1.25)", "1.25 1 round 1.25 round +", big.NewRat(23, 10)},
+	}
+	for _, tc := range cases {
+		p, err := Compile(tc.in)
+		if err != nil {
+			t.Errorf("can not compile [%v], err %v", tc.in, err)
+			continue
+		}
+		if code := disassemble(p); code != tc.code {
+			t.Errorf("[%v] code should be %v but %v", tc.in, tc.code, code)
+		}
+		result, err := p.Run()
+		if err != nil {
+			t.Error(err)
+			continue
+		}
+		if result.Cmp(tc.result) != 0 {
+			t.Errorf("[%v] result should be %v but %v", tc.in, tc.result, result)
+		}
+	}
+}
+
+func TestCompileResult(t *testing.T) {
+	for _, tc := range testCase {
+		if !tc.canConv {
+			continue
+		}
+		p, err := Compile(tc.in)
+		if err != nil {
+			t.Errorf("can not compile [%v], err %v", tc.in, err)
+			continue
+		}
+		result, err := p.Run()
+		if err != nil {
+			if tc.canCalc {
+				t.Error(err)
+			}
+			continue
+		}
+		if result.Cmp(tc.result) != 0 {
+			t.Errorf("[%v] result should be %v but %v", tc.in, tc.result, result)
+		}
+	}
+}
+
+func disassemble(p *Program) string {
+	s := make([]string, 0, len(p.code))
+	for _, in := range p.code {
+		switch in.op {
+		case opStore:
+			s = append(s, "store"+strconv.Itoa(in.arg))
+		case opLoadReg:
+			s = append(s, "load"+strconv.Itoa(in.arg))
+		case opJumpFalse:
+			s = append(s, "jz"+strconv.Itoa(in.arg))
+		case opJumpTrue:
+			s = append(s, "jnz"+strconv.Itoa(in.arg))
+		case opSkip:
+			s = append(s, "skip"+strconv.Itoa(in.arg))
+		case opJump:
+			s = append(s, "jmp"+strconv.Itoa(in.arg))
+		default:
+			s = append(s, in.tok.v)
+		}
+	}
+	return strings.Join(s, " ")
+}
+
+func TestConstantPool(t *testing.T) {
+	p, err := Compile("2 * x + 2 * y + 2.50 - 2.5 + 0.5")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(p.consts) != 3 {
+		t.Errorf("constant pool should hold 3 values but %v", p.consts)
+	}
+	vars := map[string]*big.Rat{"x": big.NewRat(1, 1), "y": big.NewRat(2, 1)}
+	for i := 0; i < 2; i++ {
+		result, err := p.Eval(vars)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if result.Cmp(big.NewRat(13, 2)) != 0 {
+			t.Errorf("result should be 13/2 but %v", result)
+		}
+	}
+	if p.consts[0].Cmp(big.NewRat(2, 1)) != 0 {
+		t.Errorf("constant 2 has been modified to %v", p.consts[0])
+	}
+}
+
+func TestShortCircuit(t *testing.T) {
+	cases := []struct {
+		in     string
+		code   string
+		vars   map[string]*big.Rat
+		result *big.Rat
+	}{
+		{"x != 0 && 1 / x > 2", "x 0 != jz10 1 x / 2 > &&",
+			map[string]*big.Rat{"x": new(big.Rat)}, new(big.Rat)},
+		{"x != 0 && 1 / x > 2", "x 0 != jz10 1 x / 2 > &&",
+			map[string]*big.Rat{"x": big.NewRat(1, 4)}, big.NewRat(1, 1)},
+		{"x == 0 || 1 / x > 2", "x 0 == jnz10 1 x / 2 > ||",
+			map[string]*big.Rat{"x": new(big.Rat)}, big.NewRat(1, 1)},
+		{"x == 0 || 1 / x > 2", "x 0 == jnz10 1 x / 2 > ||",
+			map[string]*big.Rat{"x": big.NewRat(1, 2)}, new(big.Rat)},
+		{"2 && 3", "2 jz4 3 &&", nil, big.NewRat(1, 1)},
+		{"0 || 0 || 5", "0 jnz4 0 || jnz7 5 ||", nil, big.NewRat(1, 1)},
+		// the register set by the skipped sqrt must not be loaded afterwards
+		{"(x && sqrt(4) > 1) + sqrt(4)", "x jz8 4 sqrt store0 1 > && 4 sqrt store1 +",
+			map[string]*big.Rat{"x": new(big.Rat)}, big.NewRat(2, 1)},
+	}
+	for _, tc := range cases {
+		r, err := New(tc.in)
+		if err != nil {
+			t.Errorf("can not convert [%v], err %v", tc.in, err)
+			continue
+		}
+		p, _ := r.Compile()
+		if code := disassemble(p); code != tc.code {
+			t.Errorf("[%v] code should be %v but %v", tc.in, tc.code, code)
+		}
+		result, err := r.Eval(tc.vars)
+		if err != nil || result.Cmp(tc.result) != 0 {
+			t.Errorf("[%v] with %v result should be %v but %v, err %v", tc.in, tc.vars, tc.result, result, err)
+		}
+		fvars := make(map[string]float64)
+		for k, v := range tc.vars {
+			fvars[k], _ = v.Float64()
+		}
+		want, _ := tc.result.Float64()
+		if f, err := r.EvalFloat64(fvars); err != nil || f != want {
+			t.Errorf("[%v] with %v float result should be %v but %v, err %v", tc.in, fvars, want, f, err)
+		}
+		if m, err := r.EvalSigFigs(tc.vars); err != nil || m.Value.Cmp(tc.result) != 0 {
+			t.Errorf("[%v] with %v measured result should be %v but %v, err %v", tc.in, tc.vars, tc.result, m.Value, err)
+		}
+	}
+}
+
+func TestPiecewise(t *testing.T) {
+	cases := []struct {
+		in     string
+		code   string
+		x      *big.Rat
+		result *big.Rat
+		err    error
+	}{
+		{"piecewise(x < 0, -x, x >= 0, x)", "x 0 < skip7 x @ jmp14 x 0 >= skip13 x jmp14 piecewise",
+			big.NewRat(-3, 1), big.NewRat(3, 1), nil},
+		{"piecewise(x < 0, -x, x >= 0, x)", "", big.NewRat(2, 1), big.NewRat(2, 1), nil},
+		{"pw(x != 0, 1 / x, 0)", "x 0 != skip8 1 x / jmp9 0", new(big.Rat), new(big.Rat), nil},
+		{"pw(x != 0, 1 / x, 0)", "", big.NewRat(4, 1), big.NewRat(1, 4), nil},
+		{"pw(x <= 100, x * 0.1, x <= 200, 10 + (x - 100) * 0.2, 30 + (x - 200) * 0.3)", "",
+			big.NewRat(150, 1), big.NewRat(20, 1), nil},
+		{"pw(x <= 100, x * 0.1, x <= 200, 10 + (x - 100) * 0.2, 30 + (x - 200) * 0.3)", "",
+			big.NewRat(300, 1), big.NewRat(60, 1), nil},
+		{"pw(x > 10, 1)", "", new(big.Rat), nil, ErrInvalidArgument},
+		// the register set by the skipped sqrt must not be loaded afterwards
+		{"pw(x, sqrt(4) + 1, 0) + (sqrt(4) + 1)", "", new(big.Rat), big.NewRat(3, 1), nil},
+	}
+	for _, tc := range cases {
+		r, err := New(tc.in)
+		if err != nil {
+			t.Errorf("can not convert [%v], err %v", tc.in, err)
+			continue
+		}
+		p, _ := r.Compile()
+		if code := disassemble(p); tc.code != "" && code != tc.code {
+			t.Errorf("[%v] code should be %v but %v", tc.in, tc.code, code)
+		}
+		result, err := r.Eval(map[string]*big.Rat{"x": tc.x})
+		if !errors.Is(err, tc.err) || tc.err == nil && result.Cmp(tc.result) != 0 {
+			t.Errorf("[%v] with x = %v result should be %v, %v but %v, %v", tc.in, tc.x, tc.result, tc.err, result, err)
+		}
+		x, _ := tc.x.Float64()
+		f, err := r.EvalFloat64(map[string]float64{"x": x})
+		if !errors.Is(err, tc.err) {
+			t.Errorf("[%v] with x = %v float err should be %v but %v", tc.in, x, tc.err, err)
+			continue
+		}
+		if tc.err != nil {
+			continue
+		}
+		if want, _ := tc.result.Float64(); math.Abs(f-want) > 1e-9 {
+			t.Errorf("[%v] with x = %v float result should be %v but %v", tc.in, x, want, f)
+		}
+	}
+}
+
+func TestMaxStack(t *testing.T) {
+	cases := []struct {
+		in    string
+		stack int
+	}{
+		{"1", 1},
+		{"1 + 2 * 3", 3},
+		{"(1 + 2) * 3", 2},
+		{"x > 0 && y > 1", 2},
+		{"pw(x > 0, 1 + 2 * 3, 0)", 3},
+		{"1 + sum(i, 1, 3, i * (i + 1))", 6},
+		{"a, b * c, d", 3},
+	}
+	for _, tc := range cases {
+		p, err := CompileAll(tc.in)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if stack := p.MaxStack(); stack != tc.stack {
+			t.Errorf("[%v] max stack should be %d but %d", tc.in, tc.stack, stack)
+		}
+	}
+}
@@ -0,0 +1,130 @@
+package rpn
+
+import (
+	"math"
+	"math/big"
+)
+
+// promqlFunctions are the functions of PromQL applicable to scalars
+var promqlFunctions = map[string]function{
+	"abs":   functions["abs"],
+	"ceil":  {1, 1, functions["ceil"].call, func(o *options, args []float64) float64 { return math.Ceil(args[0]) }},
+	"floor": {1, 1, functions["floor"].call, func(o *options, args []float64) float64 { return math.Floor(args[0]) }},
+	"round": {1, 2, promqlRound, func(o *options, args []float64) float64 {
+		if len(args) == 1 {
+			return math.Floor(args[0] + 0.5)
+		}
+		inverse := 1 / args[1]
+		return math.Floor(args[0]*inverse+0.5) / inverse
+	}},
+	"sgn": {1, 1, func(o *options, args []*big.Rat) (*big.Rat, error) {
+		return big.NewRat(int64(args[0].Sign()), 1), nil
+	}, nil},
+	"clamp": {3, 3, func(o *options, args []*big.Rat) (*big.Rat, error) {
+		if args[1].Cmp(args[2]) > 0 {
+			return nil, ErrInvalidArgument
+		}
+		return clamp(args[0], args[1], args[2]), nil
+	}, nil},
+	"clamp_min": {2, 2, func(o *options, args []*big.Rat) (*big.Rat, error) {
+		return clamp(args[0], args[1], nil), nil
+	}, func(o *options, args []float64) float64 { return math.Max(args[0], args[1]) }},
+	"clamp_max": {2, 2, func(o *options, args []*big.Rat) (*big.Rat, error) {
+		return clamp(args[0], nil, args[1]), nil
+	}, func(o *options, args []float64) float64 { return math.Min(args[0], args[1]) }},
+	"exp":   floatFunc(math.Exp),
+	"sqrt":  floatFunc(math.Sqrt),
+	"ln":    floatFunc(math.Log),
+	"log2":  floatFunc(math.Log2),
+	"log10": floatFunc(math.Log10),
+	"sin":   floatFunc(math.Sin),
+	"cos":   floatFunc(math.Cos),
+	"tan":   floatFunc(math.Tan),
+	"asin":  floatFunc(math.Asin),
+	"acos":  floatFunc(math.Acos),
+	"atan":  floatFunc(math.Atan),
+	"sinh":  floatFunc(math.Sinh),
+	"cosh":  floatFunc(math.Cosh),
+	"tanh":  floatFunc(math.Tanh),
+	"asinh": floatFunc(math.Asinh),
+	"acosh": floatFunc(math.Acosh),
+	"atanh": floatFunc(math.Atanh),
+	"deg":   floatFunc(func(x float64) float64 { return x * 180 / math.Pi }),
+	"rad":   floatFunc(func(x float64) float64 { return x * math.Pi / 180 }),
+}
+
+// WithPromQL restricts expressions to the scalar arithmetic of PromQL: the
+// operators + - * / % ^, comparisons which must use the bool modifier as in
+// "x > bool 0", and the functions of PromQL applicable to scalars such as
+// clamp_min, clamp_max and round(v, to_nearest) in place of all others. ^ is
+// right associative and EvalFloat64 divides by zero like PromQL, yielding
+// +Inf, -Inf or NaN.
+func WithPromQL() Option {
+	return func(o *options) {
+		o.promql = true
+		o.zeroDiv = ZeroDivisionIEEE
+	}
+}
+
+// promql returns a copy of g with the functions of PromQL
+func (g *registry) promql() *registry {
+	return &registry{functions: promqlFunctions, spelling: make(map[string]string), exprs: g.exprs}
+}
+
+// promqlInfix checks that infix only uses the operators of PromQL and drops
+// the bool modifiers of comparisons
+func promqlInfix(infix []*token) ([]*token, error) {
+	tokens := make([]*token, 0, len(infix))
+	for i := 0; i < len(infix); i++ {
+		t := infix[i]
+		tokens = append(tokens, t)
+		if t.tp != tokenTypeOperator {
+			continue
+		}
+		switch t.v {
+		case "+", "-", "*", "/", "%", "^", "@":
+		case "==", "!=", "<", "<=", ">", ">=":
+			if i+1 == len(infix) || infix[i+1].tp != tokenTypeIdentifier || infix[i+1].v != "bool" {
+				return nil, &SyntaxError{Pos: t.pos, End: t.pos + len(t.v),
+					Msg: "comparisons between scalars must use bool"}
+			}
+			i++
+		default:
+			return nil, &SyntaxError{Pos: t.pos, End: t.pos + len(t.v),
+				Msg: "operator " + t.v + " is not supported by PromQL"}
+		}
+	}
+	return tokens, nil
+}
+
+// promqlRound rounds x to the nearest multiple of the optional second
+// argument, ties rounding up
+func promqlRound(o *options, args []*big.Rat) (*big.Rat, error) {
+	x := args[0]
+	if len(args) == 1 {
+		return roundHalfUp(x, big.NewRat(1, 1)), nil
+	}
+	if args[1].Sign() == 0 {
+		return nil, ErrInvalidArgument
+	}
+	return roundHalfUp(x, args[1]), nil
+}
+
+// roundHalfUp returns floor(x / n + 1/2) * n
+func roundHalfUp(x, n *big.Rat) *big.Rat {
+	q := new(big.Rat).Quo(x, n)
+	q.Add(q, big.NewRat(1, 2))
+	q.SetInt(roundInt(q, big.ToNegativeInf))
+	return q.Mul(q, n)
+}
+
+// clamp limits x to [lo, hi], a nil bound not limiting it
+func clamp(x, lo, hi *big.Rat) *big.Rat {
+	if lo != nil && x.Cmp(lo) < 0 {
+		x = lo
+	}
+	if hi != nil && x.Cmp(hi) > 0 {
+		x = hi
+	}
+	return new(big.Rat).Set(x)
+}
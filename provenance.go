@@ -0,0 +1,41 @@
+package rpn
+
+import "math/big"
+
+// Provenance lists the inputs an evaluation read, in the order they were
+// first read. Operands skipped by && and ||, conditions of piecewise and
+// case after the one which holds and values not chosen by them or by the
+// ifXX functions are not read, every input read contributes to the result.
+type Provenance struct {
+	Variables   []string // variables as named in the expression
+	Expressions []string // named expressions expanded
+	Constants   []string // literals as written, including those of named expressions
+}
+
+// EvalProvenance evaluates the expression like Eval and records which inputs
+// contributed to the result. Results are not cached by WithEvalCache.
+func (r *RPN) EvalProvenance(vars map[string]*big.Rat) (*big.Rat, *Provenance, error) {
+	return r.prog.EvalProvenance(vars)
+}
+
+// EvalProvenance evaluates the program like Eval and records which inputs
+// contributed to the result
+func (p *Program) EvalProvenance(vars map[string]*big.Rat) (*big.Rat, *Provenance, error) {
+	prov := &Provenance{}
+	e := &evaluator{opts: &p.opts, memo: p.memo, reg: p.reg, vars: vars, prov: prov}
+	rv, err := e.run(p)
+	if err != nil {
+		return nil, nil, err
+	}
+	return rv, prov, nil
+}
+
+// appendNew appends s to list unless it holds it already
+func appendNew(list []string, s string) []string {
+	for _, v := range list {
+		if v == s {
+			return list
+		}
+	}
+	return append(list, s)
+}
@@ -0,0 +1,46 @@
+package rpn
+
+import (
+	"math/big"
+	"testing"
+)
+
+func TestEvalProvenance(t *testing.T) {
+	if err := RegisterExpr("provDiscount", "rate * 2"); err != nil {
+		t.Fatal(err)
+	}
+	vars := map[string]*big.Rat{"a": big.NewRat(1, 1), "b": big.NewRat(0, 1), "rate": big.NewRat(1, 10)}
+	cases := []struct {
+		in     string
+		result *big.Rat
+		prov   Provenance
+	}{
+		{"a * 3 + a", big.NewRat(4, 1), Provenance{[]string{"a"}, nil, []string{"3"}}},
+		{"b && a / 0", big.NewRat(0, 1), Provenance{[]string{"b"}, nil, nil}},
+		{"a || rate", big.NewRat(1, 1), Provenance{[]string{"a"}, nil, nil}},
+		{"a && rate", big.NewRat(1, 1), Provenance{[]string{"a", "rate"}, nil, nil}},
+		{"pw(b, 1, a > 0, 2, 3)", big.NewRat(2, 1), Provenance{[]string{"b", "a"}, nil, []string{"0", "2"}}},
+		{"100 * (1 - provDiscount)", big.NewRat(80, 1), Provenance{[]string{"rate"}, []string{"provDiscount"}, []string{"100", "1", "2"}}},
+		{"sum(i, 1, 3, i * a)", big.NewRat(6, 1), Provenance{[]string{"a"}, nil, []string{"1", "3"}}},
+		{"ifgt(a, 0, rate, b)", big.NewRat(1, 10), Provenance{[]string{"a", "rate"}, nil, []string{"0"}}},
+		{"ifle(a, 0, rate, b * 2)", big.NewRat(0, 1), Provenance{[]string{"a", "b"}, nil, []string{"0", "2"}}},
+		{"case(a, rate, b, 2)", big.NewRat(1, 10), Provenance{[]string{"a", "rate"}, nil, nil}},
+		{"case(b, rate, a)", big.NewRat(1, 1), Provenance{[]string{"b", "a"}, nil, nil}},
+	}
+	for _, tc := range cases {
+		r, err := New(tc.in)
+		if err != nil {
+			t.Errorf("can not convert [%v], err %v", tc.in, err)
+			continue
+		}
+		result, prov, err := r.EvalProvenance(vars)
+		if err != nil || result.Cmp(tc.result) != 0 {
+			t.Errorf("[%v] result should be %v but %v, err %v", tc.in, tc.result, result, err)
+			continue
+		}
+		if !equal(prov.Variables, tc.prov.Variables) || !equal(prov.Expressions, tc.prov.Expressions) ||
+			!equal(prov.Constants, tc.prov.Constants) {
+			t.Errorf("[%v] provenance should be %+v but %+v", tc.in, tc.prov, *prov)
+		}
+	}
+}
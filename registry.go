@@ -0,0 +1,133 @@
+package rpn
+
+import (
+	"math/big"
+	"strings"
+	"sync"
+	"sync/atomic"
+)
+
+// Func is a function callable from expressions, it must not modify args
+type Func func(args []*big.Rat) (*big.Rat, error)
+
+// registry holds the functions and named expressions visible to
+// expressions. A published registry is never modified, registration
+// publishes a modified copy, so expressions keep the one current when they
+// were built no matter what is registered later.
+type registry struct {
+	functions map[string]function // by lower case name
+	spelling  map[string]string   // registered names of functions by lower case name
+	exprs     map[string]*RPN
+}
+
+var (
+	registryMu sync.Mutex   // serialises registration
+	current    atomic.Value // *registry
+)
+
+func init() {
+	current.Store(&registry{functions: functions, spelling: make(map[string]string),
+		exprs: make(map[string]*RPN)})
+}
+
+// snapshot returns the registry current at the time of the call
+func snapshot() *registry {
+	return current.Load().(*registry)
+}
+
+// update publishes a copy of the current registry modified by fn, unless
+// fn fails
+func update(fn func(g *registry) error) error {
+	registryMu.Lock()
+	defer registryMu.Unlock()
+	old := snapshot()
+	g := &registry{
+		functions: make(map[string]function, len(old.functions)+1),
+		spelling:  make(map[string]string, len(old.spelling)+1),
+		exprs:     make(map[string]*RPN, len(old.exprs)+1),
+	}
+	for name, f := range old.functions {
+		g.functions[name] = f
+	}
+	for name, s := range old.spelling {
+		g.spelling[name] = s
+	}
+	for name, r := range old.exprs {
+		g.exprs[name] = r
+	}
+	if err := fn(g); err != nil {
+		return err
+	}
+	current.Store(g)
+	return nil
+}
+
+// RegisterFunction makes fn callable from expressions built afterwards
+// under the case-insensitive name, with minArgs to maxArgs arguments, a
+// negative maxArgs allowing any number. Registering an existing function
+// replaces it. It is safe to call concurrently with parsing and evaluation.
+func RegisterFunction(name string, minArgs, maxArgs int, fn Func) error {
+	if minArgs < 0 || maxArgs >= 0 && maxArgs < minArgs || fn == nil {
+		return ErrInvalidArgument
+	}
+	return update(func(g *registry) error {
+		if !isName(name) {
+			return ErrInvalidName
+		}
+		if tp := g.typeOfToken(name); tp != tokenTypeFunction && tp != tokenTypeIdentifier {
+			return ErrInvalidName
+		}
+		if _, ok := g.exprs[name]; ok {
+			return ErrInvalidName
+		}
+		g.spelling[strings.ToLower(name)] = name
+		g.functions[strings.ToLower(name)] = function{minArgs, maxArgs, func(o *options, args []*big.Rat) (*big.Rat, error) {
+			return fn(args)
+		}, nil}
+		return nil
+	})
+}
+
+func (g *registry) typeOfToken(tok string) uint8 {
+	return g.classify(tok, CaseInsensitiveFunctions)
+}
+
+// classify returns the type of tok, function names matching as selected by c
+func (g *registry) classify(tok string, c CaseSensitivity) uint8 {
+	if isNumeral(tok) {
+		return tokenTypeOperand
+	} else if _, ok := operators[tok]; ok {
+		return tokenTypeOperator
+	} else if g.isFunction(tok, c) {
+		return tokenTypeFunction
+	} else if tok == "(" || tok == ")" {
+		return tokenTypeParenthesis
+	} else if tok == "," {
+		return tokenTypeSeparator
+	} else if isName(tok) {
+		return tokenTypeIdentifier
+	} else {
+		return tokenTypeUnknown
+	}
+}
+
+// isFunction reports whether tok names a function, a case sensitive match
+// requires the registered spelling, lower case for builtin functions
+func (g *registry) isFunction(tok string, c CaseSensitivity) bool {
+	name := strings.ToLower(tok)
+	if _, ok := g.functions[name]; !ok {
+		return false
+	}
+	if c != CaseSensitive {
+		return true
+	}
+	if s, ok := g.spelling[name]; ok {
+		return tok == s
+	}
+	return tok == name
+}
+
+func (g *registry) validArity(name string, n int) bool {
+	fn, ok := g.functions[strings.ToLower(name)]
+	return ok && n >= fn.minArgs && (fn.maxArgs < 0 || n <= fn.maxArgs)
+}
@@ -0,0 +1,171 @@
+package rpn
+
+import (
+	"errors"
+	"math/big"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func constFunc(v int64) Func {
+	return func(args []*big.Rat) (*big.Rat, error) {
+		return big.NewRat(v, 1), nil
+	}
+}
+
+var snapshotRuns int
+
+func TestRegisterFunctionSnapshot(t *testing.T) {
+	// use a fresh name on every run as registrations can not be undone
+	snapshotRuns++
+	name := "regSnap" + strconv.Itoa(snapshotRuns)
+	before, err := New(name + " + 1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := RegisterFunction(name, 0, 0, constFunc(1)); err != nil {
+		t.Fatal(err)
+	}
+	first, err := New(strings.ToUpper(name) + "() + 1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := RegisterFunction(strings.ToLower(name), 0, 0, constFunc(2)); err != nil {
+		t.Fatal(err)
+	}
+	second, err := New(name + "() + 1")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := before.Result(); !errors.Is(err, ErrUndefined) {
+		t.Errorf("expression built before registration error should be %v but %v", ErrUndefined, err)
+	}
+	for _, tc := range []struct {
+		r      *RPN
+		result int64
+	}{{first, 2}, {second, 3}} {
+		result, err := tc.r.Result()
+		if err != nil {
+			t.Error(err)
+			continue
+		}
+		if result.Cmp(big.NewRat(tc.result, 1)) != 0 {
+			t.Errorf("%v result should be %v but %v", tc.r.Postfix(), tc.result, result)
+		}
+	}
+}
+
+func TestRegisterFunctionInvalid(t *testing.T) {
+	if err := RegisterExpr("regExpr", "1"); err != nil {
+		t.Fatal(err)
+	}
+	cases := []struct {
+		name     string
+		min, max int
+		fn       Func
+		err      error
+	}{
+		{"1x", 1, 1, constFunc(0), ErrInvalidName},
+		{"div", 1, 1, constFunc(0), ErrInvalidName},
+		{"regExpr", 1, 1, constFunc(0), ErrInvalidName},
+		{"ok", 2, 1, constFunc(0), ErrInvalidArgument},
+		{"ok", -1, 1, constFunc(0), ErrInvalidArgument},
+		{"ok", 1, 1, nil, ErrInvalidArgument},
+	}
+	for _, tc := range cases {
+		if err := RegisterFunction(tc.name, tc.min, tc.max, tc.fn); !errors.Is(err, tc.err) {
+			t.Errorf("register %v error should be %v but %v", tc.name, tc.err, err)
+		}
+	}
+}
+
+func TestRegistryConcurrency(t *testing.T) {
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(2)
+		go func(i int) {
+			defer wg.Done()
+			name := "regConc" + strconv.Itoa(i)
+			if err := RegisterFunction(name, 1, 1, constFunc(int64(i))); err != nil {
+				t.Error(err)
+			}
+			if err := RegisterExpr(name+"x", strconv.Itoa(i)); err != nil {
+				t.Error(err)
+			}
+		}(i)
+		go func() {
+			defer wg.Done()
+			r, err := New("sqrt(16) + abs(-1)")
+			if err != nil {
+				t.Error(err)
+				return
+			}
+			if result, err := r.Result(); err != nil || result.Cmp(big.NewRat(5, 1)) != 0 {
+				t.Errorf("result should be 5 but %v, %v", result, err)
+			}
+		}()
+	}
+	wg.Wait()
+	for i := 0; i < 8; i++ {
+		name := "regConc" + strconv.Itoa(i)
+		r, err := New(name + "(0) + " + name + "x")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if result, err := r.Result(); err != nil || result.Cmp(big.NewRat(int64(2*i), 1)) != 0 {
+			t.Errorf("%v result should be %v but %v, %v", r.Postfix(), 2*i, result, err)
+		}
+	}
+}
+
+func TestCaseSensitivity(t *testing.T) {
+	if err := RegisterFunction("CaseFn", 1, 1, constFunc(7)); err != nil {
+		t.Fatal(err)
+	}
+	if err := RegisterExpr("caseVat", "0.5"); err != nil {
+		t.Fatal(err)
+	}
+
+	vars := map[string]*big.Rat{"x": big.NewRat(2, 1), "X": big.NewRat(3, 1)}
+	cases := []struct {
+		in     string
+		mode   CaseSensitivity
+		result *big.Rat
+		err    error
+	}{
+		{"ABS(-1) + x", CaseInsensitiveFunctions, big.NewRat(3, 1), nil},
+		{"casefn(1) + CASEFN(1)", CaseInsensitiveFunctions, big.NewRat(14, 1), nil},
+		{"(x + 1) * (X + 1)", CaseInsensitiveFunctions, big.NewRat(12, 1), nil},
+		{"y", CaseInsensitiveFunctions, nil, ErrUndefined},
+		{"CASEVAT", CaseInsensitiveFunctions, nil, ErrUndefined},
+		{"abs(-1) + CaseFn(1)", CaseSensitive, big.NewRat(8, 1), nil},
+		{"ABS(-1)", CaseSensitive, nil, ErrUnrecognizedExpression},
+		{"casefn(1)", CaseSensitive, nil, ErrUnrecognizedExpression},
+		{"(x + 1) * (X + 1)", CaseSensitive, big.NewRat(12, 1), nil},
+		{"Abs(-1) + CASEFN(1)", CaseInsensitive, big.NewRat(8, 1), nil},
+		{"Y + 1", CaseInsensitive, big.NewRat(5, 1), nil},
+		{"CASEVAT * 2", CaseInsensitive, big.NewRat(1, 1), nil},
+		{"with(S, 2, s * 3)", CaseInsensitive, big.NewRat(6, 1), nil},
+		{"with(S, 2, s * 3)", CaseSensitive, nil, ErrUndefined},
+	}
+	for _, tc := range cases {
+		vars := vars
+		if tc.mode == CaseInsensitive {
+			vars = map[string]*big.Rat{"y": big.NewRat(4, 1)}
+		}
+		r, err := New(tc.in, WithCaseSensitivity(tc.mode))
+		if err != nil {
+			if !errors.Is(err, tc.err) {
+				t.Errorf("[%v] with %v err should be %v but %v", tc.in, tc.mode, tc.err, err)
+			}
+			continue
+		}
+		result, err := r.Eval(vars)
+		if !errors.Is(err, tc.err) || tc.err == nil && result.Cmp(tc.result) != 0 {
+			t.Errorf("[%v] with %v result should be %v, %v but %v, %v", tc.in, tc.mode, tc.result, tc.err, result, err)
+		}
+	}
+}
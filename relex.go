@@ -0,0 +1,70 @@
+package rpn
+
+// Edit is a change to an expression replacing Deleted bytes from Offset on
+// by Inserted
+type Edit struct {
+	Offset   int
+	Deleted  int
+	Inserted string
+}
+
+// Apply returns expr with the edit made
+func (e Edit) Apply(expr string) string {
+	return expr[:e.Offset] + e.Inserted + expr[e.Offset+e.Deleted:]
+}
+
+// Lex splits expr into tokens recording their positions, as the Pratt
+// parser does. Unary minus is not told apart from subtraction before
+// parsing and the Args of functions are zero.
+func Lex(expr string) []Token {
+	return viewTokens(lex(expr, snapshot().typeOfToken))
+}
+
+// Relex returns the tokens of expr as Lex does, given the tokens of the
+// expression expr results from by edit. Only the text around the edit is
+// lexed again, the tokens before it are kept and those after it shifted, so
+// editors can keep the tokens of long expressions up to date as they are
+// typed.
+func Relex(tokens []Token, expr string, edit Edit) []Token {
+	inserted := edit.Offset + len(edit.Inserted) // end of the edit in expr
+	if edit.Offset < 0 || edit.Deleted < 0 || inserted > len(expr) {
+		return Lex(expr)
+	}
+	deleted := edit.Offset + edit.Deleted // end of the edit before it was made
+	delta := inserted - deleted
+
+	// the lexer looks two bytes ahead, tokens ending earlier are unchanged
+	keep, i := 0, 0
+	for keep < len(tokens) && tokens[keep].Pos+len(tokens[keep].Value)+2 <= edit.Offset {
+		i = tokens[keep].Pos + len(tokens[keep].Value)
+		keep++
+	}
+	next := keep // first token starting after the edit
+	for next < len(tokens) && tokens[next].Pos < deleted {
+		next++
+	}
+
+	s := make([]Token, keep, len(tokens)+len(edit.Inserted))
+	copy(s, tokens)
+	typeOf := snapshot().typeOfToken
+	for {
+		tok, end := nextToken(expr, i, typeOf)
+		if tok == nil {
+			return s
+		}
+		for next < len(tokens) && tokens[next].Pos+delta < tok.pos {
+			next++
+		}
+		// lexing from a token on does not depend on the text before it, the
+		// tokens are unchanged from one which already started there
+		if tok.pos >= inserted && next < len(tokens) && tokens[next].Pos+delta == tok.pos {
+			for _, t := range tokens[next:] {
+				t.Pos += delta
+				s = append(s, t)
+			}
+			return s
+		}
+		s = append(s, viewToken(tok))
+		i = end
+	}
+}
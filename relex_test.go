@@ -0,0 +1,85 @@
+package rpn
+
+import (
+	"math/rand"
+	"testing"
+)
+
+func equalTokens(a, b []Token) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func TestRelex(t *testing.T) {
+	cases := []struct {
+		in   string
+		edit Edit
+	}{
+		{"12 + 3", Edit{2, 0, "5"}},
+		{"12 + 3", Edit{0, 2, ""}},
+		{"1. + 3", Edit{2, 0, "5"}},
+		{"2 * 3", Edit{3, 0, "*"}},
+		{"sin(x) + cos(y)", Edit{0, 3, "foo"}},
+		{"sin(x) + cos(y)", Edit{7, 1, "-"}},
+		{"a + b", Edit{5, 0, "c"}},
+		{"a + b", Edit{0, 0, "-"}},
+		{"a < b", Edit{3, 0, "="}},
+		{"x+y", Edit{1, 1, " && "}},
+		{"", Edit{0, 0, "1 + 2"}},
+		{"1 + 2", Edit{0, 5, ""}},
+		{"1 + 2", Edit{4, 1, "(3 * 4)"}},
+	}
+	for _, tc := range cases {
+		expr := tc.edit.Apply(tc.in)
+		got := Relex(Lex(tc.in), expr, tc.edit)
+		if want := Lex(expr); !equalTokens(got, want) {
+			t.Errorf("[%q] edited by %+v tokens should be %v but %v", tc.in, tc.edit, want, got)
+		}
+	}
+}
+
+func TestRelexRandom(t *testing.T) {
+	pieces := []string{"1", "2.", "5", ".", " ", "x", "y1", "sin", "(", ")", ",", "+", "-", "*", "/", "<", "=", "&", "|", "÷"}
+	rnd := rand.New(rand.NewSource(1))
+	expr := ""
+	tokens := Lex(expr)
+	for i := 0; i < 2000; i++ {
+		var edit Edit
+		edit.Offset = rnd.Intn(len(expr) + 1)
+		if edit.Offset < len(expr) {
+			edit.Deleted = rnd.Intn(len(expr) - edit.Offset + 1)
+			if edit.Deleted > 3 {
+				edit.Deleted = 3
+			}
+		}
+		for n := rnd.Intn(3); n > 0; n-- {
+			edit.Inserted += pieces[rnd.Intn(len(pieces))]
+		}
+		next := edit.Apply(expr)
+		tokens = Relex(tokens, next, edit)
+		if want := Lex(next); !equalTokens(tokens, want) {
+			t.Fatalf("[%q] edited by %+v into [%q] tokens should be %v but %v", expr, edit, next, want, tokens)
+		}
+		expr = next
+		if len(expr) > 200 {
+			expr, tokens = "", nil
+		}
+	}
+}
+
+func BenchmarkRelex(b *testing.B) {
+	expr := largeExpr(1 << 20)
+	tokens := Lex(expr)
+	edit := Edit{Offset: len(expr) / 2, Inserted: "1"}
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		Relex(tokens, edit.Apply(expr), edit)
+	}
+}
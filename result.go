@@ -0,0 +1,132 @@
+package rpn
+
+import "math/big"
+
+// ResultFloat64 returns the result as the nearest float64 and whether it
+// represents the result exactly
+func (r *RPN) ResultFloat64() (float64, bool, error) {
+	rv, err := r.Result()
+	if err != nil {
+		return 0, false, err
+	}
+	f, exact := rv.Float64()
+	return f, exact, nil
+}
+
+// ResultBigInt returns the result as an integer, failing with ErrNotInteger
+// if it has a fractional part
+func (r *RPN) ResultBigInt() (*big.Int, error) {
+	rv, err := r.Result()
+	if err != nil {
+		return nil, err
+	}
+	if !rv.IsInt() {
+		return nil, ErrNotInteger
+	}
+	return new(big.Int).Set(rv.Num()), nil
+}
+
+// ResultInt64 returns the result as an int64, failing with ErrNotInteger if
+// it has a fractional part and ErrOverflow if it does not fit
+func (r *RPN) ResultInt64() (int64, error) {
+	i, err := r.ResultBigInt()
+	if err != nil {
+		return 0, err
+	}
+	if !i.IsInt64() {
+		return 0, ErrOverflow
+	}
+	return i.Int64(), nil
+}
+
+// IsInteger reports whether the result is an integer
+func (r *RPN) IsInteger() (bool, error) {
+	rv, err := r.Result()
+	if err != nil {
+		return false, err
+	}
+	return rv.IsInt(), nil
+}
+
+// ResultInBase renders an integer result in the given base between 2 and 36
+// with lowercase letters for digits above 9, failing with ErrNotInteger if
+// it has a fractional part
+func (r *RPN) ResultInBase(base int) (string, error) {
+	if base < 2 || base > 36 {
+		return "", ErrInvalidArgument
+	}
+	i, err := r.ResultBigInt()
+	if err != nil {
+		return "", err
+	}
+	return i.Text(base), nil
+}
+
+// ApproxFraction returns the fraction nearest to the result whose
+// denominator is at most maxDenominator, such as 22/7 for 3.14159 and a
+// maximum of 10, for displaying results readably
+func (r *RPN) ApproxFraction(maxDenominator int64) (*big.Rat, error) {
+	if maxDenominator < 1 {
+		return nil, ErrInvalidArgument
+	}
+	rv, err := r.Result()
+	if err != nil {
+		return nil, err
+	}
+	return approxFraction(rv, big.NewInt(maxDenominator)), nil
+}
+
+// ContinuedFraction returns up to n terms of the continued fraction
+// [a0; a1, a2, ...] of the result, fewer if the expansion ends before
+func (r *RPN) ContinuedFraction(n int) ([]*big.Int, error) {
+	if n < 1 {
+		return nil, ErrInvalidArgument
+	}
+	rv, err := r.Result()
+	if err != nil {
+		return nil, err
+	}
+	num, den := new(big.Int).Set(rv.Num()), new(big.Int).Set(rv.Denom())
+	var terms []*big.Int
+	for len(terms) < n && den.Sign() != 0 {
+		// the remainder of Euclidean division by a positive denominator is
+		// nonnegative, so a is the floor of num / den
+		a, m := new(big.Int).DivMod(num, den, new(big.Int))
+		terms = append(terms, a)
+		num, den = den, m
+	}
+	return terms, nil
+}
+
+// approxFraction returns the best approximation of x with a denominator of
+// at most max from its convergents and semiconvergents
+func approxFraction(x *big.Rat, max *big.Int) *big.Rat {
+	if x.Denom().Cmp(max) <= 0 {
+		return new(big.Rat).Set(x)
+	}
+	p0, q0, p1, q1 := big.NewInt(0), big.NewInt(1), big.NewInt(1), big.NewInt(0)
+	num, den := new(big.Int).Set(x.Num()), new(big.Int).Set(x.Denom())
+	for {
+		a, m := new(big.Int).DivMod(num, den, new(big.Int))
+		q2 := new(big.Int).Mul(a, q1)
+		q2.Add(q2, q0)
+		if q2.Cmp(max) > 0 {
+			break
+		}
+		p2 := new(big.Int).Mul(a, p1)
+		p2.Add(p2, p0)
+		p0, q0, p1, q1 = p1, q1, p2, q2
+		num, den = den, m
+	}
+	k := new(big.Int).Sub(max, q0)
+	k.Quo(k, q1)
+	p := new(big.Int).Mul(k, p1)
+	q := new(big.Int).Mul(k, q1)
+	semi := new(big.Rat).SetFrac(p.Add(p, p0), q.Add(q, q0))
+	conv := new(big.Rat).SetFrac(p1, q1)
+	dc, ds := new(big.Rat).Sub(conv, x), new(big.Rat).Sub(semi, x)
+	if dc.Abs(dc).Cmp(ds.Abs(ds)) <= 0 {
+		return conv
+	}
+	return semi
+}
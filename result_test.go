@@ -0,0 +1,143 @@
+package rpn
+
+import (
+	"errors"
+	"math/big"
+	"testing"
+)
+
+func TestResultAccessors(t *testing.T) {
+	cases := []struct {
+		in      string
+		float   float64
+		exact   bool
+		integer bool
+		bigInt  *big.Int
+		int64   int64
+		err     error
+	}{
+		{"6 / 3", 2, true, true, big.NewInt(2), 2, nil},
+		{"-1 / 4", -0.25, true, false, nil, 0, ErrNotInteger},
+		{"1 / 3", 1.0 / 3, false, false, nil, 0, ErrNotInteger},
+		{"9223372036854775807 + 1", 9223372036854775808, true, true,
+			new(big.Int).Lsh(big.NewInt(1), 63), 0, ErrOverflow},
+	}
+	for _, tc := range cases {
+		r, err := New(tc.in)
+		if err != nil {
+			t.Errorf("can not convert infix notation [%v], err %v", tc.in, err)
+			continue
+		}
+		if f, exact, err := r.ResultFloat64(); err != nil || f != tc.float || exact != tc.exact {
+			t.Errorf("[%v] float should be %v, %v but %v, %v, %v", tc.in, tc.float, tc.exact, f, exact, err)
+		}
+		if integer, err := r.IsInteger(); err != nil || integer != tc.integer {
+			t.Errorf("[%v] integer should be %v but %v, %v", tc.in, tc.integer, integer, err)
+		}
+		i, err := r.ResultBigInt()
+		if tc.bigInt == nil && !errors.Is(err, ErrNotInteger) || tc.bigInt != nil && (err != nil || i.Cmp(tc.bigInt) != 0) {
+			t.Errorf("[%v] big integer should be %v but %v, %v", tc.in, tc.bigInt, i, err)
+		}
+		if i, err := r.ResultInt64(); !errors.Is(err, tc.err) || i != tc.int64 {
+			t.Errorf("[%v] int64 should be %v, %v but %v, %v", tc.in, tc.int64, tc.err, i, err)
+		}
+	}
+
+	r, _ := New("1 / 0")
+	if _, _, err := r.ResultFloat64(); !errors.Is(err, ErrZeroDivision) {
+		t.Errorf("error should be %v but %v", ErrZeroDivision, err)
+	}
+}
+
+func TestResultInBase(t *testing.T) {
+	cases := []struct {
+		in   string
+		base int
+		s    string
+		err  error
+	}{
+		{"255", 16, "ff", nil},
+		{"-255", 16, "-ff", nil},
+		{"5", 2, "101", nil},
+		{"64 / 8", 8, "10", nil},
+		{"35", 36, "z", nil},
+		{"0", 2, "0", nil},
+		{"2 ^ 70", 16, "400000000000000000", nil},
+		{"1 / 2", 16, "", ErrNotInteger},
+		{"10", 1, "", ErrInvalidArgument},
+		{"10", 37, "", ErrInvalidArgument},
+		{"1 / 0", 10, "", ErrZeroDivision},
+	}
+	for _, tc := range cases {
+		r, err := New(tc.in)
+		if err != nil {
+			t.Errorf("can not convert infix notation [%v], err %v", tc.in, err)
+			continue
+		}
+		if s, err := r.ResultInBase(tc.base); !errors.Is(err, tc.err) || s != tc.s {
+			t.Errorf("[%v] in base %v should be %q, %v but %q, %v", tc.in, tc.base, tc.s, tc.err, s, err)
+		}
+	}
+}
+
+func TestApproxFraction(t *testing.T) {
+	cases := []struct {
+		in   string
+		max  int64
+		want *big.Rat
+	}{
+		{"3.14159265358979", 10, big.NewRat(22, 7)},
+		{"3.14159265358979", 100, big.NewRat(311, 99)},
+		{"3.14159265358979", 1000, big.NewRat(355, 113)},
+		{"1 / 3", 3, big.NewRat(1, 3)},
+		{"0.333", 10, big.NewRat(1, 3)},
+		{"-0.333", 10, big.NewRat(-1, 3)},
+		{"0.1", 1, big.NewRat(0, 1)},
+		{"7", 1, big.NewRat(7, 1)},
+	}
+	for _, tc := range cases {
+		r, err := New(tc.in)
+		if err != nil {
+			t.Errorf("can not convert infix notation [%v], err %v", tc.in, err)
+			continue
+		}
+		if got, err := r.ApproxFraction(tc.max); err != nil || got.Cmp(tc.want) != 0 {
+			t.Errorf("[%v] approximation with denominators up to %v should be %v but %v, %v", tc.in, tc.max, tc.want, got, err)
+		}
+	}
+	r, _ := New("1 / 3")
+	if _, err := r.ApproxFraction(0); !errors.Is(err, ErrInvalidArgument) {
+		t.Errorf("error should be %v but %v", ErrInvalidArgument, err)
+	}
+}
+
+func TestContinuedFraction(t *testing.T) {
+	cases := []struct {
+		in   string
+		n    int
+		want []int64
+	}{
+		{"415 / 93", 10, []int64{4, 2, 6, 7}},
+		{"415 / 93", 2, []int64{4, 2}},
+		{"-7 / 3", 10, []int64{-3, 1, 2}},
+		{"5", 3, []int64{5}},
+	}
+	for _, tc := range cases {
+		r, err := New(tc.in)
+		if err != nil {
+			t.Errorf("can not convert infix notation [%v], err %v", tc.in, err)
+			continue
+		}
+		got, err := r.ContinuedFraction(tc.n)
+		if err != nil || len(got) != len(tc.want) {
+			t.Errorf("[%v] continued fraction should be %v but %v, %v", tc.in, tc.want, got, err)
+			continue
+		}
+		for i, a := range tc.want {
+			if got[i].Int64() != a {
+				t.Errorf("[%v] continued fraction should be %v but %v", tc.in, tc.want, got)
+				break
+			}
+		}
+	}
+}
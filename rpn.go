@@ -2,12 +2,11 @@ package rpn
 
 import (
 	"errors"
-	"fmt"
 	"math"
 	"math/big"
-	"regexp"
 	"strings"
 	"text/scanner"
+	"unicode/utf8"
 )
 
 const (
@@ -23,33 +22,109 @@ const (
 	tokenTypeOperator
 	tokenTypeParenthesis
 	tokenTypeFunction
+	tokenTypeSeparator
+	tokenTypeIdentifier
 )
 
-var (
-	floatReg      = regexp.MustCompile(`(\d+(?:\.\d+)?)`)
-	funcReg       = regexp.MustCompile(`(?i)(abs|sin|cos|tan|ln|arcsin|arccos|arctan|sqrt)`)
-	blankReg      = regexp.MustCompile(`\s+`)
-	unaryMinusReg = regexp.MustCompile(`((?:^|[-+^%*/!~=(×÷])\s*)-`)
-)
+// punctReplacer surrounds parentheses and commas with spaces
+var punctReplacer = strings.NewReplacer("(", " ( ", ")", " ) ", ",", " , ")
 
 var (
 	ErrUnrecognizedExpression = errors.New("unrecognized expression")
 	ErrZeroDivision           = errors.New("zero division")
+	ErrInvalidArgument        = errors.New("invalid argument")
+	ErrInvalidName            = errors.New("invalid name")
+	ErrUndefined              = errors.New("undefined name")
+	ErrCyclicExpression       = errors.New("cyclic expression")
+	ErrNotInteger             = errors.New("not an integer")
+	ErrOverflow               = errors.New("overflow")
+	ErrBudgetExceeded         = errors.New("operation budget exceeded")
+	ErrTypeMismatch           = errors.New("type mismatch")
+	ErrNotAllowed             = errors.New("not allowed")
 )
 
+// SyntaxError describes why the expression could not be parsed and where,
+// Pos and End are the byte offsets of the offending text. In expressions
+// spanning several lines Line and Col locate Pos as well, counting from 1
+// and Col in characters, they are 0 otherwise.
+type SyntaxError struct {
+	Pos  int
+	End  int
+	Msg  string
+	Line int
+	Col  int
+}
+
+func (e *SyntaxError) Error() string {
+	return e.Msg + " " + position(e.Pos, e.Line, e.Col)
+}
+
+// Unwrap makes every SyntaxError match ErrUnrecognizedExpression
+func (e *SyntaxError) Unwrap() error {
+	return ErrUnrecognizedExpression
+}
+
+// FormatError renders err for display. A SyntaxError is shown with the line
+// of src containing it and the offending text underlined by carets, other
+// errors are rendered by their Error method.
+func FormatError(err error, src string) string {
+	var serr *SyntaxError
+	if !errors.As(err, &serr) || serr.Pos < 0 || serr.Pos > len(src) {
+		return err.Error()
+	}
+	start := strings.LastIndexByte(src[:serr.Pos], '\n') + 1
+	end := strings.IndexByte(src[serr.Pos:], '\n')
+	if end < 0 {
+		end = len(src)
+	} else {
+		end += serr.Pos
+	}
+	line := src[start:end]
+
+	var b strings.Builder
+	b.WriteString(err.Error())
+	b.WriteByte('\n')
+	b.WriteString(line)
+	b.WriteByte('\n')
+	// keep tabs so the carets line up with the offending text
+	for _, c := range src[start:serr.Pos] {
+		if c == '\t' {
+			b.WriteByte('\t')
+		} else {
+			b.WriteByte(' ')
+		}
+	}
+	width := 1
+	if serr.End > serr.Pos && serr.End <= end {
+		width = utf8.RuneCountInString(src[serr.Pos:serr.End])
+	}
+	b.WriteString(strings.Repeat("^", width))
+	return b.String()
+}
+
 var (
 	// operator precedence and operator associative
 	operators = map[string][2]int8{
-		"**": {opOff - 1, associativeLeft},
-		"^":  {opOff - 1, associativeLeft},
-		"@":  {opOff - 2, associativeRight}, // unary minus
-		"*":  {opOff - 3, associativeLeft},
-		"×":  {opOff - 3, associativeLeft},
-		"/":  {opOff - 3, associativeLeft},
-		"÷":  {opOff - 3, associativeLeft},
-		"%":  {opOff - 3, associativeLeft},
-		"+":  {opOff - 4, associativeLeft},
-		"-":  {opOff - 4, associativeLeft},
+		"**":  {opOff - 1, associativeLeft},
+		"^":   {opOff - 1, associativeLeft},
+		"@":   {opOff - 2, associativeRight}, // unary minus
+		"*":   {opOff - 3, associativeLeft},
+		"×":   {opOff - 3, associativeLeft},
+		"/":   {opOff - 3, associativeLeft},
+		"÷":   {opOff - 3, associativeLeft},
+		"%":   {opOff - 3, associativeLeft},
+		"//":  {opOff - 3, associativeLeft}, // floor division
+		"div": {opOff - 3, associativeLeft},
+		"+":   {opOff - 4, associativeLeft},
+		"-":   {opOff - 4, associativeLeft},
+		"<":   {opOff - 5, associativeLeft},
+		"<=":  {opOff - 5, associativeLeft},
+		">":   {opOff - 5, associativeLeft},
+		">=":  {opOff - 5, associativeLeft},
+		"==":  {opOff - 6, associativeLeft},
+		"!=":  {opOff - 6, associativeLeft},
+		"&&":  {opOff - 7, associativeLeft}, // the right operand is only evaluated if needed
+		"||":  {opOff - 8, associativeLeft},
 	}
 )
 
@@ -58,104 +133,269 @@ type RPN struct {
 	infix   []*token
 	postfix []*token
 	result  *big.Rat
+	opts    options
+	memo    *memo
+	cache   *lru // results by variable bindings, nil unless enabled
+	reg     *registry
+	prog    *Program
+	src     string // the expression parsed, empty if it was built
+}
+
+// configure returns an RPN with the options applied, yet to be parsed
+func configure(opts []Option) *RPN {
+	r := &RPN{opts: defaultOptions(), reg: snapshot()}
+	for _, opt := range opts {
+		opt(&r.opts)
+	}
+	if r.opts.promql {
+		r.reg = r.reg.promql()
+	}
+	return r
 }
 
 // New new reverse Polish notation with a infix notation string pattern
-func New(expr string) (*RPN, error) {
-	infix := tokenise(expr)
-	postfix, err := shuntingYard(infix)
+func New(expr string, opts ...Option) (*RPN, error) {
+	r := configure(opts)
+	var err error
+	if r.infix, err = r.tokens(expr); err != nil {
+		return nil, locate(err, expr)
+	}
+	if r.postfix, err = r.parse(r.infix, len(expr)); err != nil {
+		return nil, locate(err, expr)
+	}
+	if err = r.prepare(); err != nil {
+		return nil, err
+	}
+	r.src = expr
+	return r, nil
+}
+
+// prepare sets up the caches of the parsed expression and compiles it
+func (r *RPN) prepare() error {
+	if r.opts.memoize {
+		r.memo = newMemo()
+	}
+	if r.opts.cacheSize > 0 {
+		r.cache = newLRU(r.opts.cacheSize)
+	}
+	var err error
+	r.prog, err = compile(r, 1)
+	return err
+}
+
+// tokens splits expr into tokens for the parser selected by the options
+func (r *RPN) tokens(expr string) ([]*token, error) {
+	expr = continuations(expr)
+	if r.opts.brackets {
+		if err := CheckBalanced(expr); err != nil {
+			return nil, err
+		}
+		expr = bracketReplacer.Replace(expr)
+	}
+	typeOf := func(tok string) uint8 {
+		return r.reg.classify(tok, r.opts.caseMode)
+	}
+	expr, offsets := absBars(expr)
+	var tokens []*token
+	if r.opts.pratt {
+		tokens = lex(expr, typeOf)
+	} else {
+		tokens = tokenise(expr, typeOf)
+	}
+	if offsets != nil {
+		for _, t := range tokens {
+			t.pos = offsets[t.pos]
+		}
+	}
+	if r.opts.synonyms != nil {
+		canonicalOperators(tokens, r.opts.synonyms)
+	}
+	if r.opts.cells != nil {
+		tokens = expandRanges(tokens)
+	}
+	if r.opts.implicit {
+		tokens = implicitMultiplication(tokens)
+	}
+	if r.opts.onToken != nil {
+		for _, t := range tokens {
+			r.opts.onToken(r.opts.viewToken(t))
+		}
+	}
+	return tokens, nil
+}
+
+// implicitMultiplication inserts a multiplication between a token ending an
+// operand and a token starting one
+func implicitMultiplication(input []*token) []*token {
+	tokens := make([]*token, 0, len(input))
+	for i, t := range input {
+		if i > 0 && endsOperand(input[i-1]) && startsOperand(t) {
+			tokens = append(tokens, &token{tp: tokenTypeOperator, v: "*", pos: t.pos})
+		}
+		tokens = append(tokens, t)
+	}
+	return tokens
+}
+
+func endsOperand(t *token) bool {
+	return t.tp == tokenTypeOperand || t.tp == tokenTypeIdentifier || t.v == ")"
+}
+
+func startsOperand(t *token) bool {
+	return t.tp == tokenTypeOperand || t.tp == tokenTypeIdentifier || t.tp == tokenTypeFunction || t.v == "("
+}
+
+// parse converts infix ending at byte offset end to postfix with the parser
+// selected by the options
+func (r *RPN) parse(infix []*token, end int) ([]*token, error) {
+	var postfix []*token
+	var err error
+	if r.opts.promql {
+		if infix, err = promqlInfix(infix); err != nil {
+			return nil, err
+		}
+	}
+	if r.opts.pratt {
+		postfix, err = parsePratt(infix, end, r.reg, r.opts.rightPow())
+	} else {
+		postfix, err = shuntingYard(infix, r.reg, r.opts.rightPow())
+	}
 	if err != nil {
 		return nil, err
 	}
-	r := &RPN{
-		infix:   infix,
-		postfix: postfix,
+	if r.opts.consts != nil {
+		postfix = r.inlineConsts(postfix)
 	}
-	return r, nil
+	if err = r.opts.policy.check(postfix); err != nil {
+		return nil, err
+	}
+	return postfix, nil
 }
 
 // Result return the evaluate result from postfix notation
 func (r *RPN) Result() (*big.Rat, error) {
 	if r.result != nil {
-		return r.result, nil
+		return new(big.Rat).Set(r.result), nil
 	}
-	rv, err := calculate(r.postfix)
+	rv, err := r.prog.Eval(nil)
 	if err != nil {
 		return nil, err
 	}
 	r.result = rv
+	return new(big.Rat).Set(rv), nil
+}
+
+// Eval evaluates the expression with identifiers bound to the values in
+// vars, identifiers missing from vars refer to named expressions. Results are
+// only reused across calls when enabled by WithEvalCache, keyed by vars.
+// Like Result, it returns a value the caller is free to modify.
+func (r *RPN) Eval(vars map[string]*big.Rat) (*big.Rat, error) {
+	var key string
+	if r.cache != nil {
+		key = bindingKey(vars)
+		if rv, ok := r.cache.get(key); ok {
+			return new(big.Rat).Set(rv), nil
+		}
+	}
+	rv, err := r.prog.Eval(vars)
+	if err != nil {
+		return nil, err
+	}
+	if r.cache != nil {
+		r.cache.put(key, rv)
+		return new(big.Rat).Set(rv), nil
+	}
 	return rv, nil
 }
 
-// Postfix postfix format output
+// Postfix postfix format output, see PostfixTokens for the kinds of tokens
+// and WithUnaryMinusName for the name of unary minus
 func (r *RPN) Postfix() []string {
 	s := make([]string, 0, len(r.postfix))
 	for _, tok := range r.postfix {
-		s = append(s, tok.v)
+		s = append(s, r.opts.tokenName(tok))
 	}
 	return s
 }
 
 type token struct {
-	tp uint8
-	v  string
+	tp   uint8
+	v    string
+	argc int // number of arguments passed to a function
+	pos  int // byte offset in the expression
 }
 
-func tokenise(expr string) []*token {
-	expr = unaryMinusReg.ReplaceAllString(expr, "$1 @")
-	expr = floatReg.ReplaceAllString(expr, " ${1} ")
-	expr = funcReg.ReplaceAllString(expr, " ${1} ")
-	expr = strings.Replace(expr, "(", " ( ", -1)
-	expr = strings.Replace(expr, ")", " ) ", -1)
-	expr = blankReg.ReplaceAllString(strings.TrimSpace(expr), "|")
-	rs := strings.Split(expr, "|")
+func tokenise(expr string, typeOf func(string) uint8) []*token {
+	src := expr
+	expr = punctReplacer.Replace(spaceWords(markUnaryMinus(expr)))
+	rs := strings.FieldsFunc(strings.TrimSpace(expr), isBlank)
 
 	tokens := make([]*token, 0, len(rs))
+	at := 0 // bytes of src consumed
 	for _, tok := range rs {
+		pos := offset(src, at, tok)
+		at = pos + len(tok)
+		if tok == "@" {
+			at = pos + 1
+		}
 		tokens = append(tokens, &token{
-			tp: typeOfToken(tok),
-			v:  tok,
+			tp:  typeOf(tok),
+			v:   tok,
+			pos: pos,
 		})
 	}
 	return tokens
 }
 
-func typeOfToken(tok string) uint8 {
-	if floatReg.MatchString(tok) {
-		return tokenTypeOperand
-	} else if funcReg.MatchString(tok) {
-		return tokenTypeFunction
-	} else if tok == "(" || tok == ")" {
-		return tokenTypeParenthesis
-	} else if _, ok := operators[tok]; ok {
-		return tokenTypeOperator
-	} else {
-		return tokenTypeUnknown
+// offset returns the byte offset of the token tok in src at or after at.
+// Splitting src into tokens only adds blanks, writes unary minus as "@" and
+// drops unary plus, so tok follows blanks and unary plus signs.
+func offset(src string, at int, tok string) int {
+	for i := at; i < len(src); i++ {
+		switch {
+		case strings.HasPrefix(src[i:], tok), tok == "@" && src[i] == '-':
+			return i
+		case !isBlank(rune(src[i])) && src[i] != '+':
+			return at
+		}
 	}
+	return at
 }
 
-func shuntingYard(input []*token) ([]*token, error) {
+func shuntingYard(input []*token, reg *registry, rightPow bool) ([]*token, error) {
 	output := make([]*token, 0, len(input))
 	ops := make([]*token, 0, len(input)) // stack for operator
 	parens := [2]int{0, 0}
+	argc := make([]int, 0) // stack for argument count of each open parenthesis
 	for i := 0; i < len(input); i++ {
 		t := input[i]
 		switch t.tp {
 		case tokenTypeUnknown:
 			return nil, ErrUnrecognizedExpression
-		case tokenTypeOperand:
+		case tokenTypeOperand, tokenTypeIdentifier:
 			output = append(output, t)
 		case tokenTypeFunction:
+			t.argc = 1
 			ops = append(ops, t)
+		case tokenTypeSeparator:
+			if len(argc) == 0 {
+				return nil, ErrUnrecognizedExpression
+			}
+			for len(ops) > 0 && ops[len(ops)-1].v != "(" {
+				output = append(output, ops[len(ops)-1])
+				ops = ops[:len(ops)-1]
+			}
+			argc[len(argc)-1]++
 		case tokenTypeOperator:
 			if _, ok := operators[t.v]; !ok {
 				return nil, ErrUnrecognizedExpression
 			}
 			op1 := t
-			for len(ops) > 0 {
-				as1 := operators[op1.v][1]
+			// a prefix operator has no left operand to complete
+			for len(ops) > 0 && op1.v != "@" {
+				as1 := associativity(op1.v, rightPow)
 				op2 := ops[len(ops)-1]
-				if (priorityLE(op1.v, op2.v) && as1 == associativeLeft) || (!priorityGT(op1.v, op2.v) && as1 == associativeRight) {
+				if (priorityLE(op1.v, op2.v) && as1 == associativeLeft) || (priorityGT(op2.v, op1.v) && as1 == associativeRight) {
 					output = append(output, op2)
 					ops = ops[:len(ops)-1]
 					continue
@@ -168,6 +408,11 @@ func shuntingYard(input []*token) ([]*token, error) {
 			case "(":
 				ops = append(ops, t)
 				parens[0]++
+				if i+1 < len(input) && input[i+1].v == ")" {
+					argc = append(argc, 0)
+				} else {
+					argc = append(argc, 1)
+				}
 			case ")":
 				parens[1]++
 				mismatch := true
@@ -185,6 +430,19 @@ func shuntingYard(input []*token) ([]*token, error) {
 				if mismatch {
 					return nil, ErrUnrecognizedExpression
 				}
+				n := argc[len(argc)-1]
+				argc = argc[:len(argc)-1]
+				if len(ops) > 0 && ops[len(ops)-1].tp == tokenTypeFunction {
+					fn := ops[len(ops)-1]
+					if !reg.validArity(fn.v, n) {
+						return nil, ErrUnrecognizedExpression
+					}
+					fn.argc = n
+					output = append(output, fn)
+					ops = ops[:len(ops)-1]
+				} else if n != 1 {
+					return nil, ErrUnrecognizedExpression
+				}
 			}
 		}
 	}
@@ -215,97 +473,25 @@ func priorityGT(op1, op2 string) bool {
 	return operators[op1][0] > operators[op2][0]
 }
 
-func calculate(postfix []*token) (*big.Rat, error) {
-	var stack []*big.Rat
-	for _, tok := range postfix {
-		switch tok.tp {
-		case tokenTypeUnknown, tokenTypeParenthesis:
-			return nil, ErrUnrecognizedExpression
-		case tokenTypeOperand:
-			tmp := new(big.Rat)
-			if _, err := fmt.Sscan(tok.v, tmp); err != nil {
-				return nil, err
-			}
-			stack = append(stack, tmp)
-		case tokenTypeOperator:
-			tmp := new(big.Rat)
-			if len(stack) == 0 {
-				return nil, ErrUnrecognizedExpression
-			}
-			op2 := stack[len(stack)-1]
-			stack = stack[:len(stack)-1]
-			if tok.v == "@" {
-				stack = append(stack, tmp.Mul(big.NewRat(-1, 1), op2))
-				continue
-			}
-			if len(stack) == 0 {
-				return nil, ErrUnrecognizedExpression
-			}
-			op1 := stack[len(stack)-1]
-			stack = stack[:len(stack)-1]
-			switch tok.v {
-			case "+":
-				stack = append(stack, tmp.Add(op1, op2))
-			case "-":
-				stack = append(stack, tmp.Sub(op1, op2))
-			case "*", "×":
-				stack = append(stack, tmp.Mul(op1, op2))
-			case "/", "÷":
-				if f, _ := op2.Float64(); f == 0 {
-					return nil, ErrZeroDivision
-				}
-				stack = append(stack, tmp.Quo(op1, op2))
-			case "%":
-				f1, _ := op1.Float64()
-				f2, _ := op2.Float64()
-				stack = append(stack, tmp.SetFloat64(math.Mod(f1, f2)))
-			case "**", "^":
-				f1, _ := op1.Float64()
-				f2, _ := op2.Float64()
-				stack = append(stack, tmp.SetFloat64(math.Pow(f1, f2)))
-
-			default:
-				return nil, ErrUnrecognizedExpression
-			}
-		case tokenTypeFunction:
-			if len(stack) == 0 {
-				return nil, ErrUnrecognizedExpression
-			}
-			tmp := new(big.Rat)
-			op := stack[len(stack)-1]
-			stack = stack[:len(stack)-1]
-			fn := strings.ToLower(tok.v)
-			f, _ := op.Float64()
-			switch fn {
-			case "abs":
-				stack = append(stack, tmp.SetFloat64(math.Abs(f)))
-			case "sin":
-				stack = append(stack, tmp.SetFloat64(math.Sin(f)))
-			case "cos":
-				stack = append(stack, tmp.SetFloat64(math.Cos(f)))
-			case "tan":
-				stack = append(stack, tmp.SetFloat64(math.Tan(f)))
-			case "ln":
-				stack = append(stack, tmp.SetFloat64(math.Log(f)))
-			case "arcsin":
-				stack = append(stack, tmp.SetFloat64(math.Asin(f)))
-			case "arccos":
-				stack = append(stack, tmp.SetFloat64(math.Acos(f)))
-			case "arctan":
-				stack = append(stack, tmp.SetFloat64(math.Atan(f)))
-			case "sqrt":
-				stack = append(stack, tmp.SetFloat64(math.Sqrt(f)))
-			default:
-				return nil, ErrUnrecognizedExpression
-			}
-		}
-	}
+// truncMod sets z to the remainder of x / y truncated towards zero, which
+// has the sign of x like math.Mod, and returns z
+func truncMod(z, x, y *big.Rat) *big.Rat {
+	n := new(big.Int).Mul(x.Num(), y.Denom())
+	d := new(big.Int).Mul(x.Denom(), y.Num())
+	q := new(big.Rat).SetInt(n.Quo(n, d))
+	return z.Sub(x, q.Mul(q, y))
+}
 
-	if len(stack) == 0 {
-		return nil, ErrUnrecognizedExpression
+// floorDiv sets z to the largest integer not greater than x / y and returns z
+func floorDiv(z, x, y *big.Rat) *big.Rat {
+	n := new(big.Int).Mul(x.Num(), y.Denom())
+	d := new(big.Int).Mul(x.Denom(), y.Num())
+	if d.Sign() < 0 {
+		n.Neg(n)
+		d.Neg(d)
 	}
-	rv := stack[len(stack)-1]
-	return rv, nil
+	// Euclidean division by a positive divisor floors the quotient
+	return z.SetInt(n.Div(n, d))
 }
 
 func scan(expr string) []*token {
@@ -1,7 +1,11 @@
 package rpn
 
 import (
+	"errors"
+	"math"
 	"math/big"
+	"regexp"
+	"strings"
 	"testing"
 )
 
@@ -78,6 +82,108 @@ var testCase = []struct {
 		true,
 		true,
 	},
+	{"7 // 2 + 1",
+		[]string{"7", "2", "//", "1", "+"},
+		big.NewRat(4, 1),
+		true,
+		true,
+	},
+	{"-7.5 div 2",
+		[]string{"7.5", "@", "2", "div"},
+		big.NewRat(-4, 1),
+		true,
+		true,
+	},
+	{"7 div -2",
+		[]string{"7", "2", "@", "div"},
+		big.NewRat(-4, 1),
+		true,
+		true,
+	},
+	{"1 // 0",
+		[]string{"1", "0", "//"},
+		nil,
+		false, // zero division
+		true,
+	},
+	{"round(-2.5)",
+		[]string{"2.5", "@", "round"},
+		big.NewRat(-3, 1),
+		true,
+		true,
+	},
+	{"round(1.2345, 2) * 100",
+		[]string{"1.2345", "2", "round", "100", "*"},
+		big.NewRat(123, 1),
+		true,
+		true,
+	},
+	{"floor(-1.25, 1)",
+		[]string{"1.25", "@", "1", "floor"},
+		big.NewRat(-13, 10),
+		true,
+		true,
+	},
+	{"ceil(1234, -2)",
+		[]string{"1234", "2", "@", "ceil"},
+		big.NewRat(1300, 1),
+		true,
+		true,
+	},
+	{"round(1, 0.5)",
+		[]string{"1", "0.5", "round"},
+		nil,
+		false, // invalid argument
+		true,
+	},
+	{"round(1, 2, 3)",
+		[]string{},
+		nil,
+		false,
+		false,
+	},
+	{"ifgt(2, 1, 10, 20) + iflt(2, 1, 10, 20)",
+		[]string{"2", "1", "10", "20", "ifgt", "2", "1", "10", "20", "iflt", "+"},
+		big.NewRat(30, 1),
+		true,
+		true,
+	},
+	{"case(1 - 1, 5, between(2, 1, 3), 6, 7)",
+		[]string{"1", "1", "-", "5", "2", "1", "3", "between", "6", "7", "case"},
+		big.NewRat(6, 1),
+		true,
+		true,
+	},
+	{"case(0, 5, between(4, 1, 3), 6, -7)",
+		[]string{"0", "5", "4", "1", "3", "between", "6", "7", "@", "case"},
+		big.NewRat(-7, 1),
+		true,
+		true,
+	},
+	{"case(0, 5)",
+		[]string{"0", "5", "case"},
+		nil,
+		false, // no branch matched
+		true,
+	},
+	{"(1, 2)",
+		[]string{},
+		nil,
+		false,
+		false,
+	},
+	{"1 < 2 && 3>=3",
+		[]string{"1", "2", "<", "3", "3", ">=", "&&"},
+		big.NewRat(1, 1),
+		true,
+		true,
+	},
+	{"2>-1 == 1+1<=1 || 1 != 1",
+		[]string{"2", "1", "@", ">", "1", "1", "+", "1", "<=", "==", "1", "1", "!=", "||"},
+		big.NewRat(0, 1),
+		true,
+		true,
+	},
 }
 
 func TestRPN(t *testing.T) {
@@ -108,6 +214,37 @@ func TestRPN(t *testing.T) {
 	}
 }
 
+func TestRoundingMode(t *testing.T) {
+	cases := []struct {
+		mode   big.RoundingMode
+		in     string
+		result *big.Rat
+	}{
+		{big.ToNearestEven, "round(2.5)", big.NewRat(2, 1)},
+		{big.ToNearestEven, "round(3.5)", big.NewRat(4, 1)},
+		{big.ToNearestEven, "round(-0.125, 2)", big.NewRat(-12, 100)},
+		{big.ToNearestAway, "round(-0.125, 2)", big.NewRat(-13, 100)},
+		{big.ToZero, "round(-1.7)", big.NewRat(-1, 1)},
+		{big.AwayFromZero, "round(1.01, 1)", big.NewRat(11, 10)},
+		{big.ToZero, "floor(-1.7)", big.NewRat(-2, 1)},
+	}
+	for _, tc := range cases {
+		r, err := New(tc.in, WithRoundingMode(tc.mode))
+		if err != nil {
+			t.Errorf("can not convert infix notation [%v], err %v", tc.in, err)
+			continue
+		}
+		result, err := r.Result()
+		if err != nil {
+			t.Error(err)
+			continue
+		}
+		if result.Cmp(tc.result) != 0 {
+			t.Errorf("[%v] with mode %v result should be %v but %v", tc.in, tc.mode, tc.result, result)
+		}
+	}
+}
+
 func BenchmarkRPN(b *testing.B) {
 	for i := 0; i < b.N; i++ {
 		for _, tc := range testCase {
@@ -140,3 +277,340 @@ func equal(a, b []string) bool {
 	}
 	return true
 }
+
+func TestEval(t *testing.T) {
+	if err := RegisterExpr("evalSubtotal", "price * qty"); err != nil {
+		t.Fatal(err)
+	}
+	cases := []struct {
+		in     string
+		vars   map[string]*big.Rat
+		result *big.Rat
+		err    error
+	}{
+		{"evalSubtotal * (1 + rate)",
+			map[string]*big.Rat{"price": big.NewRat(5, 1), "qty": big.NewRat(3, 1), "rate": big.NewRat(1, 5)},
+			big.NewRat(18, 1), nil},
+		{"evalSubtotal * (1 + rate)",
+			map[string]*big.Rat{"price": big.NewRat(5, 1), "qty": big.NewRat(4, 1), "rate": big.NewRat(1, 5)},
+			big.NewRat(24, 1), nil},
+		{"evalSubtotal * (1 + rate)",
+			map[string]*big.Rat{"evalSubtotal": big.NewRat(10, 1), "rate": big.NewRat(0, 1)},
+			big.NewRat(10, 1), nil},
+		{"evalSubtotal * (1 + rate)",
+			map[string]*big.Rat{"price": big.NewRat(5, 1), "qty": big.NewRat(4, 1)},
+			nil, ErrUndefined},
+	}
+	for _, opts := range [][]Option{nil, {WithEvalCache(1)}, {WithEvalCache(8)}} {
+		for _, tc := range cases {
+			r, err := New(tc.in, opts...)
+			if err != nil {
+				t.Fatal(err)
+			}
+			// evaluate twice so cached results are compared as well
+			for i := 0; i < 2; i++ {
+				result, err := r.Eval(tc.vars)
+				if !errors.Is(err, tc.err) {
+					t.Errorf("[%v] with %v error should be %v but %v", tc.in, tc.vars, tc.err, err)
+					continue
+				}
+				if err == nil && result.Cmp(tc.result) != 0 {
+					t.Errorf("[%v] with %v result should be %v but %v", tc.in, tc.vars, tc.result, result)
+				}
+			}
+		}
+	}
+}
+
+func TestMaxOperations(t *testing.T) {
+	if err := RegisterExpr("budgetSquare", "budgetX * budgetX"); err != nil {
+		t.Fatal(err)
+	}
+	cases := []struct {
+		in  string
+		max int
+		err error
+	}{
+		{"1 + 2 * 3", 2, nil},
+		{"1 + 2 * 3", 1, ErrBudgetExceeded},
+		{"-abs(1) + 2", 3, nil},
+		{"-abs(1) + 2", 2, ErrBudgetExceeded},
+		{"budgetSquare + 1", 2, nil},
+		{"budgetSquare + budgetSquare", 2, ErrBudgetExceeded},
+		{"(1 + 1) * (1 + 1) * (1 + 1)", 0, nil},
+		// control flow is not counted
+		{"ifgt(budgetX, 1, budgetX + 1, 3)", 2, nil},
+		{"ifgt(budgetX, 1, budgetX + 1, 3)", 1, ErrBudgetExceeded},
+		{"budgetX > 1 && budgetX < 5", 2, nil},
+		{"piecewise(budgetX > 1, 2, 3)", 1, nil},
+		{"case(budgetX < 1, 2, budgetX < 5, 3)", 2, nil},
+	}
+	vars := map[string]*big.Rat{"budgetX": big.NewRat(3, 1)}
+	for _, tc := range cases {
+		r, err := New(tc.in, WithMaxOperations(tc.max))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := r.Eval(vars); !errors.Is(err, tc.err) {
+			t.Errorf("[%v] with budget %v error should be %v but %v", tc.in, tc.max, tc.err, err)
+		}
+		p, err := r.Compile()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := p.Eval(vars); !errors.Is(err, tc.err) {
+			t.Errorf("compiled [%v] with budget %v error should be %v but %v", tc.in, tc.max, tc.err, err)
+		}
+	}
+}
+
+func TestImplicitMultiplication(t *testing.T) {
+	vars := map[string]*big.Rat{"a": big.NewRat(2, 1), "b": big.NewRat(3, 1), "ab": big.NewRat(5, 1), "x": big.NewRat(4, 1)}
+	cases := []struct {
+		in     string
+		result *big.Rat
+	}{
+		{"a b", big.NewRat(6, 1)},
+		{"ab", big.NewRat(5, 1)},
+		{"ab a", big.NewRat(10, 1)},
+		{"2 x", big.NewRat(8, 1)},
+		{"2x + 1", big.NewRat(9, 1)},
+		{"2(x + 1)", big.NewRat(10, 1)},
+		{"(a)(b)", big.NewRat(6, 1)},
+		{"x (a - b)", big.NewRat(-4, 1)},
+		{"2 abs(-b) + 1", big.NewRat(7, 1)},
+		{"a -b", big.NewRat(-1, 1)},
+		{"x^2 a", big.NewRat(32, 1)},
+		{"-a b", big.NewRat(-6, 1)},
+		{"1 / 2 x", big.NewRat(2, 1)},
+		{"round(a b / 4, 1)", big.NewRat(3, 2)},
+	}
+	for _, pratt := range []bool{false, true} {
+		opts := []Option{WithImplicitMultiplication()}
+		if pratt {
+			opts = append(opts, WithPrattParser())
+		}
+		for _, tc := range cases {
+			r, err := New(tc.in, opts...)
+			if err != nil {
+				t.Errorf("can not convert infix notation [%v], err %v", tc.in, err)
+				continue
+			}
+			if result, err := r.Eval(vars); err != nil || result.Cmp(tc.result) != 0 {
+				t.Errorf("[%v] result should be %v but %v, err %v", tc.in, tc.result, result, err)
+			}
+		}
+	}
+	if _, err := New("a b"); !errors.Is(err, ErrUnrecognizedExpression) {
+		t.Errorf("err should be %v but %v", ErrUnrecognizedExpression, err)
+	}
+}
+
+// largeExpr returns an expression of about size bytes
+func largeExpr(size int) string {
+	var b strings.Builder
+	b.Grow(size + 64)
+	b.WriteString("0")
+	for b.Len() < size {
+		b.WriteString(" + (12.5 * x - abs(-3)) / 2")
+	}
+	return b.String()
+}
+
+func BenchmarkLarge(b *testing.B) {
+	expr := largeExpr(1 << 20)
+	for _, bc := range []struct {
+		name string
+		opts []Option
+	}{
+		{"ShuntingYard", nil},
+		{"Pratt", []Option{WithPrattParser()}},
+	} {
+		b.Run(bc.name, func(b *testing.B) {
+			b.SetBytes(int64(len(expr)))
+			for i := 0; i < b.N; i++ {
+				if _, err := New(expr, bc.opts...); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
+
+// tokeniseRegexp is the regular expression based tokeniser tokenise must
+// agree with
+func tokeniseRegexp(expr string) []string {
+	// signs after signs are unary as well, replace until none is left
+	unary := regexp.MustCompile(`((?:^|[-+^%*/!~=<>&|(,×÷@]|\bdiv)\s*)([-+])`)
+	for prev := ""; prev != expr; {
+		prev = expr
+		expr = unary.ReplaceAllStringFunc(expr, func(s string) string {
+			if s[len(s)-1] == '-' {
+				return s[:len(s)-1] + " @"
+			}
+			return s[:len(s)-1] + " "
+		})
+	}
+	expr = regexp.MustCompile(`([A-Za-z_][A-Za-z0-9_]*|\d+(?:\.\d+)?)`).ReplaceAllString(expr, " ${1} ")
+	expr = strings.NewReplacer("(", " ( ", ")", " ) ", ",", " , ").Replace(expr)
+	return regexp.MustCompile(`\s+`).Split(strings.TrimSpace(expr), -1)
+}
+
+func TestTokenise(t *testing.T) {
+	inputs := []string{
+		"-1.5.2x_1+abc2(3,-4)", "2*-(-1)", "1 div -2", "a\t-\nb", "1.  2", "÷-3×-4", "x2y__z 007",
+		"  ", "é-1", "--1", "sin(-x)//-2", " -1", "- - -1", "adiv -1", "1div-2", "3 - -  -4", "x&&-1||-y",
+		"+1", "2*+x", "+-1", "-+1", "1 + + 2", "(+x)", "2 div +3",
+		largeExpr(1 << 10),
+	}
+	for _, tc := range testCase {
+		inputs = append(inputs, tc.in)
+	}
+	for _, in := range inputs {
+		var got []string
+		for _, tok := range tokenise(in, snapshot().typeOfToken) {
+			got = append(got, tok.v)
+		}
+		want := tokeniseRegexp(in)
+		if len(want) == 1 && want[0] == "" {
+			want = nil
+		}
+		if !equal(got, want) {
+			t.Errorf("[%q] tokens should be %q but %q", in, want, got)
+		}
+	}
+}
+
+func TestSemanticsVersion(t *testing.T) {
+	cases := []struct {
+		in      string
+		version SemanticsVersion
+		postfix []string
+		result  *big.Rat
+		float   float64
+		err     error // float mode error
+	}{
+		{"2 ^ 3 ^ 2", Semantics1, []string{"2", "3", "^", "2", "^"}, big.NewRat(64, 1), 64, nil},
+		{"2 ^ 3 ^ 2", Semantics2, []string{"2", "3", "2", "^", "^"}, big.NewRat(512, 1), 512, nil},
+		{"2 ** 3 ^ 2", Semantics2, []string{"2", "3", "2", "^", "**"}, big.NewRat(512, 1), 512, nil},
+		{"-2 ^ 2", Semantics2, []string{"2", "2", "^", "@"}, big.NewRat(-4, 1), -4, nil},
+		{"0.3 % 0.1", Semantics1, []string{"0.3", "0.1", "%"}, big.NewRat(900719925474099, 9007199254740992), 0.09999999999999998, nil},
+		{"0.3 % 0.1", Semantics2, []string{"0.3", "0.1", "%"}, new(big.Rat), 0.09999999999999998, nil},
+		{"-7.5 % 2", Semantics2, []string{"7.5", "@", "2", "%"}, big.NewRat(-3, 2), -1.5, nil},
+		{"7 % -2", Semantics2, []string{"7", "2", "@", "%"}, big.NewRat(1, 1), 1, nil},
+		{"(0 - 8) ^ 0.5", Semantics1, []string{"0", "8", "-", "0.5", "^"}, nil, math.NaN(), nil},
+		{"(0 - 8) ^ 0.5", Semantics2, []string{"0", "8", "-", "0.5", "^"}, nil, 0, ErrInvalidArgument},
+	}
+	for _, tc := range cases {
+		for _, opts := range [][]Option{nil, {WithPrattParser()}} {
+			r, err := New(tc.in, append(opts, WithSemanticsVersion(tc.version))...)
+			if err != nil {
+				t.Errorf("can not convert [%v], err %v", tc.in, err)
+				continue
+			}
+			if !equal(tc.postfix, r.Postfix()) {
+				t.Errorf("[%v] in version %v postfix should be %v but %v", tc.in, tc.version, tc.postfix, r.Postfix())
+			}
+			result, err := r.Result()
+			if tc.result == nil {
+				if !errors.Is(err, ErrInvalidArgument) {
+					t.Errorf("[%v] in version %v err should be %v but %v", tc.in, tc.version, ErrInvalidArgument, err)
+				}
+			} else if err != nil || result.Cmp(tc.result) != 0 {
+				t.Errorf("[%v] in version %v result should be %v but %v, err %v", tc.in, tc.version, tc.result, result, err)
+			}
+			f, err := r.EvalFloat64(nil)
+			if !errors.Is(err, tc.err) || err == nil && math.IsNaN(f) != math.IsNaN(tc.float) ||
+				err == nil && !math.IsNaN(f) && f != tc.float {
+				t.Errorf("[%v] in version %v float result should be %v, %v but %v, %v", tc.in, tc.version, tc.float, tc.err, f, err)
+			}
+		}
+	}
+}
+
+func TestPromQL(t *testing.T) {
+	inf := math.Inf(1)
+	cases := []struct {
+		in     string
+		result *big.Rat // exact result, nil for an error
+		float  float64
+		err    bool // parsing fails
+	}{
+		{"clamp_min(3 - 5, 0) + clamp_max(7, 5)", big.NewRat(5, 1), 5, false},
+		{"clamp(12, 0, 10) * 2 ^ 3 ^ 2", big.NewRat(5120, 1), 5120, false},
+		{"round(7.25, 0.5) + round(-2.5)", big.NewRat(11, 2), 5.5, false},
+		{"2 > bool 1 == bool 1", big.NewRat(1, 1), 1, false},
+		{"sgn(-3) * log10(100)", big.NewRat(-2, 1), -2, false},
+		{"1 / 0", nil, inf, false},
+		{"2 > 1", nil, 0, true},
+		{"1 && 1", nil, 0, true},
+		{"7 // 2", nil, 0, true},
+		{"with(x, 1, x)", nil, 0, true},
+		{"ifgt(1, 0, 1, 0)", nil, 0, true},
+	}
+	for _, tc := range cases {
+		for _, opts := range [][]Option{{WithPromQL()}, {WithPromQL(), WithPrattParser()}} {
+			r, err := New(tc.in, opts...)
+			if tc.err {
+				if !errors.Is(err, ErrUnrecognizedExpression) {
+					t.Errorf("[%v] err should be %v but %v", tc.in, ErrUnrecognizedExpression, err)
+				}
+				continue
+			}
+			if err != nil {
+				t.Errorf("can not convert [%v], err %v", tc.in, err)
+				continue
+			}
+			result, err := r.Result()
+			if tc.result != nil && (err != nil || result.Cmp(tc.result) != 0) {
+				t.Errorf("[%v] result should be %v but %v, err %v", tc.in, tc.result, result, err)
+			}
+			if f, err := r.EvalFloat64(nil); err != nil || f != tc.float {
+				t.Errorf("[%v] float result should be %v but %v, err %v", tc.in, tc.float, f, err)
+			}
+		}
+	}
+	for _, opts := range [][]Option{{WithPromQL()}, {WithPromQL(), WithPrattParser()}} {
+		var serr *SyntaxError
+		if _, err := New("x + 1 >= 2", opts...); !errors.As(err, &serr) || serr.Pos != 6 || serr.End != 8 {
+			t.Errorf("err should be at offsets 6 to 8 but %v", err)
+		}
+	}
+}
+
+func TestSigns(t *testing.T) {
+	cases := []struct {
+		in     string
+		result *big.Rat
+		err    error
+	}{
+		{"+5", big.NewRat(5, 1), nil},
+		{"- -5", big.NewRat(5, 1), nil},
+		{"--5", big.NewRat(5, 1), nil},
+		{"+-5", big.NewRat(-5, 1), nil},
+		{"-+5", big.NewRat(-5, 1), nil},
+		{"2 - - - 5", big.NewRat(-3, 1), nil},
+		{"2 * +5", big.NewRat(10, 1), nil},
+		{"2 ^ -1", big.NewRat(1, 2), nil},
+		{"-2 ^ 2", big.NewRat(-4, 1), nil},
+		{"(+5) div -2", big.NewRat(-3, 1), nil},
+		{"+", nil, ErrUnrecognizedExpression},
+		{"2 -", nil, ErrUnrecognizedExpression},
+		{"- * 2", nil, ErrUnrecognizedExpression},
+		{"2 + * 3", nil, ErrUnrecognizedExpression},
+	}
+	for _, tc := range cases {
+		for _, opts := range [][]Option{nil, {WithPrattParser()}} {
+			r, err := New(tc.in, opts...)
+			if err == nil {
+				var rv *big.Rat
+				if rv, err = r.Result(); err == nil && rv.Cmp(tc.result) != 0 {
+					t.Errorf("[%v] result should be %v but %v", tc.in, tc.result, rv)
+				}
+			}
+			if !errors.Is(err, tc.err) {
+				t.Errorf("[%v] err should be %v but %v", tc.in, tc.err, err)
+			}
+		}
+	}
+}
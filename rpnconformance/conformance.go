@@ -0,0 +1,127 @@
+// Package rpnconformance is the suite of formulas a numeric backend, such as
+// one evaluating in decimal or complex arithmetic, must evaluate like the
+// rpn package to be compatible with it: operator semantics, precedence and
+// the errors reported. Backends run it from a test:
+//
+//	func TestConformance(t *testing.T) {
+//		rpnconformance.Run(t, myBackend)
+//	}
+package rpnconformance
+
+import (
+	"errors"
+	"math/big"
+	"testing"
+
+	"github.com/Pasithea/rpn"
+)
+
+// Backend evaluates expr with the variables bound to the decimal numbers in
+// vars and returns the result as a decimal number or fraction, such as
+// "-1.5" or "1/3", or the error it fails with
+type Backend func(expr string, vars map[string]string) (string, error)
+
+// Tolerance is the relative difference allowed between a result and the
+// expected one, so that backends rounding their results pass
+const Tolerance = 1e-9
+
+// Case is a formula and the result or error expected from it
+type Case struct {
+	Name string
+	Expr string
+	Vars map[string]string
+	Want string // the expected result, empty if the formula fails
+	Err  error  // the error expected, nil for any error
+}
+
+// Cases is the suite run by Run
+var Cases = []Case{
+	// precedence and associativity
+	{Name: "mul before add", Expr: "2 + 3 * 4", Want: "14"},
+	{Name: "parentheses", Expr: "(2 + 3) * 4", Want: "20"},
+	{Name: "sub left", Expr: "10 - 4 - 3", Want: "3"},
+	{Name: "div left", Expr: "8 / 4 / 2", Want: "1"},
+	{Name: "pow left", Expr: "2 ^ 3 ^ 2", Want: "64"},
+	{Name: "pow before sign", Expr: "-2 ^ 2", Want: "-4"},
+	{Name: "signed power", Expr: "2 ^ -1", Want: "0.5"},
+	{Name: "sign after operator", Expr: "2 * -3", Want: "-6"},
+	{Name: "repeated signs", Expr: "- -5 + +-1", Want: "4"},
+	{Name: "arithmetic before comparison", Expr: "1 + 2 < 4", Want: "1"},
+	{Name: "comparison before equality", Expr: "1 < 2 == 1", Want: "1"},
+	{Name: "and before or", Expr: "1 || 0 && 0", Want: "1"},
+
+	// operators
+	{Name: "decimals", Expr: "0.1 + 0.2", Want: "0.3"},
+	{Name: "division", Expr: "1 / 4", Want: "0.25"},
+	{Name: "floor division", Expr: "-7 // 2", Want: "-4"},
+	{Name: "remainder", Expr: "7 % 3", Want: "1"},
+	{Name: "negative remainder", Expr: "-7 % 3", Want: "-1"},
+	{Name: "zero power", Expr: "0 ^ 0", Want: "1"},
+	{Name: "fractional power", Expr: "2 ^ 0.5 * 2 ^ 0.5", Want: "2"},
+	{Name: "comparisons", Expr: "(1 <= 1) + (2 > 3) + (2 >= 3) + (1 != 2)", Want: "2"},
+	{Name: "and", Expr: "2 && -1", Want: "1"},
+	{Name: "or", Expr: "0 || 0", Want: "0"},
+	{Name: "short circuit and", Expr: "0 && 1 / 0", Want: "0"},
+	{Name: "short circuit or", Expr: "1 || 1 / 0", Want: "1"},
+
+	// variables and functions
+	{Name: "variables", Expr: "price * qty", Vars: map[string]string{"price": "2.5", "qty": "4"}, Want: "10"},
+	{Name: "abs", Expr: "abs(-3)", Want: "3"},
+	{Name: "round half away", Expr: "round(2.5) + round(-2.5)", Want: "0"},
+	{Name: "round digits", Expr: "round(1.25, 1)", Want: "1.3"},
+	{Name: "floor and ceil", Expr: "floor(-1.5) + ceil(1.2)", Want: "0"},
+	{Name: "piecewise", Expr: "pw(x > 0, 1, x < 0, -1, 0)", Vars: map[string]string{"x": "-2"}, Want: "-1"},
+
+	// errors
+	{Name: "zero division", Expr: "1 / 0", Err: rpn.ErrZeroDivision},
+	{Name: "zero floor division", Expr: "5 // 0", Err: rpn.ErrZeroDivision},
+	{Name: "zero remainder", Expr: "5 % 0", Err: rpn.ErrZeroDivision},
+	{Name: "zero negative power", Expr: "0 ^ -1", Err: rpn.ErrZeroDivision},
+	{Name: "undefined", Expr: "y + 1", Err: rpn.ErrUndefined},
+	{Name: "syntax", Expr: "1 +"},
+	{Name: "no branch", Expr: "pw(0, 1)", Err: rpn.ErrInvalidArgument},
+}
+
+// Run runs each case of the suite as a subtest evaluating it with b
+func Run(t *testing.T, b Backend) {
+	t.Helper()
+	for _, c := range Cases {
+		c := c
+		t.Run(c.Name, func(t *testing.T) {
+			got, err := b(c.Expr, c.Vars)
+			if c.Want == "" {
+				if err == nil {
+					t.Errorf("[%v] should fail but is %v", c.Expr, got)
+				} else if c.Err != nil && !errors.Is(err, c.Err) {
+					t.Errorf("[%v] error should be %v but %v", c.Expr, c.Err, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Errorf("[%v] should be %v but fails with %v", c.Expr, c.Want, err)
+				return
+			}
+			if !Equal(got, c.Want) {
+				t.Errorf("[%v] should be %v but %v", c.Expr, c.Want, got)
+			}
+		})
+	}
+}
+
+// Equal reports whether the numbers got and want differ by at most
+// Tolerance relative to want
+func Equal(got, want string) bool {
+	g, ok := new(big.Rat).SetString(got)
+	if !ok {
+		return false
+	}
+	w, _ := new(big.Rat).SetString(want)
+	diff := new(big.Rat).Sub(g, w)
+	diff.Abs(diff)
+	bound := new(big.Rat).Abs(w)
+	if bound.Cmp(big.NewRat(1, 1)) < 0 {
+		bound.SetInt64(1)
+	}
+	bound.Mul(bound, new(big.Rat).SetFloat64(Tolerance))
+	return diff.Cmp(bound) <= 0
+}
@@ -0,0 +1,63 @@
+package rpnconformance
+
+import (
+	"math/big"
+	"strconv"
+	"testing"
+
+	"github.com/Pasithea/rpn"
+)
+
+func TestExact(t *testing.T) {
+	Run(t, func(expr string, vars map[string]string) (string, error) {
+		r, err := rpn.New(expr)
+		if err != nil {
+			return "", err
+		}
+		bound := make(map[string]*big.Rat, len(vars))
+		for name, v := range vars {
+			bound[name], _ = new(big.Rat).SetString(v)
+		}
+		rv, err := r.Eval(bound)
+		if err != nil {
+			return "", err
+		}
+		return rv.RatString(), nil
+	})
+}
+
+func TestFloat64(t *testing.T) {
+	Run(t, func(expr string, vars map[string]string) (string, error) {
+		r, err := rpn.New(expr)
+		if err != nil {
+			return "", err
+		}
+		bound := make(map[string]float64, len(vars))
+		for name, v := range vars {
+			bound[name], _ = strconv.ParseFloat(v, 64)
+		}
+		f, err := r.EvalFloat64(bound)
+		if err != nil {
+			return "", err
+		}
+		return strconv.FormatFloat(f, 'f', -1, 64), nil
+	})
+}
+
+func TestEqual(t *testing.T) {
+	cases := []struct {
+		got, want string
+		equal     bool
+	}{
+		{"1/3", "0.333333333333", true},
+		{"2.0000000000000004", "2", true},
+		{"2.001", "2", false},
+		{"-0", "0", true},
+		{"NaN", "0", false},
+	}
+	for _, tc := range cases {
+		if equal := Equal(tc.got, tc.want); equal != tc.equal {
+			t.Errorf("Equal(%v, %v) should be %v", tc.got, tc.want, tc.equal)
+		}
+	}
+}
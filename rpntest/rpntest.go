@@ -0,0 +1,119 @@
+// Package rpntest runs suites of formulas against golden files.
+//
+// Each name.expr file of a suite holds a formula, optionally preceded by
+// variable bindings such as "price = 12.5" and comment lines starting with
+// "#". Its name.golden file holds the expected postfix notation and result:
+//
+//	postfix: price 2 *
+//	result: 25
+//
+// or "error: " followed by the message of the error the formula fails with.
+// Running the tests with -rpntest.update rewrites the golden files from the
+// current results.
+package rpntest
+
+import (
+	"flag"
+	"io/ioutil"
+	"math/big"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/Pasithea/rpn"
+)
+
+// Update makes Run rewrite the golden files instead of comparing with them
+var Update = flag.Bool("rpntest.update", false, "rewrite the golden files of formula suites")
+
+var bindingReg = regexp.MustCompile(`^\s*([A-Za-z_][A-Za-z0-9_]*)\s*=\s*([^=].*)$`)
+
+// Run runs each .expr file of dir as a subtest parsing and evaluating its
+// formula with opts, comparing the outcome with its golden file
+func Run(t *testing.T, dir string, opts ...rpn.Option) {
+	t.Helper()
+	files, err := filepath.Glob(filepath.Join(dir, "*.expr"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(files) == 0 {
+		t.Fatalf("no .expr files in %s", dir)
+	}
+	for _, file := range files {
+		file := file
+		t.Run(strings.TrimSuffix(filepath.Base(file), ".expr"), func(t *testing.T) {
+			src, err := ioutil.ReadFile(file)
+			if err != nil {
+				t.Fatal(err)
+			}
+			got, err := Outcome(string(src), opts...)
+			if err != nil {
+				t.Fatal(err)
+			}
+			golden := strings.TrimSuffix(file, ".expr") + ".golden"
+			if *Update {
+				if err := ioutil.WriteFile(golden, []byte(got), 0644); err != nil {
+					t.Fatal(err)
+				}
+				return
+			}
+			want, err := ioutil.ReadFile(golden)
+			if os.IsNotExist(err) {
+				t.Fatalf("%s is missing, run with -rpntest.update to create it", golden)
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != string(want) {
+				t.Errorf("%s\ngot:\n%swant:\n%s", file, got, want)
+			}
+		})
+	}
+}
+
+// Outcome returns the golden file content of the .expr file src, an error
+// only if its variable bindings are malformed
+func Outcome(src string, opts ...rpn.Option) (string, error) {
+	vars := make(map[string]*big.Rat)
+	var expr []string
+	for _, line := range strings.Split(src, "\n") {
+		trimmed := strings.TrimSpace(line)
+		if trimmed == "" || strings.HasPrefix(trimmed, "#") {
+			continue
+		}
+		if m := bindingReg.FindStringSubmatch(line); m != nil && len(expr) == 0 {
+			v, err := value(m[2])
+			if err != nil {
+				return "", err
+			}
+			vars[m[1]] = v
+			continue
+		}
+		expr = append(expr, trimmed)
+	}
+
+	r, err := rpn.New(strings.Join(expr, " "), opts...)
+	if err != nil {
+		return "error: " + err.Error() + "\n", nil
+	}
+	var b strings.Builder
+	b.WriteString("postfix: " + strings.Join(r.Postfix(), " ") + "\n")
+	rv, err := r.Eval(vars)
+	if err != nil {
+		b.WriteString("error: " + err.Error() + "\n")
+	} else {
+		b.WriteString("result: " + rv.RatString() + "\n")
+	}
+	return b.String(), nil
+}
+
+// value evaluates the value of a binding
+func value(expr string) (*big.Rat, error) {
+	r, err := rpn.New(expr)
+	if err != nil {
+		return nil, err
+	}
+	return r.Result()
+}
@@ -0,0 +1,32 @@
+package rpntest
+
+import "testing"
+
+func TestRun(t *testing.T) {
+	Run(t, "testdata")
+}
+
+func TestOutcome(t *testing.T) {
+	cases := []struct {
+		src     string
+		outcome string
+	}{
+		{"a = 1/2\n# comment\n\na * 4", "postfix: a 4 *\nresult: 2\n"},
+		{"a = 2\nb", "postfix: b\nerror: undefined name\n"},
+		{"1 +\n2", "postfix: 1 2 +\nresult: 3\n"},
+		{"a == 1", "postfix: a 1 ==\nerror: undefined name\n"},
+	}
+	for _, tc := range cases {
+		got, err := Outcome(tc.src)
+		if err != nil {
+			t.Errorf("[%q] err %v", tc.src, err)
+			continue
+		}
+		if got != tc.outcome {
+			t.Errorf("[%q] outcome should be %q but %q", tc.src, tc.outcome, got)
+		}
+	}
+	if _, err := Outcome("a = (1\na"); err == nil {
+		t.Error("a malformed binding should fail")
+	}
+}
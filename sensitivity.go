@@ -0,0 +1,90 @@
+package rpn
+
+import "math/big"
+
+// sensitivityStep is the step of the finite differences taken by
+// Sensitivity, relative to the magnitude of the variable
+var sensitivityStep = big.NewRat(1, 1000000)
+
+// Sensitivity returns the partial derivative of the result with respect to
+// each variable in vars at the values in vars, so the inputs affecting the
+// result most can be told apart. Derivatives are estimated from central
+// differences evaluated exactly and refined by Richardson extrapolation,
+// which is exact for polynomials up to degree 4, and from forward differences
+// where the function is not defined on both sides. A variable the result
+// does not depend on has a derivative of 0.
+func (p *Program) Sensitivity(vars map[string]*big.Rat) (map[string]float64, error) {
+	if _, err := p.Eval(vars); err != nil {
+		return nil, err
+	}
+	s := make(map[string]float64, len(vars))
+	for name, x := range vars {
+		h := new(big.Rat).Abs(x)
+		if h.Cmp(ratOne) < 0 {
+			h.SetInt64(1)
+		}
+		h.Mul(h, sensitivityStep)
+		d, err := p.derivative(vars, name, x, h)
+		if err != nil {
+			return nil, err
+		}
+		f, _ := d.Float64()
+		s[name] = f
+	}
+	return s, nil
+}
+
+// derivative estimates the derivative with respect to the variable name at
+// x with the step h
+func (p *Program) derivative(vars map[string]*big.Rat, name string, x, h *big.Rat) (*big.Rat, error) {
+	d1, err := p.central(vars, name, x, h)
+	if err != nil {
+		return p.forward(vars, name, x, h)
+	}
+	d2, err := p.central(vars, name, x, new(big.Rat).Quo(h, big.NewRat(2, 1)))
+	if err != nil {
+		return d1, nil
+	}
+	// (4 d2 - d1) / 3 cancels the error in h^2
+	d := new(big.Rat).Mul(d2, big.NewRat(4, 1))
+	d.Sub(d, d1)
+	return d.Quo(d, big.NewRat(3, 1)), nil
+}
+
+// central returns (f(x + h) - f(x - h)) / 2h
+func (p *Program) central(vars map[string]*big.Rat, name string, x, h *big.Rat) (*big.Rat, error) {
+	hi, err := p.evalAt(vars, name, new(big.Rat).Add(x, h))
+	if err != nil {
+		return nil, err
+	}
+	lo, err := p.evalAt(vars, name, new(big.Rat).Sub(x, h))
+	if err != nil {
+		return nil, err
+	}
+	d := hi.Sub(hi, lo)
+	return d.Quo(d, new(big.Rat).Add(h, h)), nil
+}
+
+// forward returns (f(x + h) - f(x)) / h
+func (p *Program) forward(vars map[string]*big.Rat, name string, x, h *big.Rat) (*big.Rat, error) {
+	hi, err := p.evalAt(vars, name, new(big.Rat).Add(x, h))
+	if err != nil {
+		return nil, err
+	}
+	v, err := p.Eval(vars)
+	if err != nil {
+		return nil, err
+	}
+	d := hi.Sub(hi, v)
+	return d.Quo(d, h), nil
+}
+
+// evalAt evaluates the program with the variable name set to x
+func (p *Program) evalAt(vars map[string]*big.Rat, name string, x *big.Rat) (*big.Rat, error) {
+	at := make(map[string]*big.Rat, len(vars))
+	for k, v := range vars {
+		at[k] = v
+	}
+	at[name] = x
+	return p.Eval(at)
+}
@@ -0,0 +1,49 @@
+package rpn
+
+import (
+	"errors"
+	"math"
+	"math/big"
+	"testing"
+)
+
+func TestSensitivity(t *testing.T) {
+	cases := []struct {
+		in   string
+		vars map[string]*big.Rat
+		want map[string]float64
+	}{
+		{"price * qty * (1 + rate)", map[string]*big.Rat{
+			"price": big.NewRat(20, 1), "qty": big.NewRat(3, 1), "rate": big.NewRat(1, 5), "unused": big.NewRat(1, 1),
+		}, map[string]float64{"price": 3.6, "qty": 24, "rate": 60, "unused": 0}},
+		{"x ^ 3 - 2 * x * y", map[string]*big.Rat{"x": big.NewRat(2, 1), "y": big.NewRat(-1, 1)},
+			map[string]float64{"x": 14, "y": -4}},
+		// a forward difference at the edge of the domain
+		{"sqrt(x)", map[string]*big.Rat{"x": big.NewRat(0, 1)}, map[string]float64{"x": 1000}},
+		{"sin(x)", map[string]*big.Rat{"x": big.NewRat(1, 1)}, map[string]float64{"x": math.Cos(1)}},
+	}
+	for _, tc := range cases {
+		p, err := Compile(tc.in)
+		if err != nil {
+			t.Fatal(err)
+		}
+		s, err := p.Sensitivity(tc.vars)
+		if err != nil {
+			t.Errorf("[%v] %v", tc.in, err)
+			continue
+		}
+		for name, want := range tc.want {
+			if math.Abs(s[name]-want) > 1e-6*math.Max(1, math.Abs(want)) {
+				t.Errorf("[%v] sensitivity to %v should be %v but %v", tc.in, name, want, s[name])
+			}
+		}
+	}
+
+	p, err := Compile("1 / x")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := p.Sensitivity(map[string]*big.Rat{"x": new(big.Rat)}); !errors.Is(err, ErrZeroDivision) {
+		t.Errorf("error should be a zero division but %v", err)
+	}
+}
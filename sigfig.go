@@ -0,0 +1,252 @@
+package rpn
+
+import (
+	"math/big"
+	"strconv"
+	"strings"
+)
+
+// Measurement is a result together with the precision it is known to,
+// derived from the significant figures of the literals it was computed from
+type Measurement struct {
+	Value   *big.Rat // the unrounded result
+	Exact   bool     // computed from exact values only, such as variables
+	Figures int      // number of significant figures unless exact
+	Last    int      // power of ten of the last significant digit unless exact
+	mode    big.RoundingMode
+}
+
+// String renders the value rounded to its significant figures, in
+// scientific notation when trailing zeros of an integer would be ambiguous
+func (m Measurement) String() string {
+	if m.Exact {
+		return m.Value.RatString()
+	}
+	v := roundRat(m.Value, -m.Last, m.mode)
+	if m.Last <= 0 {
+		return v.FloatString(-m.Last)
+	}
+	digits := roundInt(new(big.Rat).Quo(v, pow10(m.Last)), m.mode).String()
+	sign := ""
+	if digits[0] == '-' {
+		sign, digits = "-", digits[1:]
+	}
+	if digits == "0" {
+		return "0"
+	}
+	exp := m.Last + len(digits) - 1
+	if len(digits) > 1 {
+		digits = digits[:1] + "." + digits[1:]
+	}
+	return sign + digits + "e" + strconv.Itoa(exp)
+}
+
+// ResultSigFigs evaluates the expression tracking significant figures, see
+// EvalSigFigs
+func (r *RPN) ResultSigFigs() (Measurement, error) {
+	return r.EvalSigFigs(nil)
+}
+
+// EvalSigFigs evaluates the expression with identifiers bound to the values
+// in vars, tracking significant figures from the literals. Products,
+// quotients and functions keep the fewest significant figures of their
+// inexact operands, sums and differences keep the coarsest last decimal
+// place. Variables, named expressions and integer exponents are exact.
+func (r *RPN) EvalSigFigs(vars map[string]*big.Rat) (Measurement, error) {
+	p := r.prog
+	e := &evaluator{opts: &p.opts, memo: p.memo, reg: p.reg, vars: vars}
+	m, err := e.runSigFigs(p)
+	if err != nil {
+		return Measurement{}, err
+	}
+	return Measurement{Value: new(big.Rat).Set(m.v), Exact: m.exact, Figures: m.figures(),
+		Last: m.last, mode: p.opts.rounding}, nil
+}
+
+// measure is a value and the power of ten of its last significant digit
+type measure struct {
+	v     *big.Rat
+	last  int
+	exact bool
+}
+
+func (m measure) figures() int {
+	if m.exact {
+		return 0
+	}
+	if m.v.Sign() == 0 {
+		return 1
+	}
+	return msd(m.v) - m.last + 1
+}
+
+// runSigFigs executes the code of p, values are never modified in place
+func (e *evaluator) runSigFigs(p *Program) (measure, error) {
+	regs := make([]measure, p.regs)
+	var s []measure
+	for pc := 0; pc < len(p.code); {
+		in := p.code[pc]
+		pc++
+		if in.op.counted() {
+			if err := e.spend(); err != nil {
+				return measure{}, err
+			}
+		}
+		switch in.op {
+		case opConst:
+			s = append(s, measure{v: p.consts[in.arg], last: literalLast(in.tok.v)})
+		case opLoad:
+			name := p.names[in.arg]
+			rv := e.variable(name)
+			if rv == nil {
+				var err error
+				if rv, err = e.expand(name); err != nil {
+					return measure{}, err
+				}
+			}
+			s = append(s, measure{v: rv, exact: true})
+		case opStore:
+			regs[in.arg] = s[len(s)-1]
+		case opLoadReg:
+			s = append(s, regs[in.arg])
+		case opCapture:
+		case opBool:
+			s[len(s)-1] = measure{v: ratBool(s[len(s)-1].v.Sign() != 0), exact: true}
+		case opJumpFalse, opJumpTrue:
+			x := s[len(s)-1]
+			s = s[:len(s)-1]
+			if (x.v.Sign() != 0) == (in.op == opJumpTrue) {
+				s = append(s, measure{v: ratBool(in.op == opJumpTrue), exact: true})
+				pc = in.arg
+			}
+		case opSkip:
+			if s[len(s)-1].v.Sign() == 0 {
+				pc = in.arg
+			}
+			s = s[:len(s)-1]
+		case opJump:
+			pc = in.arg
+		case opNoMatch:
+			return measure{}, ErrInvalidArgument
+		case opNeg:
+			x := s[len(s)-1]
+			s[len(s)-1] = measure{v: new(big.Rat).Neg(x.v), last: x.last, exact: x.exact}
+		case opCall:
+			n := len(s) - in.argc
+			args := make([]*big.Rat, in.argc)
+			for i, m := range s[n:] {
+				args[i] = m.v
+			}
+			rv, err := e.call(p.funcNames[in.arg], p.funcs[in.arg], args)
+			if err != nil {
+				return measure{}, err
+			}
+			s = append(s[:n], scaled(rv, s[n:]...))
+		case opForm:
+			// the body is evaluated exactly, only the arguments are measured
+			n := len(s) - in.argc
+			args := make([]*big.Rat, in.argc)
+			for i, m := range s[n:] {
+				args[i] = m.v
+			}
+			sub := p.subs[in.arg]
+			rv, err := sub.form.eval(e, sub.body, args)
+			if err != nil {
+				return measure{}, err
+			}
+			s = append(s[:n], scaled(rv, s[n:]...))
+		default:
+			x, y := s[len(s)-2], s[len(s)-1]
+			s = s[:len(s)-2]
+			rv, err := binary(in.op, new(big.Rat), x.v, y.v, e.opts.semantics)
+			if err == ErrZeroDivision && e.opts.zeroDiv == ZeroDivisionValue {
+				s = append(s, measure{v: e.opts.zeroValue, exact: true})
+				continue
+			}
+			if err != nil {
+				return measure{}, err
+			}
+			switch {
+			case in.op >= opEq && in.op <= opGe:
+				s = append(s, measure{v: rv, exact: true})
+			case in.op == opAdd || in.op == opSub:
+				m := measure{v: rv, last: x.last, exact: x.exact && y.exact}
+				if x.exact || !y.exact && y.last > x.last {
+					m.last = y.last
+				}
+				s = append(s, m)
+			case in.op == opPow && y.v.IsInt():
+				s = append(s, scaled(rv, x))
+			default:
+				s = append(s, scaled(rv, x, y))
+			}
+		}
+	}
+	return s[len(s)-1], nil
+}
+
+// scaled measures v computed from operands by multiplication or a function,
+// keeping the fewest significant figures of the inexact operands
+func scaled(v *big.Rat, operands ...measure) measure {
+	m := measure{v: v, exact: true}
+	figures, zeroLast := 0, 0
+	for _, o := range operands {
+		if o.exact {
+			continue
+		}
+		if f := o.figures(); m.exact || f < figures {
+			figures = f
+		}
+		if m.exact || o.last > zeroLast {
+			zeroLast = o.last
+		}
+		m.exact = false
+	}
+	if m.exact {
+		return m
+	}
+	if v.Sign() == 0 {
+		m.last = zeroLast
+	} else {
+		m.last = msd(v) - figures + 1
+	}
+	return m
+}
+
+// literalLast returns the power of ten of the last significant digit of a
+// literal, trailing zeros of an integer are not significant
+func literalLast(lit string) int {
+	if i := strings.IndexByte(lit, '.'); i >= 0 {
+		return i + 1 - len(lit)
+	}
+	trimmed := strings.TrimRight(lit, "0")
+	if trimmed == "" {
+		return 0
+	}
+	return len(lit) - len(trimmed)
+}
+
+// msd returns the power of ten of the most significant digit of x, which
+// must not be zero
+func msd(x *big.Rat) int {
+	num := new(big.Int).Abs(x.Num())
+	exp := len(num.String()) - len(x.Denom().String())
+	// num/denom lies in [10^(exp-1), 10^(exp+1)), compare with 10^exp
+	if new(big.Rat).SetFrac(num, x.Denom()).Cmp(pow10(exp)) < 0 {
+		exp--
+	}
+	return exp
+}
+
+// pow10 returns 10 raised to exp
+func pow10(exp int) *big.Rat {
+	abs := exp
+	if abs < 0 {
+		abs = -abs
+	}
+	p := new(big.Rat).SetInt(new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(abs)), nil))
+	if exp < 0 {
+		p.Inv(p)
+	}
+	return p
+}
@@ -0,0 +1,56 @@
+package rpn
+
+import (
+	"math/big"
+	"testing"
+)
+
+func TestSigFigs(t *testing.T) {
+	cases := []struct {
+		in      string
+		vars    map[string]*big.Rat
+		figures int
+		s       string
+	}{
+		{"12.3 * 2.00", nil, 3, "24.6"},
+		{"12.3 * 2.0", nil, 2, "25"},
+		{"12.3 * 2", nil, 1, "2e1"},
+		{"1.0 + 2.25", nil, 2, "3.3"},
+		{"100 + 1.234", nil, 1, "1e2"},
+		{"0.0050 * 3.00", nil, 2, "0.015"},
+		{"1.0 - 0.98", nil, 0, "0.0"},
+		{"4.00 / 3", nil, 1, "1"},
+		{"9.96 + 0.1", nil, 3, "10.1"},
+		{"2.0 ^ 3", nil, 2, "8.0"},
+		{"-1.50 * 2.0", nil, 2, "-3.0"},
+		{"sqrt(2.00)", nil, 3, "1.41"},
+		{"1200 * 1.00", nil, 2, "1.2e3"},
+		{"x * 1.50", map[string]*big.Rat{"x": big.NewRat(1, 3)}, 3, "0.500"},
+		{"x + 0.10", map[string]*big.Rat{"x": big.NewRat(1, 3)}, 2, "0.43"},
+		{"x / 3", map[string]*big.Rat{"x": big.NewRat(1, 1)}, 1, "0.3"},
+	}
+	for _, tc := range cases {
+		r, err := New(tc.in)
+		if err != nil {
+			t.Errorf("can not convert [%v], err %v", tc.in, err)
+			continue
+		}
+		m, err := r.EvalSigFigs(tc.vars)
+		if err != nil {
+			t.Errorf("[%v] err %v", tc.in, err)
+			continue
+		}
+		if m.Figures != tc.figures || m.String() != tc.s {
+			t.Errorf("[%v] should be %v with %v figures but %v with %v", tc.in, tc.s, tc.figures, m, m.Figures)
+		}
+	}
+
+	r, err := New("x * y", WithRoundingMode(big.ToNearestEven))
+	if err != nil {
+		t.Fatal(err)
+	}
+	m, err := r.EvalSigFigs(map[string]*big.Rat{"x": big.NewRat(1, 3), "y": big.NewRat(3, 2)})
+	if err != nil || !m.Exact || m.String() != "1/2" {
+		t.Errorf("exact result should be 1/2 but %v, err %v", m, err)
+	}
+}
@@ -0,0 +1,185 @@
+package rpn
+
+import (
+	"math"
+	"math/big"
+)
+
+// SolveFor returns the value of the variable name making the expression
+// hold given the values of the other variables in known. An equation such
+// as "total == price * qty * (1 + rate)" holds when both sides are equal,
+// any other expression when it is zero. If name occurs once, outside of
+// functions, powers and named expressions, the equation is inverted
+// exactly, otherwise it is solved numerically like solve starting from the
+// value of name in known, 1 if there is none. SolveFor fails with
+// ErrInvalidArgument if name does not occur or no solution is found.
+func (r *RPN) SolveFor(name string, known map[string]*big.Rat) (*big.Rat, error) {
+	roots, _, err := buildTree(r.postfix, 1, r.opts.caseMode == CaseInsensitive)
+	if err != nil {
+		return nil, err
+	}
+	lhs, rhs := roots[0], (*node)(nil)
+	if lhs.tok.tp == tokenTypeOperator && lhs.tok.v == "==" {
+		lhs, rhs = lhs.args[0], lhs.args[1]
+	}
+	s := &solver{r: r, name: name, known: known}
+	if s.occurrences(lhs)+s.occurrences(rhs) == 0 {
+		return nil, ErrInvalidArgument
+	}
+	if rv, err := s.invert(lhs, rhs); err == nil && s.holds(lhs, rhs, rv) {
+		return rv, nil
+	}
+	return s.numeric(lhs, rhs)
+}
+
+type solver struct {
+	r     *RPN
+	name  string
+	known map[string]*big.Rat
+}
+
+// occurrences counts the identifiers naming the variable in n
+func (s *solver) occurrences(n *node) int {
+	if n == nil {
+		return 0
+	}
+	if n.tok.tp == tokenTypeIdentifier && s.r.opts.sameName(n.tok.v, s.name) {
+		return 1
+	}
+	c := 0
+	for _, arg := range n.args {
+		c += s.occurrences(arg)
+	}
+	return c
+}
+
+// program compiles the subtree n, nil for a nil n
+func (s *solver) program(n *node) (*Program, error) {
+	if n == nil {
+		return nil, nil
+	}
+	return compile(&RPN{opts: s.r.opts, reg: s.r.reg, postfix: postfixOf(n, nil)}, 1)
+}
+
+// eval evaluates the subtree n with the known values, zero for a nil n
+func (s *solver) eval(n *node) (*big.Rat, error) {
+	p, err := s.program(n)
+	if err != nil || p == nil {
+		return new(big.Rat), err
+	}
+	return p.Eval(s.known)
+}
+
+// invert solves lhs = rhs, zero if rhs is nil, by undoing the operators
+// applied to the variable in turn
+func (s *solver) invert(lhs, rhs *node) (*big.Rat, error) {
+	if s.occurrences(lhs) == 0 {
+		lhs, rhs = rhs, lhs
+	}
+	if s.occurrences(lhs) != 1 || s.occurrences(rhs) != 0 {
+		return nil, ErrInvalidArgument
+	}
+	t, err := s.eval(rhs)
+	if err != nil {
+		return nil, err
+	}
+	t = new(big.Rat).Set(t)
+	n := lhs
+	for n.tok.tp != tokenTypeIdentifier {
+		if n.tok.tp != tokenTypeOperator {
+			return nil, ErrInvalidArgument
+		}
+		if n.tok.v == "@" {
+			t.Neg(t)
+			n = n.args[0]
+			continue
+		}
+		i := 0 // index of the argument holding the variable
+		if s.occurrences(n.args[1]) == 1 {
+			i = 1
+		}
+		other, err := s.eval(n.args[1-i])
+		if err != nil {
+			return nil, err
+		}
+		switch {
+		case n.tok.v == "+":
+			t.Sub(t, other)
+		case n.tok.v == "-" && i == 0:
+			t.Add(t, other)
+		case n.tok.v == "-":
+			t.Sub(other, t)
+		case (n.tok.v == "*" || n.tok.v == "×") && other.Sign() != 0:
+			t.Quo(t, other)
+		case (n.tok.v == "/" || n.tok.v == "÷") && i == 0 && other.Sign() != 0:
+			t.Mul(t, other)
+		case (n.tok.v == "/" || n.tok.v == "÷") && t.Sign() != 0 && other.Sign() != 0:
+			t.Quo(other, t)
+		default:
+			return nil, ErrInvalidArgument
+		}
+		n = n.args[i]
+	}
+	return t, nil
+}
+
+// holds reports whether lhs = rhs, zero if rhs is nil, evaluates exactly
+// with the variable set to x
+func (s *solver) holds(lhs, rhs *node, x *big.Rat) bool {
+	vars := make(map[string]*big.Rat, len(s.known)+1)
+	for k, v := range s.known {
+		vars[k] = v
+	}
+	vars[s.name] = x
+	at := &solver{r: s.r, name: s.name, known: vars}
+	l, err := at.eval(lhs)
+	if err != nil {
+		return false
+	}
+	r, err := at.eval(rhs)
+	return err == nil && l.Cmp(r) == 0
+}
+
+// numeric solves lhs = rhs with the secant method
+func (s *solver) numeric(lhs, rhs *node) (*big.Rat, error) {
+	pl, err := s.program(lhs)
+	if err != nil {
+		return nil, err
+	}
+	pr, err := s.program(rhs)
+	if err != nil {
+		return nil, err
+	}
+	vars := make(map[string]*big.Rat, len(s.known)+1)
+	for k, v := range s.known {
+		vars[k] = v
+	}
+	guess := 1.0
+	if v := lookupVar(s.known, s.name, s.r.opts.caseMode == CaseInsensitive); v != nil {
+		guess, _ = v.Float64()
+	}
+	f := func(x float64) (float64, error) {
+		if math.IsInf(x, 0) || math.IsNaN(x) {
+			return 0, ErrInvalidArgument
+		}
+		vars[s.name] = new(big.Rat).SetFloat64(x)
+		d, err := pl.Eval(vars)
+		if err != nil {
+			return 0, err
+		}
+		if pr != nil {
+			b, err := pr.Eval(vars)
+			if err != nil {
+				return 0, err
+			}
+			d.Sub(d, b)
+		}
+		f, _ := d.Float64()
+		return f, nil
+	}
+	x, err := solve(f, []float64{guess})
+	if err != nil {
+		return nil, err
+	}
+	return setFinite(new(big.Rat), x)
+}
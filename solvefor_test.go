@@ -0,0 +1,66 @@
+package rpn
+
+import (
+	"errors"
+	"math/big"
+	"testing"
+)
+
+func TestSolveFor(t *testing.T) {
+	known := map[string]*big.Rat{
+		"total": big.NewRat(330, 1),
+		"qty":   big.NewRat(3, 1),
+		"rate":  big.NewRat(1, 10),
+		"price": big.NewRat(100, 1),
+	}
+	cases := []struct {
+		in     string
+		name   string
+		result *big.Rat
+		exact  bool
+		err    error
+	}{
+		{"total == price * qty * (1 + rate)", "price", big.NewRat(100, 1), true, nil},
+		{"total == price * qty * (1 + rate)", "qty", big.NewRat(3, 1), true, nil},
+		{"total == price * qty * (1 + rate)", "rate", big.NewRat(1, 10), true, nil},
+		{"price * qty * (1 + rate) == total", "total", big.NewRat(330, 1), true, nil},
+		{"100 / (x - 1) - 4", "x", big.NewRat(26, 1), true, nil},
+		{"-(2 - x) / 3 == 1 / 3", "x", big.NewRat(3, 1), true, nil},
+		{"x * x == 2", "x", big.NewRat(1414213562373095, 1000000000000000), false, nil},
+		{"sqrt(x) == 3", "x", big.NewRat(9, 1), false, nil},
+		{"x * 0 == 1", "x", nil, false, ErrInvalidArgument},
+		{"x / (qty - 3) == 5", "x", nil, false, ErrZeroDivision},
+		{"(qty - 3) / x == 2", "x", nil, false, ErrInvalidArgument},
+		// any x but 0 holds, the numeric solver keeps its first guess
+		{"(qty - 3) / x == 0", "x", big.NewRat(1, 1), true, nil},
+		{"qty + 1", "x", nil, false, ErrInvalidArgument},
+	}
+	for _, tc := range cases {
+		r, err := New(tc.in)
+		if err != nil {
+			t.Errorf("can not convert [%v], err %v", tc.in, err)
+			continue
+		}
+		vars := make(map[string]*big.Rat)
+		for k, v := range known {
+			if k != tc.name {
+				vars[k] = v
+			}
+		}
+		rv, err := r.SolveFor(tc.name, vars)
+		if !errors.Is(err, tc.err) {
+			t.Errorf("[%v] err should be %v but %v", tc.in, tc.err, err)
+			continue
+		}
+		if err != nil {
+			continue
+		}
+		if tc.exact && rv.Cmp(tc.result) != 0 {
+			t.Errorf("[%v] %v should be %v but %v", tc.in, tc.name, tc.result, rv)
+		}
+		d := new(big.Rat).Sub(rv, tc.result)
+		if d.Abs(d).Cmp(big.NewRat(1, 1000000000)) > 0 {
+			t.Errorf("[%v] %v should be about %v but %v", tc.in, tc.name, tc.result.FloatString(9), rv.FloatString(9))
+		}
+	}
+}
@@ -0,0 +1,61 @@
+package rpn
+
+// MaxStack returns the largest number of values on the evaluation stack
+// while the program runs, including those of the bodies of forms on top of
+// the values below them. Named expressions are evaluated by programs of their
+// own and are not counted.
+func (p *Program) MaxStack() int {
+	return p.stack
+}
+
+// maxStack returns the stack depth the code of p needs, following both
+// ways of each jump
+func maxStack(p *Program) int {
+	depths := make([]int, len(p.code)+1)
+	for i := range depths {
+		depths[i] = -1
+	}
+	depths[0] = 0
+	reach := func(pc, d int) {
+		if d > depths[pc] {
+			depths[pc] = d
+		}
+	}
+	max := 0
+	for pc, in := range p.code {
+		d := depths[pc]
+		if d < 0 {
+			continue
+		}
+		switch in.op {
+		case opConst, opLoad, opLoadReg, opLocal:
+			d++
+		case opStore, opBool, opCapture, opNeg:
+		case opCall:
+			d += 1 - in.argc
+		case opForm:
+			if top := d + p.subs[in.arg].body.stack; top > max {
+				max = top
+			}
+			d += 1 - in.argc
+		case opJumpFalse, opJumpTrue:
+			reach(in.arg, d)
+			d--
+		case opSkip:
+			d--
+			reach(in.arg, d)
+		case opJump:
+			reach(in.arg, d)
+			continue
+		case opNoMatch:
+			continue
+		default:
+			d--
+		}
+		if d > max {
+			max = d
+		}
+		reach(pc+1, d)
+	}
+	return max
+}
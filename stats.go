@@ -0,0 +1,123 @@
+package rpn
+
+import (
+	"math"
+	"math/big"
+	"sort"
+)
+
+// The statistical functions take the values of a sample as their arguments,
+// such as the cells of a range
+
+// sumAll returns the sum of args
+func sumAll(o *options, args []*big.Rat) (*big.Rat, error) {
+	sum := new(big.Rat)
+	for _, x := range args {
+		sum.Add(sum, x)
+	}
+	return sum, nil
+}
+
+// variance returns the sample variance of args, dividing by n - 1
+func variance(o *options, args []*big.Rat) (*big.Rat, error) {
+	if len(args) < 2 {
+		return nil, ErrInvalidArgument
+	}
+	mean := new(big.Rat)
+	for _, x := range args {
+		mean.Add(mean, x)
+	}
+	mean.Quo(mean, big.NewRat(int64(len(args)), 1))
+	sum, d := new(big.Rat), new(big.Rat)
+	for _, x := range args {
+		d.Sub(x, mean)
+		sum.Add(sum, d.Mul(d, d))
+	}
+	return sum.Quo(sum, big.NewRat(int64(len(args)-1), 1)), nil
+}
+
+// stdev returns the sample standard deviation of args
+func stdev(o *options, args []*big.Rat) (*big.Rat, error) {
+	v, err := variance(o, args)
+	if err != nil {
+		return nil, err
+	}
+	if r := ratSqrt(v); r != nil {
+		return r, nil
+	}
+	f, _ := v.Float64()
+	return setFinite(v, math.Sqrt(f))
+}
+
+// percentile returns the p-th percentile of the values preceding p, p
+// ranging from 0 to 1, interpolating linearly between the closest ranks
+func percentile(o *options, args []*big.Rat) (*big.Rat, error) {
+	p := args[len(args)-1]
+	if p.Sign() < 0 || p.Cmp(ratOne) > 0 {
+		return nil, ErrInvalidArgument
+	}
+	values := append([]*big.Rat(nil), args[:len(args)-1]...)
+	sort.Slice(values, func(i, j int) bool { return values[i].Cmp(values[j]) < 0 })
+	rank := new(big.Rat).Mul(p, big.NewRat(int64(len(values)-1), 1))
+	k := new(big.Int).Quo(rank.Num(), rank.Denom()).Int64()
+	if k == int64(len(values)-1) {
+		return new(big.Rat).Set(values[k]), nil
+	}
+	frac := rank.Sub(rank, new(big.Rat).SetInt64(k))
+	v := new(big.Rat).Sub(values[k+1], values[k])
+	v.Mul(v, frac)
+	return v.Add(v, values[k]), nil
+}
+
+// corr returns the Pearson correlation coefficient of two samples of the
+// same size, the first half of args and the second
+func corr(o *options, args []*big.Rat) (*big.Rat, error) {
+	n := len(args) / 2
+	if len(args)%2 != 0 || n < 2 {
+		return nil, ErrInvalidArgument
+	}
+	xs, ys := args[:n], args[n:]
+	mx, my := new(big.Rat), new(big.Rat)
+	for i := 0; i < n; i++ {
+		mx.Add(mx, xs[i])
+		my.Add(my, ys[i])
+	}
+	mx.Quo(mx, big.NewRat(int64(n), 1))
+	my.Quo(my, big.NewRat(int64(n), 1))
+	sxy, sxx, syy := new(big.Rat), new(big.Rat), new(big.Rat)
+	dx, dy, t := new(big.Rat), new(big.Rat), new(big.Rat)
+	for i := 0; i < n; i++ {
+		dx.Sub(xs[i], mx)
+		dy.Sub(ys[i], my)
+		sxy.Add(sxy, t.Mul(dx, dy))
+		sxx.Add(sxx, t.Mul(dx, dx))
+		syy.Add(syy, t.Mul(dy, dy))
+	}
+	if sxx.Sign() == 0 || syy.Sign() == 0 {
+		return nil, ErrInvalidArgument
+	}
+	// the coefficient is exact when its square is a perfect square
+	sq := new(big.Rat).Mul(sxy, sxy)
+	sq.Quo(sq, t.Mul(sxx, syy))
+	if r := ratSqrt(sq); r != nil {
+		if sxy.Sign() < 0 {
+			r.Neg(r)
+		}
+		return r, nil
+	}
+	f, _ := sq.Float64()
+	f = math.Sqrt(f)
+	if sxy.Sign() < 0 {
+		f = -f
+	}
+	return setFinite(new(big.Rat), f)
+}
+
+// ratSqrt returns the square root of x if it is rational, nil otherwise
+func ratSqrt(x *big.Rat) *big.Rat {
+	num, den := new(big.Int).Sqrt(x.Num()), new(big.Int).Sqrt(x.Denom())
+	if new(big.Int).Mul(num, num).Cmp(x.Num()) != 0 || new(big.Int).Mul(den, den).Cmp(x.Denom()) != 0 {
+		return nil
+	}
+	return new(big.Rat).SetFrac(num, den)
+}
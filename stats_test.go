@@ -0,0 +1,56 @@
+package rpn
+
+import (
+	"errors"
+	"math/big"
+	"testing"
+)
+
+func TestStatistics(t *testing.T) {
+	cases := []struct {
+		in     string
+		result *big.Rat
+		err    error
+	}{
+		{"variance(2, 4, 4, 4, 5, 5, 7, 9)", big.NewRat(32, 7), nil},
+		{"stdev(1, 3)", big.NewRat(1414213562373095, 1000000000000000), nil},
+		{"stdev(2, 4, 6)", big.NewRat(2, 1), nil},
+		{"variance(1, 1)", new(big.Rat), nil},
+		{"percentile(15, 20, 35, 40, 50, 0.4)", big.NewRat(29, 1), nil},
+		{"percentile(3, 1, 2, 0.5)", big.NewRat(2, 1), nil},
+		{"percentile(3, 1, 2, 1)", big.NewRat(3, 1), nil},
+		{"percentile(3, 1, 2, 0)", big.NewRat(1, 1), nil},
+		{"percentile(1, 2, 1.5)", nil, ErrInvalidArgument},
+		{"corr(1, 2, 3, 2, 4, 6)", big.NewRat(1, 1), nil},
+		{"corr(1, 2, 3, 3, 2, 1)", big.NewRat(-1, 1), nil},
+		{"corr(1, 2, 3, 1, 3, 2)", big.NewRat(1, 2), nil},
+		{"corr(1, 1, 2, 3)", nil, ErrInvalidArgument},
+		{"corr(1, 2, 3, 4, 5)", nil, ErrInvalidArgument},
+	}
+	for _, tc := range cases {
+		r, err := New(tc.in)
+		if err != nil {
+			t.Errorf("can not convert [%v], err %v", tc.in, err)
+			continue
+		}
+		result, err := r.Result()
+		if !errors.Is(err, tc.err) {
+			t.Errorf("[%v] err should be %v but %v", tc.in, tc.err, err)
+			continue
+		}
+		if err != nil {
+			continue
+		}
+		if tc.in == "stdev(1, 3)" {
+			// irrational, compare approximately
+			d := new(big.Rat).Sub(result, tc.result)
+			if d.Abs(d).Cmp(big.NewRat(1, 1000000000000)) > 0 {
+				t.Errorf("[%v] result should be about %v but %v", tc.in, tc.result.FloatString(15), result.FloatString(15))
+			}
+			continue
+		}
+		if result.Cmp(tc.result) != 0 {
+			t.Errorf("[%v] result should be %v but %v", tc.in, tc.result, result)
+		}
+	}
+}
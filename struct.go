@@ -0,0 +1,150 @@
+package rpn
+
+import (
+	"math"
+	"math/big"
+	"reflect"
+)
+
+var (
+	ratType = reflect.TypeOf(big.Rat{})
+	intType = reflect.TypeOf(big.Int{})
+)
+
+// EvalStruct evaluates the expression with identifiers bound to the
+// exported fields of the struct v, or to the entries of the map v with
+// string keys. A field is named by its `rpn:"name"` tag if it has one and
+// skipped if the tag is "-", fields of embedded structs are promoted.
+// Integers, floats, booleans, big.Int and big.Rat values and pointers to them
+// are bound, other fields and nil pointers are skipped. A pointer to a struct
+// or map is followed.
+func (r *RPN) EvalStruct(v interface{}) (*big.Rat, error) {
+	vars, err := structVars(v)
+	if err != nil {
+		return nil, err
+	}
+	return r.Eval(vars)
+}
+
+// structVars returns the variables bound by the struct or map v
+func structVars(v interface{}) (map[string]*big.Rat, error) {
+	rv := reflect.ValueOf(v)
+	for rv.Kind() == reflect.Ptr || rv.Kind() == reflect.Interface {
+		if rv.IsNil() {
+			return nil, ErrInvalidArgument
+		}
+		rv = rv.Elem()
+	}
+	vars := make(map[string]*big.Rat)
+	switch rv.Kind() {
+	case reflect.Struct:
+		if err := addFields(vars, rv); err != nil {
+			return nil, err
+		}
+	case reflect.Map:
+		if rv.Type().Key().Kind() != reflect.String {
+			return nil, ErrInvalidArgument
+		}
+		iter := rv.MapRange()
+		for iter.Next() {
+			x, err := reflectRat(iter.Value())
+			if err != nil {
+				return nil, err
+			}
+			if x != nil {
+				vars[iter.Key().String()] = x
+			}
+		}
+	default:
+		return nil, ErrInvalidArgument
+	}
+	return vars, nil
+}
+
+// addFields binds the fields of the struct v not bound yet, those of
+// embedded structs after the others so that they are shadowed like in Go
+func addFields(vars map[string]*big.Rat, v reflect.Value) error {
+	t := v.Type()
+	var embedded []reflect.Value
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		tag := f.Tag.Get("rpn")
+		if tag == "-" || f.PkgPath != "" && !f.Anonymous {
+			continue
+		}
+		fv := v.Field(i)
+		if f.Anonymous && tag == "" {
+			for fv.Kind() == reflect.Ptr && !fv.IsNil() {
+				fv = fv.Elem()
+			}
+			if fv.Kind() == reflect.Struct && fv.Type() != ratType && fv.Type() != intType {
+				embedded = append(embedded, fv)
+				continue
+			}
+			if f.PkgPath != "" {
+				continue
+			}
+		}
+		name := tag
+		if name == "" {
+			name = f.Name
+		}
+		if _, ok := vars[name]; ok {
+			continue
+		}
+		x, err := reflectRat(fv)
+		if err != nil {
+			return err
+		}
+		if x != nil {
+			vars[name] = x
+		}
+	}
+	for _, fv := range embedded {
+		if err := addFields(vars, fv); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// reflectRat returns the value of v, or nil if v holds no number. Floats
+// which are not finite are invalid.
+func reflectRat(v reflect.Value) (*big.Rat, error) {
+	switch v.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return new(big.Rat).SetInt64(v.Int()), nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return new(big.Rat).SetInt(new(big.Int).SetUint64(v.Uint())), nil
+	case reflect.Float32, reflect.Float64:
+		f := v.Float()
+		if math.IsInf(f, 0) || math.IsNaN(f) {
+			return nil, ErrInvalidArgument
+		}
+		return new(big.Rat).SetFloat64(f), nil
+	case reflect.Bool:
+		return new(big.Rat).Set(ratBool(v.Bool())), nil
+	case reflect.Ptr, reflect.Interface:
+		if v.IsNil() {
+			return nil, nil
+		}
+		if !v.CanInterface() {
+			return nil, nil
+		}
+		if x, ok := v.Interface().(*big.Rat); ok {
+			return x, nil
+		}
+		return reflectRat(v.Elem())
+	case reflect.Struct:
+		if !v.CanInterface() {
+			return nil, nil
+		}
+		switch x := v.Interface().(type) {
+		case big.Rat:
+			return new(big.Rat).Set(&x), nil
+		case big.Int:
+			return new(big.Rat).SetInt(&x), nil
+		}
+	}
+	return nil, nil
+}
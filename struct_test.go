@@ -0,0 +1,69 @@
+package rpn
+
+import (
+	"errors"
+	"math"
+	"math/big"
+	"testing"
+)
+
+type structBase struct {
+	Qty      int
+	Discount float64
+}
+
+type structOrder struct {
+	structBase
+	UnitPrice *big.Rat `rpn:"unit_price"`
+	Discount  big.Rat
+	Express   bool
+	Note      string
+	Secret    int `rpn:"-"`
+	hidden    int
+	Missing   *int
+}
+
+func TestEvalStruct(t *testing.T) {
+	order := structOrder{
+		structBase: structBase{Qty: 3, Discount: 0.5},
+		UnitPrice:  big.NewRat(5, 2),
+		Express:    true,
+		Secret:     1,
+		hidden:     1,
+	}
+	order.Discount.SetFrac64(1, 10)
+	cases := []struct {
+		in     string
+		v      interface{}
+		result *big.Rat
+		err    error
+	}{
+		{"unit_price * Qty * (1 - Discount) + Express", order, big.NewRat(31, 4), nil},
+		{"unit_price * Qty", &order, big.NewRat(15, 2), nil},
+		{"Secret", order, nil, ErrUndefined},
+		{"hidden", order, nil, ErrUndefined},
+		{"Missing", order, nil, ErrUndefined},
+		{"Note", order, nil, ErrUndefined},
+		{"a + b * c", map[string]interface{}{"a": 1, "b": uint8(2), "c": big.NewInt(3), "d": "x"}, big.NewRat(7, 1), nil},
+		{"a / 4", map[string]float64{"a": 1}, big.NewRat(1, 4), nil},
+		{"a", map[string]float64{"a": math.NaN()}, nil, ErrInvalidArgument},
+		{"a", map[int]int{1: 1}, nil, ErrInvalidArgument},
+		{"a", 5, nil, ErrInvalidArgument},
+		{"a", (*structOrder)(nil), nil, ErrInvalidArgument},
+	}
+	for _, tc := range cases {
+		r, err := New(tc.in)
+		if err != nil {
+			t.Errorf("can not convert [%v], err %v", tc.in, err)
+			continue
+		}
+		result, err := r.EvalStruct(tc.v)
+		if !errors.Is(err, tc.err) {
+			t.Errorf("[%v] with %+v err should be %v but %v", tc.in, tc.v, tc.err, err)
+			continue
+		}
+		if err == nil && result.Cmp(tc.result) != 0 {
+			t.Errorf("[%v] with %+v result should be %v but %v", tc.in, tc.v, tc.result, result)
+		}
+	}
+}
@@ -0,0 +1,114 @@
+package rpn
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"hash/fnv"
+	"strconv"
+	"strings"
+)
+
+// DefaultSynonyms returns the operators which are synonyms of others mapped
+// to the operators they stand for: × and ÷ to * and /, ** to ^ and div to //
+func DefaultSynonyms() map[string]string {
+	return map[string]string{"×": "*", "÷": "/", "**": "^", "div": "//"}
+}
+
+// WithOperatorSynonyms rewrites operators which are keys of table to their
+// values as the expression is parsed, so that Postfix, Tokens and
+// Fingerprint show a single form of each operator. Only operators are
+// rewritten, an expression using a synonym of something other than an
+// operator is unrecognized. Operators are kept as written by default.
+func WithOperatorSynonyms(table map[string]string) Option {
+	synonyms := make(map[string]string, len(table))
+	for k, v := range table {
+		synonyms[k] = v
+	}
+	return func(o *options) {
+		o.synonyms = synonyms
+	}
+}
+
+// canonicalOperators rewrites the operators of tokens in place according to
+// synonyms
+func canonicalOperators(tokens []*token, synonyms map[string]string) {
+	for _, t := range tokens {
+		if t.tp != tokenTypeOperator {
+			continue
+		}
+		if v, ok := synonyms[t.v]; ok {
+			t.v = v
+			if _, ok := operators[v]; !ok {
+				t.tp = tokenTypeUnknown
+			}
+		}
+	}
+}
+
+// Fingerprint returns the SHA-256 digest in hex of the canonical postfix
+// notation of the expression, so that expressions differing only in
+// spacing, parentheses, operator synonyms, the spelling of numbers such as
+// 1.50 and 1.5 or the case of names matched regardless of case share it.
+// Synonyms are those given to WithOperatorSynonyms, DefaultSynonyms if none.
+func (r *RPN) Fingerprint() string {
+	sum := sha256.Sum256([]byte(strings.Join(r.canonicalPostfix(), "\x00")))
+	return hex.EncodeToString(sum[:])
+}
+
+// ID parses expr with opts and returns a 64-bit FNV-1a hash of its canonical
+// postfix notation, shared by the same expressions as Fingerprint. It is
+// cheap and stable across releases, suited to keys of sharded caches and
+// labels of metrics, but unlike Fingerprint not resistant to collisions
+// crafted on purpose.
+func ID(expr string, opts ...Option) (uint64, error) {
+	r, err := New(expr, opts...)
+	if err != nil {
+		return 0, err
+	}
+	h := fnv.New64a()
+	for _, v := range r.canonicalPostfix() {
+		h.Write([]byte(v))
+		h.Write([]byte{0})
+	}
+	return h.Sum64(), nil
+}
+
+// canonicalPostfix returns the postfix notation of the expression with
+// operators, numbers and names in canonical form, functions followed by
+// their number of arguments
+func (r *RPN) canonicalPostfix() []string {
+	s := make([]string, 0, len(r.postfix))
+	for _, tok := range r.postfix {
+		s = append(s, r.canonical(tok))
+	}
+	return s
+}
+
+// canonical returns the canonical form of tok, see canonicalPostfix
+func (r *RPN) canonical(tok *token) string {
+	synonyms := r.opts.synonyms
+	if synonyms == nil {
+		synonyms = DefaultSynonyms()
+	}
+	v := tok.v
+	switch tok.tp {
+	case tokenTypeOperator:
+		if c, ok := synonyms[v]; ok {
+			v = c
+		}
+	case tokenTypeOperand:
+		if x, err := parseLiteral(v); err == nil {
+			v = x.RatString()
+		}
+	case tokenTypeIdentifier:
+		if r.opts.caseMode == CaseInsensitive {
+			v = strings.ToLower(v)
+		}
+	case tokenTypeFunction:
+		if r.opts.caseMode != CaseSensitive {
+			v = strings.ToLower(v)
+		}
+		v += "/" + strconv.Itoa(tok.argc)
+	}
+	return v
+}
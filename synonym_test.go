@@ -0,0 +1,115 @@
+package rpn
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestOperatorSynonyms(t *testing.T) {
+	cases := []struct {
+		in      string
+		table   map[string]string
+		postfix []string
+		err     error
+	}{
+		{"2 × 3 ÷ x ** 2", DefaultSynonyms(), []string{"2", "3", "*", "x", "2", "^", "/"}, nil},
+		{"7 div -2", DefaultSynonyms(), []string{"7", "2", "@", "//"}, nil},
+		{"2 × 3", nil, []string{"2", "3", "×"}, nil},
+		{"2 * 3 ^ 2", map[string]string{"*": "×", "^": "**"}, []string{"2", "3", "2", "**", "×"}, nil},
+		{"2 * 3", map[string]string{"*": "mul"}, nil, ErrUnrecognizedExpression},
+	}
+	for _, tc := range cases {
+		for _, pratt := range []bool{false, true} {
+			opts := []Option{WithOperatorSynonyms(tc.table)}
+			if tc.table == nil {
+				opts = nil
+			}
+			if pratt {
+				opts = append(opts, WithPrattParser())
+			}
+			r, err := New(tc.in, opts...)
+			if !errors.Is(err, tc.err) {
+				t.Errorf("[%v] err should be %v but %v", tc.in, tc.err, err)
+				continue
+			}
+			if err == nil && !reflect.DeepEqual(r.Postfix(), tc.postfix) {
+				t.Errorf("[%v] postfix should be %v but %v", tc.in, tc.postfix, r.Postfix())
+			}
+		}
+	}
+
+	DefaultSynonyms()["×"] = "+"
+	if v := DefaultSynonyms()["×"]; v != "*" {
+		t.Errorf("DefaultSynonyms should return a copy but × maps to %v", v)
+	}
+}
+
+func TestFingerprint(t *testing.T) {
+	fingerprint := func(expr string, opts ...Option) string {
+		r, err := New(expr, opts...)
+		if err != nil {
+			t.Fatalf("can not convert [%v], err %v", expr, err)
+		}
+		return r.Fingerprint()
+	}
+	same := [][]string{
+		{"2*x+1", "2 × x + 1", "(2 * x) + 1.0", "((2) × (x)) + 1.00"},
+		{"a ** 2 ÷ b", "a ^ 2 / b"},
+		{"SQRT(x)", "sqrt(x)"},
+		{"7 div 2", "7 // 2"},
+	}
+	for _, group := range same {
+		want := fingerprint(group[0])
+		if len(want) != 64 {
+			t.Errorf("[%v] fingerprint should be 64 hex digits but %v", group[0], want)
+		}
+		for _, expr := range group[1:] {
+			if got := fingerprint(expr); got != want {
+				t.Errorf("[%v] and [%v] fingerprints should be equal", group[0], expr)
+			}
+		}
+	}
+	different := [][2]string{
+		{"2 * x + 1", "2 * (x + 1)"},
+		{"x", "X"},
+		{"1 - 2", "2 - 1"},
+		{"case(1, 2, 3)", "case(1, 2)"},
+	}
+	for _, pair := range different {
+		if fingerprint(pair[0]) == fingerprint(pair[1]) {
+			t.Errorf("[%v] and [%v] fingerprints should differ", pair[0], pair[1])
+		}
+	}
+	if fingerprint("x", WithCaseSensitivity(CaseInsensitive)) != fingerprint("X", WithCaseSensitivity(CaseInsensitive)) {
+		t.Errorf("names matched regardless of case should share fingerprints")
+	}
+	if fingerprint("a × b", WithOperatorSynonyms(map[string]string{"*": "×"})) != fingerprint("a * b", WithOperatorSynonyms(map[string]string{"*": "×"})) {
+		t.Errorf("fingerprints should use the configured synonyms")
+	}
+}
+
+func TestID(t *testing.T) {
+	id := func(expr string) uint64 {
+		v, err := ID(expr)
+		if err != nil {
+			t.Fatalf("can not convert [%v], err %v", expr, err)
+		}
+		return v
+	}
+	// IDs must not change across releases
+	if v := id("2*x+1"); v != 0x16af3a317279b34f {
+		t.Errorf("ID should be %#x but %#x", uint64(0x16af3a317279b34f), v)
+	}
+	for _, expr := range []string{"2 × x + 1", "(2 * x) + 1.0"} {
+		if id(expr) != id("2*x+1") {
+			t.Errorf("[%v] and [2*x+1] IDs should be equal", expr)
+		}
+	}
+	if id("2 * x + 1") == id("2 * (x + 1)") {
+		t.Errorf("IDs of different expressions should differ")
+	}
+	if _, err := ID("1 +"); err == nil {
+		t.Errorf("an invalid expression should fail")
+	}
+}
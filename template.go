@@ -0,0 +1,84 @@
+package rpn
+
+import (
+	"strconv"
+	"strings"
+)
+
+// hole names the holes of a template while it is parsed
+const hole = "__template_hole"
+
+// Template parses pattern and splices the parsed subs into its holes, each
+// {} in turn, so that Template("({}) * rate", sub) multiplies the value of
+// sub by rate. A sub-expression is kept whole whatever its operators, the
+// parentheses are not needed, and it can not change the rest of the pattern
+// as splicing strings could. The pattern is parsed with the options of the
+// first sub-expression, the default options if there is none.
+func Template(pattern string, subs ...*RPN) (*RPN, error) {
+	if strings.Contains(pattern, hole) || strings.Count(pattern, "{}") != len(subs) {
+		return nil, ErrInvalidArgument
+	}
+	r := configure(nil)
+	if len(subs) > 0 {
+		r.opts, r.reg = subs[0].opts, subs[0].reg
+		// the holes are not tokens of the expression
+		r.opts.onToken = nil
+	}
+	for i := range subs {
+		pattern = strings.Replace(pattern, "{}", " "+hole+strconv.Itoa(i)+" ", 1)
+	}
+	infix, err := r.tokens(pattern)
+	if err != nil {
+		return nil, err
+	}
+	postfix, err := r.parse(infix, len(pattern))
+	if err != nil {
+		return nil, err
+	}
+	// the tokens of each sub-expression are copied once for infix and postfix
+	copies := make([]map[*token]*token, len(subs))
+	for i := range copies {
+		copies[i] = make(map[*token]*token)
+	}
+	r.infix = splice(infix, subs, copies, true)
+	r.postfix = splice(postfix, subs, copies, false)
+	if err := r.prepare(); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// splice replaces the holes of tokens by copies of the infix, parenthesised,
+// or postfix tokens of subs
+func splice(tokens []*token, subs []*RPN, copies []map[*token]*token, infix bool) []*token {
+	s := make([]*token, 0, len(tokens))
+	for _, t := range tokens {
+		i, ok := holeIndex(t)
+		if !ok {
+			s = append(s, t)
+			continue
+		}
+		sub := subs[i].postfix
+		if infix {
+			s = append(s, &token{tp: tokenTypeParenthesis, v: "(", pos: t.pos})
+			sub = subs[i].infix
+		}
+		for _, c := range cloneTokens(sub, copies[i]) {
+			c.pos = t.pos
+			s = append(s, c)
+		}
+		if infix {
+			s = append(s, &token{tp: tokenTypeParenthesis, v: ")", pos: t.pos})
+		}
+	}
+	return s
+}
+
+// holeIndex returns the index of the hole t stands for
+func holeIndex(t *token) (int, bool) {
+	if t.tp != tokenTypeIdentifier || !strings.HasPrefix(t.v, hole) {
+		return 0, false
+	}
+	i, err := strconv.Atoi(t.v[len(hole):])
+	return i, err == nil
+}
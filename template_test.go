@@ -0,0 +1,70 @@
+package rpn
+
+import (
+	"errors"
+	"math/big"
+	"reflect"
+	"testing"
+)
+
+func TestTemplate(t *testing.T) {
+	parse := func(expr string, opts ...Option) *RPN {
+		r, err := New(expr, opts...)
+		if err != nil {
+			t.Fatalf("can not convert [%v], err %v", expr, err)
+		}
+		return r
+	}
+	vars := map[string]*big.Rat{"a": big.NewRat(2, 1), "b": big.NewRat(3, 1), "rate": big.NewRat(1, 10)}
+	cases := []struct {
+		pattern string
+		subs    []*RPN
+		postfix []string
+		result  *big.Rat
+		err     error
+	}{
+		{"({}) * rate", []*RPN{parse("a + b")}, []string{"a", "b", "+", "rate", "*"}, big.NewRat(1, 2), nil},
+		{"{} * rate", []*RPN{parse("a + b")}, []string{"a", "b", "+", "rate", "*"}, big.NewRat(1, 2), nil},
+		{"{} - {}", []*RPN{parse("a"), parse("b - a")}, []string{"a", "b", "a", "-", "-"}, big.NewRat(1, 1), nil},
+		{"round({}, 2)", []*RPN{parse("a / b")}, []string{"a", "b", "/", "2", "round"}, big.NewRat(67, 100), nil},
+		{"-{}^2", []*RPN{parse("-a")}, []string{"a", "@", "2", "^", "@"}, big.NewRat(-4, 1), nil},
+		{"a + 1", nil, []string{"a", "1", "+"}, big.NewRat(3, 1), nil},
+		{"{} + {}", []*RPN{parse("a")}, nil, nil, ErrInvalidArgument},
+		{"{} +", []*RPN{parse("a")}, nil, nil, ErrUnrecognizedExpression},
+		{"__template_hole0 + {}", []*RPN{parse("a")}, nil, nil, ErrInvalidArgument},
+	}
+	for _, tc := range cases {
+		r, err := Template(tc.pattern, tc.subs...)
+		if !errors.Is(err, tc.err) {
+			t.Errorf("[%v] err should be %v but %v", tc.pattern, tc.err, err)
+			continue
+		}
+		if err != nil {
+			continue
+		}
+		if !reflect.DeepEqual(r.Postfix(), tc.postfix) {
+			t.Errorf("[%v] postfix should be %v but %v", tc.pattern, tc.postfix, r.Postfix())
+		}
+		if rv, err := r.Eval(vars); err != nil || rv.Cmp(tc.result) != 0 {
+			t.Errorf("[%v] result should be %v but %v, err %v", tc.pattern, tc.result, rv, err)
+		}
+	}
+
+	// the template is parsed with the options of the first sub-expression
+	sub := parse("A + 1", WithCaseSensitivity(CaseInsensitive), WithPrattParser())
+	r, err := Template("{} * A", sub)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rv, err := r.Eval(vars); err != nil || rv.Cmp(big.NewRat(6, 1)) != 0 {
+		t.Errorf("result should be 6 but %v, err %v", rv, err)
+	}
+	want := []string{"(", "A", "+", "1", ")", "*", "A"}
+	var got []string
+	for _, tok := range r.Tokens() {
+		got = append(got, tok.Value)
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("tokens should be %v but %v", want, got)
+	}
+}
@@ -0,0 +1,88 @@
+package rpn
+
+// TokenKind is the kind of a token
+type TokenKind uint8
+
+const (
+	TokenUnknown     TokenKind = iota // text not forming any token
+	TokenNumber                       // a number literal
+	TokenIdent                        // a variable or named expression
+	TokenOperator                     // an operator, "@" being unary minus unless renamed by WithUnaryMinusName
+	TokenFunction                     // a function name
+	TokenParenthesis                  // "(" or ")"
+	TokenSeparator                    // the "," between function arguments
+)
+
+// Token is a read-only view of a token of an expression
+type Token struct {
+	Kind  TokenKind
+	Value string // the token as written, operators rewritten by WithOperatorSynonyms
+	Pos   int    // byte offset in the expression
+	Args  int    // number of operands taken from the stack in postfix notation
+	Prec  int    // precedence of an operator, from 1 for || to 8 for ^, 0 for other tokens
+}
+
+// Tokens returns the tokens of the expression in infix order
+func (r *RPN) Tokens() []Token {
+	return r.opts.viewTokens(r.infix)
+}
+
+// PostfixTokens returns the tokens of the expression in postfix order
+func (r *RPN) PostfixTokens() []Token {
+	return r.opts.viewTokens(r.postfix)
+}
+
+// viewTokens returns the views of tokens with unary minus named as
+// configured by WithUnaryMinusName
+func (o *options) viewTokens(tokens []*token) []Token {
+	s := make([]Token, 0, len(tokens))
+	for _, tok := range tokens {
+		s = append(s, o.viewToken(tok))
+	}
+	return s
+}
+
+func (o *options) viewToken(tok *token) Token {
+	t := viewToken(tok)
+	t.Value = o.tokenName(tok)
+	return t
+}
+
+// tokenName returns the value of tok with unary minus named as configured
+// by WithUnaryMinusName
+func (o *options) tokenName(tok *token) string {
+	if tok.tp == tokenTypeOperator && tok.v == "@" && o.unaryMinus != "" {
+		return o.unaryMinus
+	}
+	return tok.v
+}
+
+func viewTokens(tokens []*token) []Token {
+	s := make([]Token, 0, len(tokens))
+	for _, tok := range tokens {
+		s = append(s, viewToken(tok))
+	}
+	return s
+}
+
+func viewToken(tok *token) Token {
+	t := Token{Value: tok.v, Pos: tok.pos, Args: arity(tok)}
+	switch tok.tp {
+	case tokenTypeOperand:
+		t.Kind = TokenNumber
+	case tokenTypeIdentifier:
+		t.Kind = TokenIdent
+	case tokenTypeOperator:
+		t.Kind = TokenOperator
+		if op, ok := operators[tok.v]; ok {
+			t.Prec = int(op[0]) - opOff + 9
+		}
+	case tokenTypeFunction:
+		t.Kind = TokenFunction
+	case tokenTypeParenthesis:
+		t.Kind = TokenParenthesis
+	case tokenTypeSeparator:
+		t.Kind = TokenSeparator
+	}
+	return t
+}
@@ -0,0 +1,193 @@
+package rpn
+
+import (
+	"math/big"
+	"strings"
+	"testing"
+)
+
+func TestTokens(t *testing.T) {
+	r, err := New("round(-x, 2) * 3", WithPrattParser())
+	if err != nil {
+		t.Fatal(err)
+	}
+	infix := []Token{
+		{TokenFunction, "round", 0, 2, 0},
+		{TokenParenthesis, "(", 5, 0, 0},
+		{TokenOperator, "-", 6, 2, 5},
+		{TokenIdent, "x", 7, 0, 0},
+		{TokenSeparator, ",", 8, 0, 0},
+		{TokenNumber, "2", 10, 0, 0},
+		{TokenParenthesis, ")", 11, 0, 0},
+		{TokenOperator, "*", 13, 2, 6},
+		{TokenNumber, "3", 15, 0, 0},
+	}
+	postfix := []Token{
+		{TokenIdent, "x", 7, 0, 0},
+		{TokenOperator, "@", 6, 1, 7},
+		{TokenNumber, "2", 10, 0, 0},
+		{TokenFunction, "round", 0, 2, 0},
+		{TokenNumber, "3", 15, 0, 0},
+		{TokenOperator, "*", 13, 2, 6},
+	}
+	for _, tc := range []struct {
+		name   string
+		got    []Token
+		tokens []Token
+	}{{"infix", r.Tokens(), infix}, {"postfix", r.PostfixTokens(), postfix}} {
+		if len(tc.got) != len(tc.tokens) {
+			t.Errorf("%v tokens should be %v but %v", tc.name, tc.tokens, tc.got)
+			continue
+		}
+		for i := range tc.got {
+			if tc.got[i] != tc.tokens[i] {
+				t.Errorf("%v token %d should be %+v but %+v", tc.name, i, tc.tokens[i], tc.got[i])
+			}
+		}
+	}
+}
+
+func TestTokenPositions(t *testing.T) {
+	cases := []struct {
+		in  string
+		pos []int
+	}{
+		{"round(-x, 2) * 3", []int{0, 5, 6, 7, 8, 10, 11, 13, 15}},
+		{"  +-5 +  - 2", []int{3, 4, 6, 9, 11}},
+		{"a>=b&&sqrt(c)", []int{0, 1, 3, 4, 6, 10, 11, 12}},
+	}
+	for _, tc := range cases {
+		r, err := New(tc.in)
+		if err != nil {
+			t.Errorf("can not convert [%v], err %v", tc.in, err)
+			continue
+		}
+		tokens := r.Tokens()
+		var pos []int
+		for _, tok := range tokens {
+			pos = append(pos, tok.Pos)
+		}
+		if !equalInts(pos, tc.pos) {
+			t.Errorf("[%v] positions should be %v but %v", tc.in, tc.pos, pos)
+		}
+	}
+}
+
+func equalInts(a, b []int) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func TestUnaryMinusName(t *testing.T) {
+	var seen []string
+	r, err := New("-x ^ 2 - -3 || y", WithUnaryMinusName("neg"), WithTokenCallback(func(tok Token) {
+		seen = append(seen, tok.Value)
+	}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := strings.Join(r.Postfix(), " "), "x 2 ^ neg 3 neg - y ||"; got != want {
+		t.Errorf("postfix should be %v but %v", want, got)
+	}
+	if got, want := strings.Join(seen, " "), "neg x ^ 2 - neg 3 || y"; got != want {
+		t.Errorf("tokens seen should be %v but %v", want, got)
+	}
+	tokens := r.PostfixTokens()
+	if tok := tokens[3]; tok.Value != "neg" || tok.Kind != TokenOperator || tok.Args != 1 || tok.Prec != 7 {
+		t.Errorf("token 3 should be neg with 1 argument and precedence 7 but %+v", tok)
+	}
+	if tok := tokens[len(tokens)-1]; tok.Prec != 1 {
+		t.Errorf("|| should have precedence 1 but %+v", tok)
+	}
+	if rv, err := r.Eval(map[string]*big.Rat{"x": big.NewRat(2, 1), "y": ratZero}); err != nil || rv.Cmp(ratOne) != 0 {
+		t.Errorf("result should be 1 but %v, err %v", rv, err)
+	}
+}
+
+func TestResultNotShared(t *testing.T) {
+	x := big.NewRat(2, 1)
+	vars := map[string]*big.Rat{"x": x}
+	for _, in := range []string{"5", "x", "case(1, x)", "x * 5"} {
+		for _, opts := range [][]Option{nil, {WithEvalCache(2)}, {WithMemoization(), WithPooling(true)}} {
+			r, err := New(in, opts...)
+			if err != nil {
+				t.Fatal(err)
+			}
+			first, err := r.Eval(vars)
+			if err != nil {
+				t.Fatal(err)
+			}
+			want := new(big.Rat).Set(first)
+			first.SetInt64(100)
+			second, err := r.Eval(vars)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if second.Cmp(want) != 0 {
+				t.Errorf("[%v] result should be %v but %v after modifying a previous result", in, want, second)
+			}
+			if x.Cmp(big.NewRat(2, 1)) != 0 {
+				t.Fatalf("[%v] variable x has been modified to %v", in, x)
+			}
+		}
+
+		r, err := New(in)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := r.Result(); err != nil {
+			continue
+		}
+		first, _ := r.Result()
+		first.SetInt64(100)
+		if second, _ := r.Result(); second.Cmp(first) == 0 {
+			t.Errorf("[%v] modifying the result changed the cached result", in)
+		}
+	}
+}
+
+func TestTokenCallback(t *testing.T) {
+	for _, pratt := range []bool{false, true} {
+		var got []Token
+		opts := []Option{WithTokenCallback(func(tok Token) { got = append(got, tok) })}
+		if pratt {
+			opts = append(opts, WithPrattParser())
+		}
+		if _, err := New("round(x, -1) * |y|", opts...); err != nil {
+			t.Fatal(err)
+		}
+		kinds := []TokenKind{TokenFunction, TokenParenthesis, TokenIdent, TokenSeparator, TokenOperator, TokenNumber,
+			TokenParenthesis, TokenOperator, TokenFunction, TokenParenthesis, TokenIdent, TokenParenthesis}
+		values := []string{"round", "(", "x", ",", "@", "1", ")", "*", "abs", "(", "y", ")"}
+		if pratt {
+			// the Pratt parser tells unary minus from context
+			values[4] = "-"
+		}
+		if len(got) != len(values) {
+			t.Fatalf("pratt %v: tokens should be %v but %v", pratt, values, got)
+		}
+		for i, tok := range got {
+			if tok.Kind != kinds[i] || tok.Value != values[i] {
+				t.Errorf("pratt %v: token %d should be %v %q but %v %q", pratt, i, kinds[i], values[i], tok.Kind, tok.Value)
+			}
+		}
+	}
+
+	// tokens are reported even if the expression does not parse
+	var names []string
+	_, err := New("a + b +", WithTokenCallback(func(tok Token) {
+		if tok.Kind == TokenIdent {
+			names = append(names, tok.Value)
+		}
+	}))
+	if err == nil || len(names) != 2 {
+		t.Errorf("names should be [a b] but %v, err %v", names, err)
+	}
+}
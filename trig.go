@@ -0,0 +1,118 @@
+package rpn
+
+import (
+	"math"
+	"math/big"
+)
+
+// atan2Func returns atan2(y, x), the angle of the point (x, y) from the
+// positive x axis in the unit selected by WithAngleUnit, between -π and π
+func atan2Func(o *options, args []*big.Rat) (*big.Rat, error) {
+	y, _ := args[0].Float64()
+	x, _ := args[1].Float64()
+	return new(big.Rat).SetFloat64(atan2Float64(o, []float64{y, x})), nil
+}
+
+// atan2Float64 is atan2Func in float64
+func atan2Float64(o *options, args []float64) float64 {
+	return o.applyAngle(angleResult, func(y float64) float64 { return math.Atan2(y, args[1]) }, args[0])
+}
+
+// hypot returns hypot(a, b), the square root of a² + b², exact if it is
+// rational such as hypot(3, 4) and otherwise computed in float64 without
+// overflow
+func hypot(o *options, args []*big.Rat) (*big.Rat, error) {
+	sq := new(big.Rat).Mul(args[0], args[0])
+	sq.Add(sq, new(big.Rat).Mul(args[1], args[1]))
+	if rv := ratSqrt(sq); rv != nil {
+		return rv, nil
+	}
+	a, _ := args[0].Float64()
+	b, _ := args[1].Float64()
+	h := math.Hypot(a, b)
+	if math.IsInf(h, 0) {
+		return nil, ErrOverflow
+	}
+	return new(big.Rat).SetFloat64(h), nil
+}
+
+// snapAngle returns v, the sine, cosine or tangent of deg degrees computed in
+// float64, made exact if deg is a multiple of 15. Those values are either
+// multiples of 1/2, infinite or irrational and far from a multiple of 1/2.
+func snapAngle(deg, v float64) float64 {
+	if math.Mod(deg, 15) != 0 {
+		return v
+	}
+	if math.Abs(v) > 1e15 {
+		return math.Copysign(math.Inf(1), v)
+	}
+	if k := math.Round(2*v) / 2; math.Abs(v-k) < 1e-9 {
+		return k
+	}
+	return v
+}
+
+// wrapFunc returns wrap(x, lo, hi), x shifted by a multiple of hi - lo into
+// [lo, hi)
+func wrapFunc(o *options, args []*big.Rat) (*big.Rat, error) {
+	x, lo, hi := args[0], args[1], args[2]
+	if hi.Cmp(lo) <= 0 {
+		return nil, ErrInvalidArgument
+	}
+	return wrap(x, lo, new(big.Rat).Sub(hi, lo)), nil
+}
+
+// wrap returns x shifted by a multiple of width into [lo, lo + width)
+func wrap(x, lo, width *big.Rat) *big.Rat {
+	q := new(big.Rat).Sub(x, lo)
+	q.Quo(q, width)
+	n := new(big.Int).Div(q.Num(), q.Denom())
+	rv := new(big.Rat).SetInt(n)
+	rv.Mul(rv, width)
+	return rv.Sub(x, rv)
+}
+
+// wrapFloat64 is wrapFunc in float64
+func wrapFloat64(o *options, args []float64) float64 {
+	x, lo, hi := args[0], args[1], args[2]
+	if !(hi > lo) {
+		return math.NaN()
+	}
+	m := math.Mod(x-lo, hi-lo)
+	if m < 0 {
+		m += hi - lo
+	}
+	return lo + m
+}
+
+// normAngle returns normangle(x), the angle x in the unit selected by
+// WithAngleUnit shifted by whole turns into (-180, 180] or (-π, π], exactly
+// in degrees
+func normAngle(o *options, args []*big.Rat) (*big.Rat, error) {
+	if o.angle == Degrees {
+		rv := wrap(args[0], big.NewRat(-180, 1), big.NewRat(360, 1))
+		if rv.Cmp(big.NewRat(-180, 1)) == 0 {
+			rv.Neg(rv)
+		}
+		return rv, nil
+	}
+	f, _ := args[0].Float64()
+	f = normAngleFloat64(o, []float64{f})
+	if math.IsNaN(f) || math.IsInf(f, 0) {
+		return nil, ErrInvalidArgument
+	}
+	return new(big.Rat).SetFloat64(f), nil
+}
+
+// normAngleFloat64 is normAngle in float64
+func normAngleFloat64(o *options, args []float64) float64 {
+	half := math.Pi
+	if o.angle == Degrees {
+		half = 180
+	}
+	rv := math.Remainder(args[0], 2*half)
+	if rv <= -half {
+		rv += 2 * half
+	}
+	return rv
+}
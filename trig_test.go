@@ -0,0 +1,104 @@
+package rpn
+
+import (
+	"errors"
+	"math"
+	"math/big"
+	"testing"
+)
+
+func TestAtan2Hypot(t *testing.T) {
+	cases := []struct {
+		in     string
+		opts   []Option
+		result *big.Rat // nil if inexact
+		float  float64
+		err    error
+	}{
+		{"atan2(1, 1)", nil, nil, math.Pi / 4, nil},
+		{"atan2(1, -1)", nil, nil, 3 * math.Pi / 4, nil},
+		{"atan2(-1, -1)", nil, nil, -3 * math.Pi / 4, nil},
+		{"atan2(0, 0)", nil, big.NewRat(0, 1), 0, nil},
+		{"atan2(1, 0)", []Option{WithAngleUnit(Degrees)}, big.NewRat(90, 1), 90, nil},
+		{"atan2(-1, -1)", []Option{WithAngleUnit(Degrees)}, big.NewRat(-135, 1), -135, nil},
+		{"hypot(3, 4)", nil, big.NewRat(5, 1), 5, nil},
+		{"hypot(-1 / 3, 1 / 4)", nil, big.NewRat(5, 12), 5.0 / 12, nil},
+		{"hypot(1, 1)", nil, nil, math.Sqrt2, nil},
+		{"hypot(10 ^ 300, 10 ^ 300)", nil, nil, math.Hypot(1e300, 1e300), nil},
+		{"hypot(10 ^ 309, 1)", nil, big.NewRat(0, 1), 0, ErrOverflow},
+	}
+	for _, tc := range cases {
+		r, err := New(tc.in, tc.opts...)
+		if err != nil {
+			t.Errorf("can not convert [%v], err %v", tc.in, err)
+			continue
+		}
+		result, err := r.Result()
+		if !errors.Is(err, tc.err) {
+			t.Errorf("[%v] error should be %v but %v", tc.in, tc.err, err)
+			continue
+		}
+		if err != nil {
+			continue
+		}
+		if f, _ := result.Float64(); tc.result != nil && result.Cmp(tc.result) != 0 || math.Abs(f-tc.float) > 1e-12*math.Max(1, math.Abs(tc.float)) {
+			t.Errorf("[%v] result should be %v (%v) but %v", tc.in, tc.result, tc.float, result)
+		}
+		if f, err := r.EvalFloat64(nil); err != nil || math.Abs(f-tc.float) > 1e-12*math.Max(1, math.Abs(tc.float)) {
+			t.Errorf("[%v] float result should be %v but %v, err %v", tc.in, tc.float, f, err)
+		}
+	}
+}
+
+func TestAngles(t *testing.T) {
+	degrees := []Option{WithAngleUnit(Degrees)}
+	cases := []struct {
+		in     string
+		opts   []Option
+		result *big.Rat // nil if inexact
+		float  float64
+		err    error
+	}{
+		{"wrap(370, 0, 360)", nil, big.NewRat(10, 1), 10, nil},
+		{"wrap(-10, 0, 360)", nil, big.NewRat(350, 1), 350, nil},
+		{"wrap(360, 0, 360)", nil, big.NewRat(0, 1), 0, nil},
+		{"wrap(7 / 2, 1, 2)", nil, big.NewRat(3, 2), 1.5, nil},
+		{"wrap(1, 2, 2)", nil, nil, 0, ErrInvalidArgument},
+		{"normangle(540)", degrees, big.NewRat(180, 1), 180, nil},
+		{"normangle(-180)", degrees, big.NewRat(180, 1), 180, nil},
+		{"normangle(-190)", degrees, big.NewRat(170, 1), 170, nil},
+		{"normangle(1 / 3)", degrees, big.NewRat(1, 3), 1.0 / 3, nil},
+		{"normangle(7)", nil, nil, 7 - 2*math.Pi, nil},
+		{"deg2rad(180)", nil, nil, math.Pi, nil},
+		{"rad2deg(1)", nil, nil, 180 / math.Pi, nil},
+		{"sin(180)", degrees, big.NewRat(0, 1), 0, nil},
+		{"sin(-30)", degrees, big.NewRat(-1, 2), -0.5, nil},
+		{"cos(120)", degrees, big.NewRat(-1, 2), -0.5, nil},
+		{"cos(90)", degrees, big.NewRat(0, 1), 0, nil},
+		{"tan(135)", degrees, big.NewRat(-1, 1), -1, nil},
+		{"tan(45)", degrees, big.NewRat(1, 1), 1, nil},
+		{"tan(60)", degrees, nil, math.Sqrt(3), nil},
+		{"tan(90)", degrees, nil, 0, ErrInvalidArgument},
+	}
+	for _, tc := range cases {
+		r, err := New(tc.in, tc.opts...)
+		if err != nil {
+			t.Errorf("can not convert [%v], err %v", tc.in, err)
+			continue
+		}
+		result, err := r.Result()
+		if !errors.Is(err, tc.err) {
+			t.Errorf("[%v] error should be %v but %v", tc.in, tc.err, err)
+			continue
+		}
+		if err != nil {
+			continue
+		}
+		if f, _ := result.Float64(); tc.result != nil && result.Cmp(tc.result) != 0 || math.Abs(f-tc.float) > 1e-12 {
+			t.Errorf("[%v] result should be %v (%v) but %v", tc.in, tc.result, tc.float, result)
+		}
+		if f, err := r.EvalFloat64(nil); err != nil || math.Abs(f-tc.float) > 1e-12 {
+			t.Errorf("[%v] float result should be %v but %v, err %v", tc.in, tc.float, f, err)
+		}
+	}
+}
@@ -0,0 +1,93 @@
+package rpn
+
+import "math/big"
+
+// Variant is an expression differing from another by a single operator or
+// constant, as made by Variants
+type Variant struct {
+	Pos    int    // byte offset of the changed token
+	Change string // such as "+ to -", "2 to 3" or "negation removed"
+	Expr   *RPN
+}
+
+// mutations lists the operators each operator is replaced by
+var mutations = map[string][]string{
+	"+":  {"-", "*"},
+	"-":  {"+"},
+	"*":  {"/", "+"},
+	"×":  {"÷"},
+	"/":  {"*"},
+	"÷":  {"×"},
+	"//": {"/"},
+	"%":  {"*"},
+	"^":  {"*"},
+	"**": {"*"},
+	"<":  {"<=", ">"},
+	"<=": {"<", ">="},
+	">":  {">=", "<"},
+	">=": {">", "<="},
+	"==": {"!="},
+	"!=": {"=="},
+	"&&": {"||"},
+	"||": {"&&"},
+}
+
+// Variants returns the expressions made by perturbing one operator or
+// constant of the expression at a time, for sensitivity analysis or to check
+// that tests of a system using it notice a changed formula. Operators are
+// swapped for similar ones, such as + for - or < for <=, negations are
+// removed and constants are increased and decreased by 1. Variants using an
+// operator the options do not allow are left out.
+func (r *RPN) Variants() []Variant {
+	var variants []Variant
+	add := func(i int, change string, repl ...*token) {
+		postfix := make([]*token, 0, len(r.postfix)+len(repl))
+		postfix = append(append(append(postfix, r.postfix[:i]...), repl...), r.postfix[i+1:]...)
+		if v := r.variant(postfix); v != nil {
+			variants = append(variants, Variant{Pos: r.postfix[i].pos, Change: change, Expr: v})
+		}
+	}
+	for i, tok := range r.postfix {
+		switch tok.tp {
+		case tokenTypeOperator:
+			if tok.v == "@" {
+				add(i, "negation removed")
+			}
+			for _, op := range mutations[tok.v] {
+				add(i, tok.v+" to "+op, &token{tp: tokenTypeOperator, v: op, pos: tok.pos})
+			}
+		case tokenTypeOperand:
+			v, err := parseLiteral(tok.v)
+			if err != nil {
+				continue
+			}
+			for _, d := range []int64{1, -1} {
+				w := new(big.Rat).Add(v, big.NewRat(d, 1))
+				repl := postfixOf(numberNode(w), nil)
+				for _, t := range repl {
+					t.pos = tok.pos
+				}
+				add(i, tok.v+" to "+formatExplained(w), repl...)
+			}
+		}
+	}
+	return variants
+}
+
+// variant returns the expression with the options of r and the given postfix
+// notation, nil if the options do not allow it
+func (r *RPN) variant(postfix []*token) *RPN {
+	roots, _, err := buildTree(postfix, 1, false)
+	if err != nil {
+		return nil
+	}
+	v := &RPN{opts: r.opts, reg: r.reg, postfix: postfix}
+	if v.opts.policy.check(postfix) != nil {
+		return nil
+	}
+	v.infix = infixOf(roots[0], v.opts.rightPow(), nil)
+	if v.prepare() != nil {
+		return nil
+	}
+	return v
+}
@@ -0,0 +1,59 @@
+package rpn
+
+import (
+	"math/big"
+	"strings"
+	"testing"
+)
+
+func TestVariants(t *testing.T) {
+	r, err := New("-x + 2 * y > 0.5", WithPrattParser())
+	if err != nil {
+		t.Fatal(err)
+	}
+	vars := map[string]*big.Rat{"x": big.NewRat(1, 1), "y": big.NewRat(1, 1)}
+	want := []struct {
+		pos     int
+		change  string
+		postfix string
+		result  int64
+	}{
+		{0, "negation removed", "x 2 y * + 0.5 >", 1},
+		{5, "2 to 3", "x @ 3 y * + 0.5 >", 1},
+		{5, "2 to 1", "x @ 1 y * + 0.5 >", 0},
+		{7, "* to /", "x @ 2 y / + 0.5 >", 1},
+		{7, "* to +", "x @ 2 y + + 0.5 >", 1},
+		{3, "+ to -", "x @ 2 y * - 0.5 >", 0},
+		{3, "+ to *", "x @ 2 y * * 0.5 >", 0},
+		{13, "0.5 to 1.5", "x @ 2 y * + 1.5 >", 0},
+		{13, "0.5 to -0.5", "x @ 2 y * + 0.5 @ >", 1},
+		{11, "> to >=", "x @ 2 y * + 0.5 >=", 1},
+		{11, "> to <", "x @ 2 y * + 0.5 <", 0},
+	}
+	variants := r.Variants()
+	if len(variants) != len(want) {
+		t.Fatalf("there should be %d variants but %d", len(want), len(variants))
+	}
+	for i, v := range variants {
+		w := want[i]
+		postfix := strings.Join(v.Expr.Postfix(), " ")
+		if v.Pos != w.pos || v.Change != w.change || postfix != w.postfix {
+			t.Errorf("variant %d should be %v at %d, %v but %v at %d, %v", i, w.change, w.pos, w.postfix, v.Change, v.Pos, postfix)
+		}
+		result, err := v.Expr.Eval(vars)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if result.Cmp(big.NewRat(w.result, 1)) != 0 {
+			t.Errorf("[%v] result should be %d but %v", w.change, w.result, result)
+		}
+	}
+
+	r, err = New("a + b", WithDeniedOperators("-"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if variants := r.Variants(); len(variants) != 1 || variants[0].Change != "+ to *" {
+		t.Errorf("the only variant should be + to * but %v", variants)
+	}
+}
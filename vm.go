@@ -0,0 +1,402 @@
+package rpn
+
+import (
+	"math"
+	"math/big"
+	"strings"
+)
+
+type opcode uint8
+
+const (
+	opConst   opcode = iota // push the constant consts[arg]
+	opLoad                  // push the variable or named expression names[arg]
+	opStore                 // copy the top of the stack into register arg
+	opLoadReg               // push the value of register arg
+	opLocal                 // push the local variable arg
+	opBool                  // replace a non-zero top of the stack by 1
+	opCapture               // record the top of the stack as the value of captures[arg]
+	opNeg                   // negate the top of the stack
+	opAdd                   // binary operators pop two operands and push one
+	opSub
+	opMul
+	opDiv
+	opFloorDiv
+	opMod
+	opPow
+	opEq // comparisons push 1 if they hold and 0 otherwise
+	opNe
+	opLt
+	opLe
+	opGt
+	opGe
+	opCall      // call funcs[arg] with argc arguments
+	opForm      // evaluate the form subs[arg] with argc arguments
+	opJumpFalse // jump to arg keeping a zero top of the stack, else pop it
+	opJumpTrue  // jump to arg replacing a non-zero top of the stack by 1, else pop it
+	opSkip      // pop the top of the stack, jump to arg if it is zero
+	opJump      // jump to arg
+	opNoMatch   // fail as no branch of a piecewise function applies
+)
+
+// counted reports whether op applies an operator or a function, which
+// WithMaxOperations counts, rather than loading a value or directing control
+func (op opcode) counted() bool {
+	return op >= opNeg && op <= opForm
+}
+
+// opcodes maps operators to the opcode applying them
+var opcodes = map[string]opcode{
+	"@":   opNeg,
+	"+":   opAdd,
+	"-":   opSub,
+	"*":   opMul,
+	"×":   opMul,
+	"/":   opDiv,
+	"÷":   opDiv,
+	"//":  opFloorDiv,
+	"div": opFloorDiv,
+	"%":   opMod,
+	"**":  opPow,
+	"^":   opPow,
+	"==":  opEq,
+	"!=":  opNe,
+	"<":   opLt,
+	"<=":  opLe,
+	">":   opGt,
+	">=":  opGe,
+}
+
+// ratZero and ratOne are the results of boolean operators, they are shared
+// and must never be modified
+var ratZero, ratOne = new(big.Rat), big.NewRat(1, 1)
+
+// ratBool returns ratOne if b holds and ratZero otherwise
+func ratBool(b bool) *big.Rat {
+	if b {
+		return ratOne
+	}
+	return ratZero
+}
+
+type instr struct {
+	op   opcode
+	arg  int
+	argc int
+	tok  *token // the token compiled into the instruction, if any
+}
+
+// evaluator holds the state of a single evaluation
+type evaluator struct {
+	opts      *options
+	memo      *memo // nil unless memoization is enabled
+	reg       *registry
+	vars      map[string]*big.Rat
+	env       *Env                // scopes consulted after vars, nil if none
+	fvars     map[string]float64  // variables in float mode
+	locals    []*big.Rat          // variables bound by forms such as with
+	flocals   []float64           // variables bound by forms in float mode
+	expanding []frame             // named expressions currently being evaluated
+	ops       int                 // operators and functions applied so far
+	prov      *Provenance         // inputs read so far, nil unless recorded
+	captures  map[string]*big.Rat // values of capture, nil unless recorded
+	explain   *explainer          // narrates the steps taken, nil unless explained
+}
+
+// variable returns the value of the variable name, nil if it is not bound
+func (e *evaluator) variable(name string) *big.Rat {
+	fold := e.opts.caseMode == CaseInsensitive
+	if rv := lookupVar(e.vars, name, fold); rv != nil {
+		return rv
+	}
+	for env := e.env; env != nil; env = env.parent {
+		if rv := lookupVar(env.vars, name, fold); rv != nil {
+			return rv
+		}
+	}
+	return e.cell(name)
+}
+
+// lookupVar returns the value of name in vars, nil if it is not bound
+func lookupVar(vars map[string]*big.Rat, name string, fold bool) *big.Rat {
+	if rv := vars[name]; rv != nil || !fold {
+		return rv
+	}
+	for k, rv := range vars {
+		if strings.EqualFold(k, name) && rv != nil {
+			return rv
+		}
+	}
+	return nil
+}
+
+// spend counts an operation against the budget
+func (e *evaluator) spend() error {
+	e.ops++
+	if e.opts.maxOps > 0 && e.ops > e.opts.maxOps {
+		return ErrBudgetExceeded
+	}
+	return nil
+}
+
+// run executes the code of p returning the value of its last expression
+func (e *evaluator) run(p *Program) (*big.Rat, error) {
+	s := newStack(e.opts.pooling, p.stack)
+	defer s.free()
+	if err := e.exec(p, s); err != nil {
+		return nil, err
+	}
+	// hand out a copy of values referenced elsewhere, such as constants
+	rv, owned := s.pop()
+	if !owned {
+		rv = new(big.Rat).Set(rv)
+	}
+	return rv, nil
+}
+
+// runAll executes the code of p returning the values of all its expressions
+func (e *evaluator) runAll(p *Program) ([]*big.Rat, error) {
+	s := newStack(e.opts.pooling, p.stack)
+	defer s.free()
+	if err := e.exec(p, s); err != nil {
+		return nil, err
+	}
+	rvs := make([]*big.Rat, len(s.vals))
+	for i, rv := range s.vals {
+		if !s.owned[i] {
+			rv = new(big.Rat).Set(rv)
+		}
+		rvs[i] = rv
+	}
+	return rvs, nil
+}
+
+// exec executes the code of p leaving the values of its expressions on s
+func (e *evaluator) exec(p *Program, s *stack) error {
+	regs := make([]*big.Rat, p.regs)
+	for pc := 0; pc < len(p.code); {
+		in := p.code[pc]
+		pc++
+		if in.op.counted() {
+			if err := e.spend(); err != nil {
+				return err
+			}
+		}
+		switch in.op {
+		case opConst:
+			if e.prov != nil {
+				e.prov.Constants = appendNew(e.prov.Constants, in.tok.v)
+			}
+			s.push(p.consts[in.arg], false)
+			if e.explain != nil {
+				e.explain.exec(p, in, p.consts[in.arg])
+			}
+		case opLoad:
+			name := p.names[in.arg]
+			if rv := e.variable(name); rv != nil {
+				if e.prov != nil {
+					e.prov.Variables = appendNew(e.prov.Variables, name)
+				}
+				s.push(rv, false)
+				if e.explain != nil {
+					e.explain.exec(p, in, rv)
+				}
+				continue
+			}
+			if e.prov != nil {
+				e.prov.Expressions = appendNew(e.prov.Expressions, name)
+			}
+			rv, err := e.expand(name)
+			if err != nil {
+				return err
+			}
+			s.push(rv, false)
+			if e.explain != nil {
+				e.explain.expanded(name, rv)
+			}
+		case opStore:
+			regs[in.arg] = s.vals[len(s.vals)-1]
+			s.owned[len(s.owned)-1] = false
+			if e.explain != nil {
+				e.explain.exec(p, in, nil)
+			}
+		case opLoadReg:
+			s.push(regs[in.arg], false)
+			if e.explain != nil {
+				e.explain.exec(p, in, regs[in.arg])
+			}
+		case opCapture:
+			if e.captures != nil {
+				e.captures[p.captures[in.arg]] = new(big.Rat).Set(s.vals[len(s.vals)-1])
+			}
+		case opLocal:
+			s.push(e.locals[in.arg], false)
+		case opForm:
+			n := len(s.vals) - in.argc
+			sub := p.subs[in.arg]
+			// the bodies of forms are not explained
+			explain := e.explain
+			e.explain = nil
+			rv, err := sub.form.eval(e, sub.body, s.vals[n:])
+			e.explain = explain
+			if err != nil {
+				return err
+			}
+			s.drop(in.argc, nil)
+			s.push(rv, true)
+			if e.explain != nil {
+				e.explain.exec(p, in, rv)
+			}
+		case opBool:
+			x, xo := s.pop()
+			rv := ratBool(x.Sign() != 0)
+			s.push(rv, false)
+			s.release(x, xo, nil)
+			if e.explain != nil {
+				e.explain.exec(p, in, rv)
+			}
+		case opJumpFalse, opJumpTrue:
+			x, xo := s.pop()
+			jump := (x.Sign() != 0) == (in.op == opJumpTrue)
+			s.release(x, xo, nil)
+			var rv *big.Rat // nil unless it jumps
+			if jump {
+				rv = ratBool(in.op == opJumpTrue)
+				s.push(rv, false)
+				pc = in.arg
+			}
+			if e.explain != nil {
+				e.explain.exec(p, in, rv)
+			}
+		case opSkip:
+			x, xo := s.pop()
+			if x.Sign() == 0 {
+				pc = in.arg
+			}
+			s.release(x, xo, nil)
+			if e.explain != nil {
+				e.explain.exec(p, in, nil)
+			}
+		case opJump:
+			pc = in.arg
+		case opNoMatch:
+			return ErrInvalidArgument
+		case opNeg:
+			x, xo := s.pop()
+			z := s.dst(x, xo, nil, false)
+			s.push(z.Neg(x), true)
+			if e.explain != nil {
+				e.explain.exec(p, in, z)
+			}
+		case opCall:
+			n := len(s.vals) - in.argc
+			rv, err := e.call(p.funcNames[in.arg], p.funcs[in.arg], s.vals[n:])
+			if err != nil {
+				return err
+			}
+			rv = e.rounded(rv)
+			s.drop(in.argc, rv)
+			s.push(rv, false)
+			if e.explain != nil {
+				e.explain.exec(p, in, rv)
+			}
+		default:
+			y, yo := s.pop()
+			x, xo := s.pop()
+			z := s.dst(x, xo, y, yo)
+			rv, err := e.arith(in.op, z, x, y)
+			switch {
+			case err == ErrZeroDivision && e.opts.zeroDiv == ZeroDivisionValue:
+				rv = e.opts.zeroValue
+				s.push(rv, false)
+			case err != nil:
+				return err
+			default:
+				s.release(x, xo, z)
+				s.release(y, yo, z)
+				s.push(rv, rv != nil)
+			}
+			if e.explain != nil {
+				e.explain.exec(p, in, rv)
+			}
+		}
+	}
+	return nil
+}
+
+// binary sets z to the result of a binary operator and returns it, z may
+// be one of the operands. Exact values have no negative zero and no
+// infinities: 0^0 is 1, 0 raised to a negative power divides by zero, results
+// too large to represent overflow and other undefined results are invalid.
+// The remainder is exact from Semantics2 on.
+func binary(op opcode, z, x, y *big.Rat, v SemanticsVersion) (*big.Rat, error) {
+	switch op {
+	case opAdd:
+		return z.Add(x, y), nil
+	case opSub:
+		return z.Sub(x, y), nil
+	case opMul:
+		return z.Mul(x, y), nil
+	case opDiv:
+		if y.Sign() == 0 {
+			return nil, ErrZeroDivision
+		}
+		return z.Quo(x, y), nil
+	case opFloorDiv:
+		if y.Sign() == 0 {
+			return nil, ErrZeroDivision
+		}
+		return floorDiv(z, x, y), nil
+	case opMod:
+		if y.Sign() == 0 {
+			return nil, ErrZeroDivision
+		}
+		if v >= Semantics2 {
+			return truncMod(z, x, y), nil
+		}
+		f1, _ := x.Float64()
+		f2, _ := y.Float64()
+		return setFinite(z, math.Mod(f1, f2))
+	case opPow:
+		if x.Sign() == 0 && y.Sign() < 0 {
+			return nil, ErrZeroDivision
+		}
+		f1, _ := x.Float64()
+		f2, _ := y.Float64()
+		return setFinite(z, math.Pow(f1, f2))
+	}
+	if op >= opEq && op <= opGe {
+		return z.Set(ratBool(compare(op, x.Cmp(y)))), nil
+	}
+	return nil, ErrUnrecognizedExpression
+}
+
+// compare reports whether a comparison holds given the sign of the
+// difference of its operands
+func compare(op opcode, c int) bool {
+	switch op {
+	case opEq:
+		return c == 0
+	case opNe:
+		return c != 0
+	case opLt:
+		return c < 0
+	case opLe:
+		return c <= 0
+	case opGt:
+		return c > 0
+	}
+	return c >= 0
+}
+
+// setFinite sets z to f and returns it, failing when f is infinite or NaN as
+// a Rat can not hold it
+func setFinite(z *big.Rat, f float64) (*big.Rat, error) {
+	switch {
+	case math.IsNaN(f):
+		return nil, ErrInvalidArgument
+	case math.IsInf(f, 0):
+		return nil, ErrOverflow
+	}
+	return z.SetFloat64(f), nil
+}